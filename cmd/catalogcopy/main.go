@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"imagestorage/internal/config"
+	"imagestorage/internal/storage"
+	"imagestorage/internal/storage/catalog"
+)
+
+// Snapshotter - каталог, который умеет выгружать и загружать себя целиком
+type Snapshotter interface {
+	Export() (catalog.Snapshot, error)
+	Import(snapshot catalog.Snapshot) error
+}
+
+// catalogcopy переносит каталог файлов между хранилищами метаданных, например из sqlite в bolt.
+// Каталог назначения должен быть пустым, id записей сохраняются. Сервер на время переноса останавливают
+func main() {
+	var fromDriver, fromPath, toDriver, toPath string
+
+	flag.StringVar(&fromDriver, "from-driver", "sqlite", "source database driver: sqlite or bolt")
+	flag.StringVar(&fromPath, "from", "", "source storage path")
+	flag.StringVar(&toDriver, "to-driver", "bolt", "target database driver: sqlite or bolt")
+	flag.StringVar(&toPath, "to", "", "target storage path")
+	flag.Parse()
+	fmt.Printf("From: %s %s\n", fromDriver, fromPath)
+	fmt.Printf("To: %s %s\n", toDriver, toPath)
+
+	if fromPath == "" {
+		panic("from is required")
+	}
+
+	if toPath == "" {
+		panic("to is required")
+	}
+
+	from := open(fromDriver, fromPath)
+	to := open(toDriver, toPath)
+
+	snapshot, err := from.Export()
+	if err != nil {
+		panic(err)
+	}
+
+	if err := to.Import(snapshot); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("copied %d files, %d blobs, %d upload sessions\n",
+		len(snapshot.Files), len(snapshot.Blobs), len(snapshot.UploadSessions))
+}
+
+func open(driver string, path string) Snapshotter {
+	db, err := storage.New(config.DBConfig{Driver: driver, StoragePath: config.DSN(path)})
+	if err != nil {
+		panic(err)
+	}
+
+	snapshotter, ok := db.(Snapshotter)
+	if !ok {
+		panic(fmt.Sprintf("driver %s does not support export and import", driver))
+	}
+
+	return snapshotter
+}
+
+//go run ./cmd/catalogcopy/ --from-driver=sqlite --from=./internal/storage/sqlite/image.db --to-driver=bolt --to=./internal/storage/image.bolt
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"imagestorage/internal/config"
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/services/reconcileService"
+	"imagestorage/internal/storage"
+	"imagestorage/internal/storage/blob"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// reconcile сверяет каталог файлов с хранилищем blob-ов и печатает расхождения.
+// Настройки хранилищ берутся из того же .env, что у сервера. С --fix удаляет orphan-ы и помечает
+// удаленными записи без содержимого; безопаснее запускать его при остановленном сервере.
+// Код выхода 1, если остались неисправленные расхождения
+func main() {
+	var fix, verify bool
+
+	flag.BoolVar(&fix, "fix", false, "remove orphans and mark files with missing content deleted")
+	flag.BoolVar(&verify, "verify", false, "read every blob and compare its sha256 with the catalog")
+	flag.Parse()
+
+	// Без .env настройки берутся из окружения
+	_ = godotenv.Load()
+	cfg := config.MustLoad()
+	fmt.Printf("Driver: %s\n", cfg.DBConfig.Driver)
+
+	db, err := storage.New(cfg.DBConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	blobStore, err := blob.New(cfg.BlobStorage, cfg.ServerImageStorage)
+	if err != nil {
+		panic(err)
+	}
+
+	coldStore, err := blob.NewCold(cfg.Tiering, cfg.BlobStorage)
+	if err != nil {
+		panic(err)
+	}
+
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	// Содержимое сверяется в том виде, в каком оно лежит в хранилище, поэтому ключи и сжатие не нужны
+	images := imageService.NewImageService(log, cfg.ServerImageStorage, blobStore, nil, nil, db, coldStore, db)
+	reconciler := reconcileService.NewReconcileService(log, db, images, cfg.OrphanAge)
+
+	report, err := reconciler.Reconcile(context.Background(), reconcileService.Options{Fix: fix, Verify: verify})
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("checked %d blobs and %d catalog paths: %d problems, %d fixed\n", report.Blobs, report.Paths,
+		len(report.Problems), len(report.Problems)-report.Unfixed())
+
+	if report.Unfixed() > 0 {
+		os.Exit(1)
+	}
+}
+
+//go run ./cmd/reconcile/ --verify
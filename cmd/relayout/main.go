@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"imagestorage/internal/config"
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/storage"
+	"imagestorage/internal/storage/catalog"
+)
+
+// relayout переносит файлы из плоского каталога в шардированную раскладку
+// и записывает новое расположение в files.path_to_file. Повторный запуск безопасен
+func main() {
+	var driver, storagePath, imagesPath string
+	var dryRun bool
+
+	flag.StringVar(&driver, "driver", "sqlite", "database driver: sqlite, postgres or bolt")
+	flag.StringVar(&storagePath, "storage-path", "", "path to sqlite or bolt storage, or postgres connection string")
+	flag.StringVar(&imagesPath, "images-path", "", "path to saved images (PATH_TO_SAVED_IMAGES)")
+	flag.BoolVar(&dryRun, "dry-run", false, "only print what would be moved")
+	flag.Parse()
+	fmt.Printf("Driver: %s\n", driver)
+	fmt.Printf("Images path: %s\n", imagesPath)
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+
+	if imagesPath == "" {
+		panic("images-path is required")
+	}
+
+	db, err := storage.New(config.DBConfig{Driver: driver, StoragePath: config.DSN(storagePath)})
+	if err != nil {
+		panic(err)
+	}
+
+	moved, updated, missing, err := relayout(db, imagesPath, dryRun, os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("moved %d files, updated %d rows, missing %d\n", moved, updated, missing)
+}
+
+type layoutCatalog interface {
+	ListFileLocations() ([]catalog.FileLocation, error)
+	UpdateFilePath(id int64, path string) error
+}
+
+// relayout переносит содержимое записей каталога db под imagesPath в шардированные ключи.
+// Возвращает количество перенесенных файлов, обновленных записей и записей без файла
+func relayout(db layoutCatalog, imagesPath string, dryRun bool, out io.Writer) (int, int, int, error) {
+	locations, err := db.ListFileLocations()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var moved, updated, missing int
+	for _, location := range locations {
+		target := imageService.LegacyKey(location.FileName)
+		if location.Checksum != "" {
+			target = imageService.BlobKey(location.Checksum)
+		}
+		if location.Path == target {
+			continue
+		}
+
+		src := filepath.Join(imagesPath, filepath.FromSlash(location.Path))
+		dst := filepath.Join(imagesPath, filepath.FromSlash(target))
+
+		if dryRun {
+			fmt.Fprintf(out, "%s -> %s\n", location.Path, target)
+			continue
+		}
+
+		// Несколько записей могут ссылаться на один blob: переносим его один раз
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			if _, err := os.Stat(src); os.IsNotExist(err) {
+				fmt.Fprintf(out, "missing %s (file %s, id %d)\n", location.Path, location.FileName, location.ID)
+				missing++
+				continue
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return moved, updated, missing, err
+			}
+			if err := os.Rename(src, dst); err != nil {
+				return moved, updated, missing, err
+			}
+			moved++
+		} else if err != nil {
+			return moved, updated, missing, err
+		} else if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+			return moved, updated, missing, err
+		}
+
+		if err := db.UpdateFilePath(location.ID, target); err != nil {
+			return moved, updated, missing, err
+		}
+		updated++
+	}
+
+	return moved, updated, missing, nil
+}
+
+//go run ./cmd/relayout/ --storage-path=./internal/storage/sqlite/image.db --images-path=./serverRecievedImages
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/storage/catalog"
+	"imagestorage/internal/storage/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+func writeFile(t *testing.T, root string, key string, data string) {
+	t.Helper()
+
+	path := filepath.Join(root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, root string, key string) (string, bool) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), true
+}
+
+// openCatalog - sqlite, а не bolt: в bolt нет записей без blob-а, а их тоже нужно переносить
+func openCatalog(t *testing.T, path string) *sqlite.Storage {
+	t.Helper()
+
+	m, err := migrate.New("file://../../migrations", "sqlite3://"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	db, err := sqlite.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRelayout(t *testing.T) {
+	dir := t.TempDir()
+	images := filepath.Join(dir, "images")
+	db := openCatalog(t, filepath.Join(dir, "catalog.db"))
+
+	shared := fmt.Sprintf("%064x", 1)
+	files := []struct {
+		name     string
+		checksum string
+		path     string
+		// data - содержимое по path, пусто - файла нет
+		data string
+		want string
+	}{
+		{name: "a.png", checksum: shared, path: imageService.FlatBlobKey(shared), data: "shared blob", want: imageService.BlobKey(shared)},
+		{name: "b.png", checksum: shared, path: imageService.FlatBlobKey(shared), want: imageService.BlobKey(shared)},
+		{name: "legacy.png", path: "legacy.png", data: "legacy file", want: imageService.LegacyKey("legacy.png")},
+		{name: "lost.png", checksum: fmt.Sprintf("%064x", 2), path: imageService.FlatBlobKey(fmt.Sprintf("%064x", 2)), want: imageService.FlatBlobKey(fmt.Sprintf("%064x", 2))},
+	}
+	for _, file := range files {
+		if _, _, err := db.SaveImage(file.name, "", file.path, 10, "image/png", "", file.checksum, catalog.StoredBlob{StoredSize: 10}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if file.data != "" {
+			writeFile(t, images, file.path, file.data)
+		}
+	}
+
+	// dry-run только печатает переносы
+	var out strings.Builder
+	if moved, updated, missing, err := relayout(db, images, true, &out); err != nil || moved+updated+missing != 0 {
+		t.Fatalf("dry run: moved %d, updated %d, missing %d, %v", moved, updated, missing, err)
+	}
+	if lines := strings.Count(out.String(), " -> "); lines != len(files) {
+		t.Fatalf("dry run printed %d moves:\n%s", lines, out.String())
+	}
+	if _, ok := readFile(t, images, imageService.FlatBlobKey(shared)); !ok {
+		t.Fatalf("dry run moved a file")
+	}
+
+	moved, updated, missing, err := relayout(db, images, false, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 || updated != 3 || missing != 1 {
+		t.Fatalf("moved %d, updated %d, missing %d; want 2, 3, 1", moved, updated, missing)
+	}
+
+	for _, file := range files {
+		location, err := db.FindFileLocation(file.name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.Path != file.want {
+			t.Fatalf("%s: path %s, want %s", file.name, location.Path, file.want)
+		}
+		if file.data == "" {
+			continue
+		}
+		if data, ok := readFile(t, images, file.want); !ok || data != file.data {
+			t.Fatalf("%s: content at %s is %q", file.name, file.want, data)
+		}
+		if _, ok := readFile(t, images, file.path); ok {
+			t.Fatalf("%s: old copy left at %s", file.name, file.path)
+		}
+	}
+
+	// Повторный запуск ничего не переносит, записи без файла снова считаются потерянными
+	moved, updated, missing, err = relayout(db, images, false, io.Discard)
+	if err != nil || moved != 0 || updated != 0 || missing != 1 {
+		t.Fatalf("second run: moved %d, updated %d, missing %d, %v", moved, updated, missing, err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"imagestorage/internal/config"
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/storage"
+)
+
+// rewrap перезакрывает ключи данных всех зашифрованных blob-ов новым мастер-ключом.
+// Содержимое файлов не перечитывается и не переписывается. Повторный запуск безопасен
+func main() {
+	var driver, storagePath, keyFile, previousKeyFiles string
+	var dryRun bool
+
+	flag.StringVar(&driver, "driver", "sqlite", "database driver: sqlite, postgres or bolt")
+	flag.StringVar(&storagePath, "storage-path", "", "path to sqlite or bolt storage, or postgres connection string")
+	flag.StringVar(&keyFile, "key-file", "", "file with the new master key (ENCRYPTION_KEY_FILE)")
+	flag.StringVar(&previousKeyFiles, "previous-key-files", "", "comma-separated files with the old master keys")
+	flag.BoolVar(&dryRun, "dry-run", false, "only print what would be rewrapped")
+	flag.Parse()
+	fmt.Printf("Driver: %s\n", driver)
+
+	if storagePath == "" {
+		panic("storage-path is required")
+	}
+
+	if keyFile == "" {
+		panic("key-file is required")
+	}
+
+	current, err := encryption.ReadKeyFile(keyFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var previous []*encryption.MasterKey
+	for _, path := range strings.Split(previousKeyFiles, ",") {
+		if path == "" {
+			continue
+		}
+		key, err := encryption.ReadKeyFile(path)
+		if err != nil {
+			panic(err)
+		}
+		previous = append(previous, key)
+	}
+	keys := encryption.NewKeyring(current, previous...)
+	fmt.Printf("New master key: %s\n", current.ID())
+
+	db, err := storage.New(config.DBConfig{Driver: driver, StoragePath: config.DSN(storagePath)})
+	if err != nil {
+		panic(err)
+	}
+
+	wrappedKeys, err := db.ListWrappedKeys()
+	if err != nil {
+		panic(err)
+	}
+
+	var rewrapped, skipped, unknown int
+	for _, wrapped := range wrappedKeys {
+		if wrapped.KeyID == current.ID() {
+			skipped++
+			continue
+		}
+
+		dataKey, err := keys.Unwrap(wrapped.KeyID, wrapped.WrappedKey)
+		if err != nil {
+			fmt.Printf("cannot unwrap blob %s (key %s): %v\n", wrapped.Checksum, wrapped.KeyID, err)
+			unknown++
+			continue
+		}
+
+		if dryRun {
+			fmt.Printf("%s: %s -> %s\n", wrapped.Checksum, wrapped.KeyID, current.ID())
+			continue
+		}
+
+		rewrappedKey, err := current.Wrap(dataKey)
+		if err != nil {
+			panic(err)
+		}
+
+		// Blob мог быть удален или уже перезакрыт параллельно: такие пропускаем
+		updated, err := db.UpdateWrappedKey(wrapped.Checksum, wrapped.KeyID, current.ID(), rewrappedKey)
+		if err != nil {
+			panic(err)
+		}
+		if updated {
+			rewrapped++
+		}
+	}
+
+	fmt.Printf("rewrapped %d keys, already current %d, unknown key %d\n", rewrapped, skipped, unknown)
+}
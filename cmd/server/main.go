@@ -2,18 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 
 	"imagestorage/internal/app"
+	"imagestorage/internal/compress"
 	"imagestorage/internal/config"
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/exif"
+	storagegrpc "imagestorage/internal/grpc/serverStorage"
+	"imagestorage/internal/mimetype"
 	"imagestorage/internal/services/imageService"
-	"imagestorage/internal/storage/sqlite"
+	"imagestorage/internal/services/purgeService"
+	"imagestorage/internal/services/reconcileService"
+	"imagestorage/internal/services/thumbnailService"
+	"imagestorage/internal/services/tierService"
+	"imagestorage/internal/services/transformService"
+	"imagestorage/internal/storage"
+	"imagestorage/internal/storage/blob"
 
 	"imagestorage/internal/grpc/client"
+	"imagestorage/internal/storage/catalog"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -39,7 +53,7 @@ func main() {
 	log.Info(cfg)
 	log.Info("Starting server...")
 
-	imageDB, err := sqlite.New(cfg.StoragePath)
+	imageDB, err := storage.New(cfg.DBConfig)
 
 	if err != nil {
 		log.Fatal(err)
@@ -47,12 +61,94 @@ func main() {
 
 	ctx := context.Background()
 
-	diskSaver := imageService.NewImageService(log, cfg.ServerImageStorage)
+	blobStore, err := blob.New(cfg.BlobStorage, cfg.ServerImageStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	coldStore, err := blob.NewCold(cfg.Tiering, cfg.BlobStorage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	compression, err := compress.ParsePolicy(cfg.Compression.Policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := encryption.LoadKeyring(cfg.Encryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if keys != nil {
+		log.Infof("Encryption at rest enabled, master key %s", keys.Current().ID())
+	}
+
+	diskSaver := imageService.NewImageService(log, cfg.ServerImageStorage, blobStore, compression, keys, imageDB, coldStore, imageDB)
+
+	purger := purgeService.NewPurgeService(log, imageDB, diskSaver, cfg.PurgeGracePeriod, cfg.UploadSessionTTL)
+
+	// Брошенные сессии закрываем, а staging-файлы без сессии удаляем до приема новых загрузок
+	expired, err := purger.ExpireUploadSessions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("Expired upload sessions: %d", expired)
+
+	err = diskSaver.SweepStaging(func(sessionID string) (bool, error) {
+		_, err := imageDB.GetUploadSession(sessionID)
+		if errors.Is(err, catalog.ErrSessionNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go purger.Run(ctx, cfg.PurgeInterval)
+
+	// Время скачивания отмечается всегда, а переносятся blob-ы, только если есть холодное хранилище
+	tiers := tierService.NewTierService(log, imageDB, diskSaver, cfg.IdlePeriod)
+	if coldStore != nil {
+		log.Infof("Cold storage enabled, files idle for %s are moved to %s", cfg.IdlePeriod, cfg.ColdDriver)
+		go tiers.Run(ctx, cfg.Tiering.Interval)
+	}
+
+	reconciler := reconcileService.NewReconcileService(log, imageDB, diskSaver, cfg.OrphanAge)
+	if cfg.Reconcile.Interval > 0 {
+		go reconciler.Run(ctx, cfg.Reconcile.Interval, reconcileService.Options{Fix: cfg.Fix, Verify: cfg.Verify})
+	}
+
+	thumbnails := thumbnailService.NewThumbnailService(log, imageDB, diskSaver, cfg.Thumbnails.Sizes, cfg.Thumbnails.Quality)
+
+	transformCacheDir := cfg.Transforms.CacheDir
+	if transformCacheDir == "" {
+		transformCacheDir = filepath.Join(cfg.ServerImageStorage, ".transforms")
+	}
+	transforms := transformService.NewTransformService(log, imageDB, diskSaver, transformCacheDir, cfg.Transforms.CacheSize)
+
 	GRPCport, err := strconv.Atoi(cfg.GRPC.Port)
 	if err != nil {
 		log.Fatal(err)
 	}
-	storeImageServer := app.NewApp(log, GRPCport, imageDB, diskSaver)
+	limits := storagegrpc.Limits{
+		MaxFileSize:         cfg.MaxFileSize,
+		QuotaBytes:          cfg.QuotaBytes,
+		QuotaFiles:          cfg.QuotaFiles,
+		NamespaceQuotaBytes: cfg.NamespaceQuotaBytes,
+		NamespaceQuotaFiles: cfg.NamespaceQuotaFiles,
+	}
+	stripExif, err := exif.ParseMode(cfg.Exif.Strip)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contentTypeMismatch, err := mimetype.ParsePolicy(cfg.ContentType.Mismatch)
+	if err != nil {
+		log.Fatal(err)
+	}
+	policy := storagegrpc.Policy{StripExif: stripExif, ContentTypeMismatch: contentTypeMismatch}
+	storeImageServer := app.NewApp(log, GRPCport, imageDB, diskSaver, purger, tiers, thumbnails, transforms, limits, policy)
 
 	go storeImageServer.GRPCsrv.Start()
 
@@ -11,12 +11,12 @@ type App struct {
 	GRPCsrv *grpcConstructor.App
 }
 
-func NewApp(log *logrus.Logger, grpcPort int, storage storagegrpc.Storage, diskSaver storagegrpc.ImageSaver) *App {
+func NewApp(log *logrus.Logger, grpcPort int, storage storagegrpc.Storage, diskSaver storagegrpc.ImageSaver, purger storagegrpc.Purger, access storagegrpc.AccessTracker, thumbnails storagegrpc.Thumbnailer, transforms storagegrpc.Transformer, limits storagegrpc.Limits, policy storagegrpc.Policy) *App {
 	// TODO: хранилище
 
 	//init image storage
 
-	grpcApp := grpcConstructor.NewApp(log, grpcPort, storage, diskSaver)
+	grpcApp := grpcConstructor.NewApp(log, grpcPort, storage, diskSaver, purger, access, thumbnails, transforms, limits, policy)
 	return &App{
 		GRPCsrv: grpcApp,
 	}
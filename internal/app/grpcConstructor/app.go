@@ -3,6 +3,7 @@ package grpcConstructor
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 
 	"github.com/sirupsen/logrus"
@@ -10,6 +11,7 @@ import (
 
 	"imagestorage/internal/app/middleware"
 	storagegrpc "imagestorage/internal/grpc/serverStorage"
+	"imagestorage/internal/storage/catalog"
 )
 
 type App struct {
@@ -18,11 +20,20 @@ type App struct {
 	port       int
 }
 type ImageSaver interface {
-	DiskSave(ctx context.Context, imageName string, imageData []byte) error
-	DeleteFile(log *logrus.Logger, imageName string, success bool)
+	DiskSave(ctx context.Context, stageName string, imageData []byte) error
+	DeleteFile(log *logrus.Logger, stageName string, success bool)
+	Truncate(stageName string, size int64) error
+	StoreBlob(stageName string, checksum string, mimeType string, commit func(stored catalog.StoredBlob) (bool, error), discard func() error) error
+	BlobKey(checksum string) string
+	StatBlob(ctx context.Context, key string) (int64, error)
+	ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error)
+	ReadVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, offset int64, length int64) (io.ReadCloser, error)
+	ReadExif(stageName string) (catalog.Exif, bool, error)
+	StripExif(stageName string, mode string) (bool, string, int64, error)
+	DetectType(stageName string) (string, error)
 }
 
-func NewApp(log *logrus.Logger, port int, storage storagegrpc.Storage, diskSaver ImageSaver) *App {
+func NewApp(log *logrus.Logger, port int, storage storagegrpc.Storage, diskSaver ImageSaver, purger storagegrpc.Purger, access storagegrpc.AccessTracker, thumbnails storagegrpc.Thumbnailer, transforms storagegrpc.Transformer, limits storagegrpc.Limits, policy storagegrpc.Policy) *App {
 	//TODO: в конфиг
 	uploadDownloadSemaphore := middleware.NewSemaphore(10) // Upload/Download
 	listFilesSemaphore := middleware.NewSemaphore(100)     // ListFiles
@@ -36,7 +47,7 @@ func NewApp(log *logrus.Logger, port int, storage storagegrpc.Storage, diskSaver
 	// Создаем gRPC сервер с middleware
 	grpcServer := grpc.NewServer(opts...)
 
-	storagegrpc.RegisterServer(grpcServer, log, storage, diskSaver)
+	storagegrpc.RegisterServer(grpcServer, log, storage, diskSaver, purger, access, thumbnails, transforms, limits, policy)
 
 	return &App{
 		log:        log,
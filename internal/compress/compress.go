@@ -0,0 +1,98 @@
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// None - содержимое хранится как есть
+	None = ""
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Policy сопоставляет MIME-типу алгоритм сжатия. Ключи - точный тип ("application/json"),
+// вся группа ("text/*") или любой тип ("*")
+type Policy map[string]string
+
+// ParsePolicy проверяет алгоритмы из конфига
+func ParsePolicy(rules map[string]string) (Policy, error) {
+	const op = "compress.ParsePolicy"
+
+	policy := make(Policy, len(rules))
+	for mimeType, encoding := range rules {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != Gzip && encoding != Zstd && encoding != "none" {
+			return nil, fmt.Errorf("%s: unknown encoding %q for %s", op, encoding, mimeType)
+		}
+		if encoding == "none" {
+			encoding = None
+		}
+		policy[strings.ToLower(strings.TrimSpace(mimeType))] = encoding
+	}
+
+	return policy, nil
+}
+
+// Encoding возвращает алгоритм для mimeType, None - не сжимать
+func (p Policy) Encoding(mimeType string) string {
+	mimeType = strings.ToLower(mimeType)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+
+	if encoding, ok := p[mimeType]; ok {
+		return encoding
+	}
+	if group, _, ok := strings.Cut(mimeType, "/"); ok {
+		if encoding, ok := p[group+"/*"]; ok {
+			return encoding
+		}
+	}
+	return p["*"]
+}
+
+// NewWriter оборачивает w сжатием encoding. Close дописывает хвост потока, но не закрывает w
+func NewWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("compress.NewWriter: unknown encoding %q", encoding)
+	}
+}
+
+// NewReader распаковывает r, сжатый encoding
+func NewReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("compress.NewReader: unknown encoding %q", encoding)
+	}
+}
+
+// Extension - расширение файла для потока, сжатого encoding
+func Extension(encoding string) string {
+	switch encoding {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
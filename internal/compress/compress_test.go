@@ -0,0 +1,80 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(map[string]string{" Text/* ": "GZIP", "application/json": "zstd", "image/png": "none", "*": "zstd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{mimeType: "application/json", want: Zstd},
+		{mimeType: "text/plain; charset=utf-8", want: Gzip},
+		{mimeType: "TEXT/HTML", want: Gzip},
+		{mimeType: "image/png", want: None},
+		{mimeType: "image/jpeg", want: Zstd},
+	}
+	for _, tt := range tests {
+		if got := policy.Encoding(tt.mimeType); got != tt.want {
+			t.Fatalf("%s: %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+
+	if got := Policy(nil).Encoding("text/plain"); got != None {
+		t.Fatalf("empty policy: %q", got)
+	}
+	if _, err := ParsePolicy(map[string]string{"text/*": "brotli"}); err == nil {
+		t.Fatalf("unknown encoding accepted")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible line of text\n"), 1000)
+
+	for _, encoding := range []string{Gzip, Zstd} {
+		t.Run(encoding, func(t *testing.T) {
+			var compressed bytes.Buffer
+			w, err := NewWriter(encoding, &compressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if compressed.Len() >= len(data) {
+				t.Fatalf("compressed to %d bytes from %d", compressed.Len(), len(data))
+			}
+
+			r, err := NewReader(encoding, &compressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("round trip gives %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+
+	if _, err := NewWriter("brotli", io.Discard); err == nil {
+		t.Fatalf("writer for an unknown encoding")
+	}
+	if _, err := NewReader(None, bytes.NewReader(data)); err == nil {
+		t.Fatalf("reader for no encoding")
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDSNString(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  DSN
+		want string
+	}{
+		{name: "file path", dsn: "./internal/storage/sqlite/image.db", want: "./internal/storage/sqlite/image.db"},
+		{name: "url", dsn: "postgres://app:secret@db:5432/images?sslmode=disable", want: "postgres://app:xxxxx@db:5432/images?sslmode=disable"},
+		{name: "url without password", dsn: "postgres://app@db:5432/images", want: "postgres://app@db:5432/images"},
+		{name: "key value", dsn: "host=db user=app password=secret dbname=images", want: "host=db user=app password=*** dbname=images"},
+		{name: "quoted key value", dsn: "host=db password='s e c' dbname=images", want: "host=db password=*** dbname=images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dsn.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Секреты вложенных структур не попадают в вывод конфига
+func TestConfigHidesSecrets(t *testing.T) {
+	var cfg Config
+	cfg.StoragePath = "postgres://app:dbsecret@db:5432/images"
+	cfg.S3.SecretAccessKey = "s3secret"
+	cfg.Encryption.Key = "masterkey"
+
+	out := fmt.Sprint(cfg) + fmt.Sprint(&cfg)
+	for _, secret := range []string{"dbsecret", "s3secret", "masterkey"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("%q printed in %s", secret, out)
+		}
+	}
+}
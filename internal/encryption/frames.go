@@ -0,0 +1,193 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Содержимое шифруется кадрами по FrameSize байт, каждый кадр - отдельный AES-GCM с тегом.
+// Nonce - номер кадра (ключ данных у каждого файла свой, поэтому nonce не повторяется),
+// в associated data - признак последнего кадра, чтобы обрезанный файл не расшифровался.
+// Кадры одного размера, поэтому диапазон содержимого читается без расшифровки всего файла
+const (
+	FrameSize     = 64 * 1024
+	frameOverhead = 16
+	sealedFrame   = FrameSize + frameOverhead
+)
+
+var ErrCorrupted = errors.New("encrypted stream is corrupted")
+
+// EncryptedSize - размер зашифрованного содержимого длиной plain
+func EncryptedSize(plain int64) int64 {
+	return plain + frameCount(plain)*frameOverhead
+}
+
+// PlainSize - размер содержимого по размеру зашифрованного потока
+func PlainSize(stored int64) int64 {
+	frames := (stored + sealedFrame - 1) / sealedFrame
+	return stored - frames*frameOverhead
+}
+
+// FrameRange вычисляет, какие байты зашифрованного потока нужны для диапазона [offset, offset+length)
+// содержимого размером plainSize (length 0 - до конца). skip - сколько байт расшифрованного
+// первого кадра пропустить
+func FrameRange(offset int64, length int64, plainSize int64) (firstFrame, storedOffset, storedLength, skip int64) {
+	end := plainSize
+	if length > 0 && offset+length < end {
+		end = offset + length
+	}
+
+	firstFrame = offset / FrameSize
+	lastFrame := firstFrame
+	if end > offset {
+		lastFrame = (end - 1) / FrameSize
+	}
+
+	storedOffset = firstFrame * sealedFrame
+	storedLength = (lastFrame - firstFrame + 1) * sealedFrame
+	skip = offset - firstFrame*FrameSize
+
+	return firstFrame, storedOffset, storedLength, skip
+}
+
+func frameCount(plain int64) int64 {
+	if plain == 0 {
+		return 1
+	}
+	return (plain + FrameSize - 1) / FrameSize
+}
+
+func frameNonce(aead cipher.AEAD, index int64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(index))
+	return nonce
+}
+
+func frameAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type writer struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	index int64
+}
+
+// NewWriter шифрует поток ключом данных. Close дописывает последний кадр, но не закрывает w
+func NewWriter(dataKey []byte, w io.Writer) (io.WriteCloser, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption.NewWriter: %w", err)
+	}
+
+	return &writer{w: w, aead: aead, buf: make([]byte, 0, FrameSize)}, nil
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// Полный кадр запечатываем только когда пришли следующие данные: иначе он может оказаться последним
+		if len(w.buf) == FrameSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):FrameSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *writer) Close() error {
+	return w.seal(true)
+}
+
+func (w *writer) seal(last bool) error {
+	sealed := w.aead.Seal(nil, frameNonce(w.aead, w.index), w.buf, frameAAD(last))
+	if _, err := w.w.Write(sealed); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+type reader struct {
+	r         io.Reader
+	aead      cipher.AEAD
+	index     int64
+	lastFrame int64
+	sealed    []byte
+	plain     []byte
+	done      bool
+}
+
+// NewReader расшифровывает кадры начиная с firstFrame. stored - полный размер зашифрованного
+// потока, по нему определяется последний кадр
+func NewReader(dataKey []byte, r io.Reader, firstFrame int64, stored int64) (io.Reader, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption.NewReader: %w", err)
+	}
+
+	return &reader{
+		r:         r,
+		aead:      aead,
+		index:     firstFrame,
+		lastFrame: (stored+sealedFrame-1)/sealedFrame - 1,
+		sealed:    make([]byte, sealedFrame),
+	}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *reader) next() error {
+	n, err := io.ReadFull(r.r, r.sealed)
+	if err == io.EOF {
+		// Диапазонное чтение может закончиться раньше последнего кадра
+		r.done = true
+		return nil
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	last := r.index == r.lastFrame
+	if err == io.ErrUnexpectedEOF && !last {
+		return ErrCorrupted
+	}
+
+	plain, openErr := r.aead.Open(r.sealed[:0:0], frameNonce(r.aead, r.index), r.sealed[:n], frameAAD(last))
+	if openErr != nil {
+		return ErrCorrupted
+	}
+
+	r.plain = plain
+	r.index++
+	if last {
+		r.done = true
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testDataKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testContent(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// encrypt пишет data кусками по chunk байт, чтобы границы записей не совпадали с кадрами
+func encrypt(t *testing.T, key []byte, data []byte, chunk int) []byte {
+	t.Helper()
+
+	var stored bytes.Buffer
+	w, err := NewWriter(key, &stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return stored.Bytes()
+}
+
+func decrypt(key []byte, stored []byte, firstFrame int64, storedSize int64) ([]byte, error) {
+	r, err := NewReader(key, bytes.NewReader(stored), firstFrame, storedSize)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestFramesRoundTrip(t *testing.T) {
+	key := testDataKey(t)
+
+	tests := []struct {
+		name  string
+		size  int
+		chunk int
+	}{
+		{name: "empty", size: 0, chunk: 1},
+		{name: "one byte", size: 1, chunk: 1},
+		{name: "under a frame", size: FrameSize - 1, chunk: 1000},
+		{name: "exactly a frame", size: FrameSize, chunk: FrameSize},
+		{name: "frame and a byte", size: FrameSize + 1, chunk: 7919},
+		{name: "several frames", size: 3*FrameSize + 12345, chunk: 40000},
+		{name: "whole frames", size: 4 * FrameSize, chunk: 3 * FrameSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testContent(tt.size)
+			stored := encrypt(t, key, data, tt.chunk)
+
+			if int64(len(stored)) != EncryptedSize(int64(tt.size)) {
+				t.Fatalf("stored %d bytes, EncryptedSize says %d", len(stored), EncryptedSize(int64(tt.size)))
+			}
+			if PlainSize(int64(len(stored))) != int64(tt.size) {
+				t.Fatalf("PlainSize %d, want %d", PlainSize(int64(len(stored))), tt.size)
+			}
+
+			got, err := decrypt(key, stored, 0, int64(len(stored)))
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("decrypted %d bytes differ from the original %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestFrameRange(t *testing.T) {
+	tests := []struct {
+		name                                   string
+		offset, length, plain                  int64
+		firstFrame, storedOffset, storedLength int64
+		skip                                   int64
+	}{
+		{name: "whole small file", offset: 0, length: 0, plain: 100, firstFrame: 0, storedOffset: 0, storedLength: sealedFrame, skip: 0},
+		{name: "empty file", offset: 0, length: 0, plain: 0, firstFrame: 0, storedOffset: 0, storedLength: sealedFrame, skip: 0},
+		{name: "inside first frame", offset: 10, length: 20, plain: 3 * FrameSize, firstFrame: 0, storedOffset: 0, storedLength: sealedFrame, skip: 10},
+		{name: "last byte of a frame", offset: FrameSize - 1, length: 1, plain: 3 * FrameSize, firstFrame: 0, storedOffset: 0, storedLength: sealedFrame, skip: FrameSize - 1},
+		{name: "across a boundary", offset: FrameSize - 1, length: 2, plain: 3 * FrameSize, firstFrame: 0, storedOffset: 0, storedLength: 2 * sealedFrame, skip: FrameSize - 1},
+		{name: "second frame start", offset: FrameSize, length: 1, plain: 3 * FrameSize, firstFrame: 1, storedOffset: sealedFrame, storedLength: sealedFrame, skip: 0},
+		{name: "to the end", offset: FrameSize + 5, length: 0, plain: 3*FrameSize + 1, firstFrame: 1, storedOffset: sealedFrame, storedLength: 3 * sealedFrame, skip: 5},
+		{name: "length past the end", offset: 2 * FrameSize, length: 10 * FrameSize, plain: 2*FrameSize + 1, firstFrame: 2, storedOffset: 2 * sealedFrame, storedLength: sealedFrame, skip: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firstFrame, storedOffset, storedLength, skip := FrameRange(tt.offset, tt.length, tt.plain)
+			if firstFrame != tt.firstFrame || storedOffset != tt.storedOffset || storedLength != tt.storedLength || skip != tt.skip {
+				t.Fatalf("got frame %d, stored [%d, +%d), skip %d; want frame %d, stored [%d, +%d), skip %d",
+					firstFrame, storedOffset, storedLength, skip, tt.firstFrame, tt.storedOffset, tt.storedLength, tt.skip)
+			}
+		})
+	}
+}
+
+// TestFrameRangeDecrypt читает диапазоны так же, как сервер: только нужные кадры
+func TestFrameRangeDecrypt(t *testing.T) {
+	key := testDataKey(t)
+	data := testContent(3*FrameSize + 100)
+	stored := encrypt(t, key, data, 65000)
+	plain := int64(len(data))
+
+	ranges := []struct{ offset, length int64 }{
+		{0, 0},
+		{0, 1},
+		{FrameSize - 3, 6},
+		{FrameSize, FrameSize},
+		{2*FrameSize + 17, 0},
+		{plain - 1, 0},
+		{plain - 50, 1000},
+		{5, plain},
+	}
+	for _, rng := range ranges {
+		firstFrame, storedOffset, storedLength, skip := FrameRange(rng.offset, rng.length, plain)
+		end := min(storedOffset+storedLength, int64(len(stored)))
+
+		got, err := decrypt(key, stored[storedOffset:end], firstFrame, int64(len(stored)))
+		if err != nil {
+			t.Fatalf("range %v: %v", rng, err)
+		}
+		got = got[skip:]
+		want := data[rng.offset:]
+		if rng.length > 0 && rng.length < int64(len(want)) {
+			want = want[:rng.length]
+		}
+		if int64(len(got)) > int64(len(want)) {
+			got = got[:len(want)]
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("range %v: got %d bytes, want %d", rng, len(got), len(want))
+		}
+	}
+}
+
+func TestFramesCorrupted(t *testing.T) {
+	key := testDataKey(t)
+	data := testContent(2*FrameSize + 10)
+	stored := encrypt(t, key, data, FrameSize)
+
+	tests := []struct {
+		name       string
+		key        []byte
+		stored     func() []byte
+		firstFrame int64
+	}{
+		{name: "flipped byte", key: key, stored: func() []byte {
+			damaged := bytes.Clone(stored)
+			damaged[FrameSize/2] ^= 1
+			return damaged
+		}},
+		{name: "last frame cut off", key: key, stored: func() []byte { return stored[:2*sealedFrame] }},
+		{name: "truncated last frame", key: key, stored: func() []byte { return stored[:len(stored)-1] }},
+		{name: "frames swapped", key: key, stored: func() []byte {
+			swapped := bytes.Clone(stored)
+			copy(swapped, stored[sealedFrame:2*sealedFrame])
+			copy(swapped[sealedFrame:], stored[:sealedFrame])
+			return swapped
+		}},
+		{name: "wrong first frame", key: key, stored: func() []byte { return stored[sealedFrame:] }, firstFrame: 0},
+		{name: "wrong key", key: testDataKey(t), stored: func() []byte { return stored }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damaged := tt.stored()
+			if _, err := decrypt(tt.key, damaged, tt.firstFrame, int64(len(damaged))); !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("got %v, want %v", err, ErrCorrupted)
+			}
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	newMasterKey := func() *MasterKey {
+		raw := make([]byte, KeySize)
+		if _, err := rand.Read(raw); err != nil {
+			t.Fatal(err)
+		}
+		key, err := ParseMasterKey(base64.StdEncoding.EncodeToString(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	current, previous, unknown := newMasterKey(), newMasterKey(), newMasterKey()
+	keyring := NewKeyring(current, previous)
+	dataKey := testDataKey(t)
+
+	tests := []struct {
+		name    string
+		wrapper *MasterKey
+		keyID   string
+		wantErr error
+	}{
+		{name: "current key", wrapper: current, keyID: current.ID()},
+		{name: "previous key", wrapper: previous, keyID: previous.ID()},
+		{name: "unknown key", wrapper: unknown, keyID: unknown.ID(), wantErr: ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped, err := tt.wrapper.Wrap(dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := keyring.Unwrap(tt.keyID, wrapped)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(got, dataKey) {
+				t.Fatalf("unwrapped another key")
+			}
+		})
+	}
+
+	// Ключ, закрытый одним мастер-ключом, не открывается другим
+	wrapped, err := previous.Wrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.Unwrap(current.ID(), wrapped); err == nil {
+		t.Fatalf("key wrapped by the previous master key opened with the current one")
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	dataKey := testDataKey(t)
+	labels := []string{"variant/thumb_256", "variant/thumb_64", "variant/thumb_256/00", ""}
+
+	seen := make(map[string]string)
+	for _, label := range labels {
+		derived := DeriveKey(dataKey, label)
+		if len(derived) != KeySize {
+			t.Fatalf("%q: derived %d bytes", label, len(derived))
+		}
+		if !bytes.Equal(derived, DeriveKey(dataKey, label)) {
+			t.Fatalf("%q: derivation is not deterministic", label)
+		}
+		if other, ok := seen[string(derived)]; ok {
+			t.Fatalf("%q and %q give the same key", label, other)
+		}
+		seen[string(derived)] = label
+	}
+	if bytes.Equal(DeriveKey(dataKey, labels[0]), DeriveKey(testDataKey(t), labels[0])) {
+		t.Fatalf("different data keys give the same derived key")
+	}
+}
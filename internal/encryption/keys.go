@@ -0,0 +1,174 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"imagestorage/internal/config"
+)
+
+// KeySize - длина мастер-ключа и ключей данных (AES-256)
+const KeySize = 32
+
+var ErrUnknownKey = errors.New("unknown master key")
+
+// MasterKey шифрует ключи данных отдельных файлов
+type MasterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// ParseMasterKey разбирает ключ в base64
+func ParseMasterKey(encoded string) (*MasterKey, error) {
+	const op = "encryption.ParseMasterKey"
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%s: master key must be %d bytes, got %d", op, KeySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// id позволяет понять, каким ключом закрыт ключ данных, не раскрывая сам ключ
+	sum := sha256.Sum256(key)
+	return &MasterKey{id: hex.EncodeToString(sum[:8]), aead: aead}, nil
+}
+
+// ReadKeyFile читает мастер-ключ в base64 из файла
+func ReadKeyFile(path string) (*MasterKey, error) {
+	const op = "encryption.ReadKeyFile"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ParseMasterKey(string(data))
+}
+
+func (m *MasterKey) ID() string {
+	return m.id
+}
+
+// Wrap закрывает ключ данных мастер-ключом: nonce || ciphertext
+func (m *MasterKey) Wrap(dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return m.aead.Seal(nonce, nonce, dataKey, []byte(m.id)), nil
+}
+
+func (m *MasterKey) Unwrap(wrapped []byte) ([]byte, error) {
+	nonceSize := m.aead.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	return m.aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(m.id))
+}
+
+// Keyring - текущий мастер-ключ и прежние, которые еще нужны для чтения во время ротации
+type Keyring struct {
+	current *MasterKey
+	keys    map[string]*MasterKey
+}
+
+func NewKeyring(current *MasterKey, previous ...*MasterKey) *Keyring {
+	keys := map[string]*MasterKey{current.ID(): current}
+	for _, key := range previous {
+		keys[key.ID()] = key
+	}
+
+	return &Keyring{current: current, keys: keys}
+}
+
+// LoadKeyring собирает ключи из конфига. nil без ошибки - шифрование выключено
+func LoadKeyring(cfg config.Encryption) (*Keyring, error) {
+	const op = "encryption.LoadKeyring"
+
+	var current *MasterKey
+	var err error
+	switch {
+	case cfg.Key != "" && cfg.KeyFile != "":
+		return nil, fmt.Errorf("%s: set either ENCRYPTION_KEY or ENCRYPTION_KEY_FILE", op)
+	case cfg.Key != "":
+		current, err = ParseMasterKey(string(cfg.Key))
+	case cfg.KeyFile != "":
+		current, err = ReadKeyFile(cfg.KeyFile)
+	default:
+		if len(cfg.PreviousKeyFiles) > 0 {
+			return nil, fmt.Errorf("%s: previous keys require a current key", op)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	previous := make([]*MasterKey, 0, len(cfg.PreviousKeyFiles))
+	for _, path := range cfg.PreviousKeyFiles {
+		key, err := ReadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		previous = append(previous, key)
+	}
+
+	return NewKeyring(current, previous...), nil
+}
+
+func (k *Keyring) Current() *MasterKey {
+	return k.current
+}
+
+// Unwrap открывает ключ данных мастер-ключом keyID
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("encryption.Keyring.Unwrap: %w: %s", ErrUnknownKey, keyID)
+	}
+
+	return key.Unwrap(wrapped)
+}
+
+// NewDataKey создает случайный ключ данных для одного файла
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeriveKey выводит из ключа данных отдельный ключ для label (HMAC-SHA256). Nonce кадров - их номера,
+// поэтому другое содержимое, связанное с тем же файлом, нельзя шифровать самим ключом данных
+func DeriveKey(dataKey []byte, label string) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,524 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Режимы вырезания EXIF из сохраняемых JPEG
+const (
+	// StripNone - файл сохраняется как есть
+	StripNone = "none"
+	// StripGPS - вырезаются координаты, остальные метаданные остаются
+	StripGPS = "gps"
+	// StripAll - вырезаются EXIF и XMP целиком, вместе с ориентацией
+	StripAll = "all"
+)
+
+var (
+	ErrNotJPEG   = errors.New("not a JPEG image")
+	ErrMalformed = errors.New("malformed EXIF")
+	ErrMode      = errors.New("unknown EXIF strip mode")
+)
+
+// Metadata - то, что сервер берет из EXIF
+type Metadata struct {
+	CameraMake  string
+	CameraModel string
+	LensMake    string
+	LensModel   string
+	// CapturedAt - время съемки, нулевое, если его нет. Без смещения часового пояса считается UTC
+	CapturedAt time.Time
+	// Orientation - значение тега Orientation (1-8), 0 - тега нет
+	Orientation int
+	// HasGPS - есть широта и долгота. Altitude в метрах, отрицательная - ниже уровня моря
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// ParseMode проверяет режим вырезания, пустой режим - StripNone
+func ParseMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "", StripNone:
+		return StripNone, nil
+	case StripGPS:
+		return StripGPS, nil
+	case StripAll:
+		return StripAll, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrMode, mode)
+}
+
+// Stronger - режим, который вырезает больше. Пустой режим - StripNone
+func Stronger(a string, b string) string {
+	rank := map[string]int{StripNone: 0, StripGPS: 1, StripAll: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	if rank[a] == 0 {
+		return StripNone
+	}
+	return a
+}
+
+const (
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// Read читает EXIF из заголовка JPEG. Возвращает false, если EXIF нет
+func Read(r io.Reader) (Metadata, bool, error) {
+	var metadata Metadata
+	found := false
+	err := segments(bufio.NewReader(r), func(marker byte, payload []byte) (bool, error) {
+		if marker != markerAPP1 || !bytes.HasPrefix(payload, exifHeader) {
+			return true, nil
+		}
+		var err error
+		metadata, err = parse(payload[len(exifHeader):])
+		found = err == nil
+		return false, err
+	})
+	return metadata, found, err
+}
+
+// Strip копирует JPEG из r в w, вырезая метаданные по режиму mode. Возвращает, было ли что вырезать
+func Strip(r io.Reader, w io.Writer, mode string) (bool, error) {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.Write([]byte{0xFF, markerSOI}); err != nil {
+		return false, err
+	}
+
+	stripped := false
+	err := segments(br, func(marker byte, payload []byte) (bool, error) {
+		if marker == markerAPP1 && mode != StripNone {
+			switch {
+			case mode == StripAll && (bytes.HasPrefix(payload, exifHeader) || bytes.HasPrefix(payload, xmpHeader)):
+				stripped = true
+				return true, nil
+			case bytes.HasPrefix(payload, exifHeader):
+				payload = bytes.Clone(payload)
+				removed, err := stripGPS(payload[len(exifHeader):])
+				if err != nil {
+					return false, err
+				}
+				stripped = stripped || removed
+			case bytes.HasPrefix(payload, xmpHeader) && bytes.Contains(payload, []byte("GPS")):
+				// Координаты в XMP вырезать по месту нельзя, поэтому такой XMP вырезается целиком
+				stripped = true
+				return true, nil
+			}
+		}
+		return true, writeSegment(bw, marker, payload)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// Дальше сжатые данные изображения, они копируются как есть
+	if _, err := bw.Write([]byte{0xFF, markerSOS}); err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(bw, br); err != nil {
+		return false, err
+	}
+	return stripped, bw.Flush()
+}
+
+// segments читает сегменты заголовка JPEG до начала данных изображения (SOS) и передает их в fn.
+// fn возвращает false, чтобы остановиться раньше. После SOS r стоит на первом байте его сегмента
+func segments(r *bufio.Reader, fn func(marker byte, payload []byte) (bool, error)) error {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, markerSOI} {
+		return ErrNotJPEG
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotJPEG, err)
+		}
+		if b != 0xFF {
+			return fmt.Errorf("%w: expected a marker, got %#x", ErrNotJPEG, b)
+		}
+		marker, err := r.ReadByte()
+		for err == nil && marker == 0xFF {
+			// Маркеру могут предшествовать байты-заполнители 0xFF
+			marker, err = r.ReadByte()
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotJPEG, err)
+		}
+
+		switch {
+		case marker == markerSOS:
+			return nil
+		case marker == markerEOI:
+			return fmt.Errorf("%w: no image data", ErrNotJPEG)
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD7:
+			// Маркеры без длины и содержимого
+			if _, err := fn(marker, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotJPEG, err)
+		}
+		size := int(binary.BigEndian.Uint16(length[:]))
+		if size < 2 {
+			return fmt.Errorf("%w: segment length %d", ErrNotJPEG, size)
+		}
+		payload := make([]byte, size-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotJPEG, err)
+		}
+
+		next, err := fn(marker, payload)
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+func writeSegment(w io.Writer, marker byte, payload []byte) error {
+	if marker == 0x01 || marker >= 0xD0 && marker <= 0xD7 {
+		_, err := w.Write([]byte{0xFF, marker})
+		return err
+	}
+	header := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(payload)+2))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Теги, которые читает сервер
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+	tagLensMake         = 0xA433
+	tagLensModel        = 0xA434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// tiff - данные TIFF из сегмента EXIF, все смещения считаются от их начала
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// entry - запись IFD. value - смещение значения: в самой записи, если оно не длиннее 4 байт
+type entry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value int
+}
+
+// Размеры типов TIFF в байтах
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+func newTIFF(data []byte) (*tiff, int, error) {
+	if len(data) < 8 {
+		return nil, 0, ErrMalformed
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, fmt.Errorf("%w: byte order %q", ErrMalformed, data[:2])
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, 0, fmt.Errorf("%w: not a TIFF header", ErrMalformed)
+	}
+	return t, int(t.order.Uint32(data[4:])), nil
+}
+
+// ifd читает записи IFD по смещению offset
+func (t *tiff) ifd(offset int) ([]entry, error) {
+	if offset < 8 || offset+2 > len(t.data) {
+		return nil, fmt.Errorf("%w: IFD offset %d", ErrMalformed, offset)
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	if offset+2+count*12 > len(t.data) {
+		return nil, fmt.Errorf("%w: IFD at %d is truncated", ErrMalformed, offset)
+	}
+
+	entries := make([]entry, 0, count)
+	for i := 0; i < count; i++ {
+		at := offset + 2 + i*12
+		e := entry{
+			tag:   t.order.Uint16(t.data[at:]),
+			typ:   t.order.Uint16(t.data[at+2:]),
+			count: t.order.Uint32(t.data[at+4:]),
+			value: at + 8,
+		}
+		size, ok := typeSizes[e.typ]
+		if !ok {
+			// Неизвестный тип пропускаем: длина его значения неизвестна
+			continue
+		}
+		length := int64(size) * int64(e.count)
+		if length > 4 {
+			e.value = int(t.order.Uint32(t.data[at+8:]))
+		}
+		if int64(e.value)+length > int64(len(t.data)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (t *tiff) length(e entry) int {
+	return typeSizes[e.typ] * int(e.count)
+}
+
+func (t *tiff) ascii(e entry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	value := t.data[e.value : e.value+t.length(e)]
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(string(value))
+}
+
+func (t *tiff) uint(e entry) (uint32, bool) {
+	if e.count == 0 {
+		return 0, false
+	}
+	switch e.typ {
+	case 1, 7:
+		return uint32(t.data[e.value]), true
+	case 3:
+		return uint32(t.order.Uint16(t.data[e.value:])), true
+	case 4:
+		return t.order.Uint32(t.data[e.value:]), true
+	}
+	return 0, false
+}
+
+func (t *tiff) rationals(e entry) []float64 {
+	if e.typ != 5 {
+		return nil
+	}
+	values := make([]float64, e.count)
+	for i := range values {
+		at := e.value + i*8
+		numerator, denominator := t.order.Uint32(t.data[at:]), t.order.Uint32(t.data[at+4:])
+		if denominator == 0 {
+			return nil
+		}
+		values[i] = float64(numerator) / float64(denominator)
+	}
+	return values
+}
+
+func find(entries []entry, tag uint16) (entry, bool) {
+	for _, e := range entries {
+		if e.tag == tag {
+			return e, true
+		}
+	}
+	return entry{}, false
+}
+
+func parse(data []byte) (Metadata, error) {
+	var metadata Metadata
+
+	t, offset, err := newTIFF(data)
+	if err != nil {
+		return metadata, err
+	}
+	ifd0, err := t.ifd(offset)
+	if err != nil {
+		return metadata, err
+	}
+
+	if e, ok := find(ifd0, tagMake); ok {
+		metadata.CameraMake = t.ascii(e)
+	}
+	if e, ok := find(ifd0, tagModel); ok {
+		metadata.CameraModel = t.ascii(e)
+	}
+	if e, ok := find(ifd0, tagOrientation); ok {
+		if orientation, ok := t.uint(e); ok && orientation >= 1 && orientation <= 8 {
+			metadata.Orientation = int(orientation)
+		}
+	}
+	var dateTime string
+	if e, ok := find(ifd0, tagDateTime); ok {
+		dateTime = t.ascii(e)
+	}
+
+	// Поддиректории с ошибками пропускаются: сломанный EXIF не должен терять то, что прочиталось
+	var offsetTime string
+	if e, ok := find(ifd0, tagExifIFD); ok {
+		if at, ok := t.uint(e); ok {
+			if exifIFD, err := t.ifd(int(at)); err == nil {
+				if e, ok := find(exifIFD, tagDateTimeOriginal); ok {
+					dateTime = t.ascii(e)
+				}
+				if e, ok := find(exifIFD, tagOffsetOriginal); ok {
+					offsetTime = t.ascii(e)
+				}
+				if e, ok := find(exifIFD, tagLensMake); ok {
+					metadata.LensMake = t.ascii(e)
+				}
+				if e, ok := find(exifIFD, tagLensModel); ok {
+					metadata.LensModel = t.ascii(e)
+				}
+			}
+		}
+	}
+	metadata.CapturedAt = parseTime(dateTime, offsetTime)
+
+	if e, ok := find(ifd0, tagGPSIFD); ok {
+		if at, ok := t.uint(e); ok {
+			if gpsIFD, err := t.ifd(int(at)); err == nil {
+				t.gps(gpsIFD, &metadata)
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+func (t *tiff) gps(entries []entry, metadata *Metadata) {
+	latitude, okLat := t.coordinate(entries, tagGPSLatitude, tagGPSLatitudeRef, "S")
+	longitude, okLon := t.coordinate(entries, tagGPSLongitude, tagGPSLongitudeRef, "W")
+	if !okLat || !okLon {
+		return
+	}
+	metadata.HasGPS = true
+	metadata.Latitude = latitude
+	metadata.Longitude = longitude
+
+	if e, ok := find(entries, tagGPSAltitude); ok {
+		if values := t.rationals(e); len(values) == 1 {
+			metadata.Altitude = values[0]
+			if e, ok := find(entries, tagGPSAltitudeRef); ok {
+				if ref, ok := t.uint(e); ok && ref == 1 {
+					metadata.Altitude = -metadata.Altitude
+				}
+			}
+		}
+	}
+}
+
+// coordinate - градусы из тега вида градусы, минуты, секунды. negative - значение ref для южной широты
+// или западной долготы
+func (t *tiff) coordinate(entries []entry, tag uint16, refTag uint16, negative string) (float64, bool) {
+	e, ok := find(entries, tag)
+	if !ok {
+		return 0, false
+	}
+	values := t.rationals(e)
+	if len(values) != 3 {
+		return 0, false
+	}
+	degrees := values[0] + values[1]/60 + values[2]/3600
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return 0, false
+	}
+	if e, ok := find(entries, refTag); ok && strings.EqualFold(t.ascii(e), negative) {
+		degrees = -degrees
+	}
+	return degrees, true
+}
+
+// parseTime читает время EXIF "2006:01:02 15:04:05" со смещением вида "+03:00"
+func parseTime(value string, offset string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t
+		}
+	}
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// stripGPS стирает по месту GPS IFD в данных TIFF: записи и их значения обнуляются, а IFD становится пустым,
+// так что смещения остальных тегов не меняются. Возвращает false, если координат не было
+func stripGPS(data []byte) (bool, error) {
+	t, offset, err := newTIFF(data)
+	if err != nil {
+		return false, err
+	}
+	ifd0, err := t.ifd(offset)
+	if err != nil {
+		return false, err
+	}
+	e, ok := find(ifd0, tagGPSIFD)
+	if !ok {
+		return false, nil
+	}
+	at, ok := t.uint(e)
+	if !ok {
+		return false, nil
+	}
+	gpsIFD, err := t.ifd(int(at))
+	if err != nil {
+		return false, err
+	}
+	if len(gpsIFD) == 0 && t.order.Uint16(data[at:]) == 0 {
+		return false, nil
+	}
+
+	for _, e := range gpsIFD {
+		if length := t.length(e); length > 4 {
+			clear(data[e.value : e.value+length])
+		}
+	}
+	count := int(t.order.Uint16(data[at:]))
+	// Вместе с записями обнуляется и смещение следующего IFD: у GPS IFD его не бывает
+	end := min(int(at)+2+count*12+4, len(data))
+	clear(data[at:end])
+	return true, nil
+}
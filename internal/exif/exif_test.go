@@ -0,0 +1,389 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// tag - запись IFD для тестовых EXIF, value уже в порядке байт файла
+type tag struct {
+	id    uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiTag(id uint16, s string) tag {
+	return tag{id: id, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func shortTag(order binary.AppendByteOrder, id uint16, v uint16) tag {
+	return tag{id: id, typ: 3, count: 1, value: order.AppendUint16(nil, v)}
+}
+
+func byteTag(id uint16, v byte) tag {
+	return tag{id: id, typ: 1, count: 1, value: []byte{v}}
+}
+
+// rationalTag - значения парами числитель, знаменатель
+func rationalTag(order binary.AppendByteOrder, id uint16, values ...uint32) tag {
+	var value []byte
+	for _, v := range values {
+		value = order.AppendUint32(value, v)
+	}
+	return tag{id: id, typ: 5, count: uint32(len(values) / 2), value: value}
+}
+
+// buildTIFF собирает данные TIFF: заголовок, IFD0, Exif IFD и GPS IFD, если они заданы, и значения после них
+func buildTIFF(order binary.AppendByteOrder, ifd0 []tag, exifIFD []tag, gpsIFD []tag) []byte {
+	ifdSize := func(n int) int { return 2 + 12*n + 4 }
+
+	ifd0 = append([]tag(nil), ifd0...)
+	if exifIFD != nil {
+		ifd0 = append(ifd0, tag{id: tagExifIFD, typ: 4, count: 1})
+	}
+	if gpsIFD != nil {
+		ifd0 = append(ifd0, tag{id: tagGPSIFD, typ: 4, count: 1})
+	}
+
+	ifd0At := 8
+	exifAt := ifd0At + ifdSize(len(ifd0))
+	gpsAt := exifAt
+	if exifIFD != nil {
+		gpsAt += ifdSize(len(exifIFD))
+	}
+	valuesAt := gpsAt
+	if gpsIFD != nil {
+		valuesAt += ifdSize(len(gpsIFD))
+	}
+	for i := range ifd0 {
+		switch ifd0[i].id {
+		case tagExifIFD:
+			ifd0[i].value = order.AppendUint32(nil, uint32(exifAt))
+		case tagGPSIFD:
+			ifd0[i].value = order.AppendUint32(nil, uint32(gpsAt))
+		}
+	}
+
+	var values []byte
+	writeIFD := func(data []byte, tags []tag) []byte {
+		data = order.AppendUint16(data, uint16(len(tags)))
+		for _, t := range tags {
+			data = order.AppendUint16(data, t.id)
+			data = order.AppendUint16(data, t.typ)
+			data = order.AppendUint32(data, t.count)
+			if len(t.value) > 4 {
+				data = order.AppendUint32(data, uint32(valuesAt+len(values)))
+				values = append(values, t.value...)
+				continue
+			}
+			inline := make([]byte, 4)
+			copy(inline, t.value)
+			data = append(data, inline...)
+		}
+		return order.AppendUint32(data, 0)
+	}
+
+	data := []byte("II")
+	if order == binary.BigEndian {
+		data = []byte("MM")
+	}
+	data = order.AppendUint16(data, 42)
+	data = order.AppendUint32(data, uint32(ifd0At))
+	data = writeIFD(data, ifd0)
+	if exifIFD != nil {
+		data = writeIFD(data, exifIFD)
+	}
+	if gpsIFD != nil {
+		data = writeIFD(data, gpsIFD)
+	}
+	return append(data, values...)
+}
+
+func segment(marker byte, payload []byte) []byte {
+	var buf bytes.Buffer
+	if err := writeSegment(&buf, marker, payload); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func exifSegment(tiffData []byte) []byte {
+	return segment(markerAPP1, append(bytes.Clone(exifHeader), tiffData...))
+}
+
+func xmpSegment(packet string) []byte {
+	return segment(markerAPP1, append(bytes.Clone(xmpHeader), packet...))
+}
+
+var (
+	jfifSegment = segment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	// scan - сегмент SOS и сжатые данные, Strip должен копировать их как есть
+	scan = append(segment(markerSOS, []byte{1, 1, 0, 0, 0x3F, 0}), 0x12, 0xFF, 0x00, 0x34, 0xFF, markerEOI)
+)
+
+func buildJPEG(segments ...[]byte) []byte {
+	data := []byte{0xFF, markerSOI}
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	return append(data, scan...)
+}
+
+// cameraTIFF - EXIF с камерой, объективом, временем съемки и координатами
+func cameraTIFF(order binary.AppendByteOrder, latRef string, lonRef string, altRef byte) []byte {
+	return buildTIFF(order,
+		[]tag{
+			asciiTag(tagMake, "Canon"),
+			asciiTag(tagModel, "EOS R5 "),
+			shortTag(order, tagOrientation, 6),
+			asciiTag(tagDateTime, "2020:01:01 00:00:00"),
+		},
+		[]tag{
+			asciiTag(tagDateTimeOriginal, "2023:07:15 14:30:05"),
+			asciiTag(tagOffsetOriginal, "+03:00"),
+			asciiTag(tagLensMake, "Canon"),
+			asciiTag(tagLensModel, "RF24-70mm F2.8 L IS USM"),
+		},
+		[]tag{
+			asciiTag(tagGPSLatitudeRef, latRef),
+			rationalTag(order, tagGPSLatitude, 55, 1, 45, 1, 36, 1),
+			asciiTag(tagGPSLongitudeRef, lonRef),
+			rationalTag(order, tagGPSLongitude, 37, 1, 37, 1, 12, 10),
+			byteTag(tagGPSAltitudeRef, altRef),
+			rationalTag(order, tagGPSAltitude, 1565, 10),
+		},
+	)
+}
+
+func TestRead(t *testing.T) {
+	captured := time.Date(2023, 7, 15, 14, 30, 5, 0, time.FixedZone("", 3*3600))
+	latitude := 55 + 45.0/60 + 36.0/3600
+	longitude := 37 + 37.0/60 + 1.2/3600
+	camera := Metadata{
+		CameraMake: "Canon", CameraModel: "EOS R5", LensMake: "Canon", LensModel: "RF24-70mm F2.8 L IS USM",
+		CapturedAt: captured, Orientation: 6,
+		HasGPS: true, Latitude: latitude, Longitude: longitude, Altitude: 156.5,
+	}
+	southWest := camera
+	southWest.Latitude, southWest.Longitude, southWest.Altitude = -latitude, -longitude, -156.5
+
+	tests := []struct {
+		name      string
+		data      []byte
+		want      Metadata
+		wantFound bool
+		wantErr   error
+	}{
+		{name: "little endian", data: buildJPEG(jfifSegment, exifSegment(cameraTIFF(binary.LittleEndian, "N", "E", 0))),
+			want: camera, wantFound: true},
+		{name: "big endian", data: buildJPEG(exifSegment(cameraTIFF(binary.BigEndian, "N", "E", 0))),
+			want: camera, wantFound: true},
+		{name: "south west below sea level", data: buildJPEG(exifSegment(cameraTIFF(binary.BigEndian, "S", "w", 1))),
+			want: southWest, wantFound: true},
+		{name: "exif after xmp", data: buildJPEG(xmpSegment("<x:xmpmeta/>"), exifSegment(cameraTIFF(binary.LittleEndian, "N", "E", 0))),
+			want: camera, wantFound: true},
+		{name: "no exif", data: buildJPEG(jfifSegment, xmpSegment("<x:xmpmeta/>"))},
+		{name: "ifd0 time without offset is utc",
+			data: buildJPEG(exifSegment(buildTIFF(binary.LittleEndian, []tag{asciiTag(tagDateTime, "2021:02:03 04:05:06")}, nil, nil))),
+			want: Metadata{CapturedAt: time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)}, wantFound: true},
+		{name: "bad values are dropped",
+			data: buildJPEG(exifSegment(buildTIFF(binary.LittleEndian,
+				[]tag{shortTag(binary.LittleEndian, tagOrientation, 9), asciiTag(tagDateTime, "yesterday")},
+				nil,
+				[]tag{
+					rationalTag(binary.LittleEndian, tagGPSLatitude, 55, 0, 45, 1, 36, 1),
+					rationalTag(binary.LittleEndian, tagGPSLongitude, 37, 1, 37, 1, 12, 10),
+				}))),
+			wantFound: true},
+		{name: "not a jpeg", data: []byte("\x89PNG\r\n\x1a\n"), wantErr: ErrNotJPEG},
+		{name: "empty", data: nil, wantErr: ErrNotJPEG},
+		{name: "truncated segment", data: buildJPEG(exifSegment(cameraTIFF(binary.LittleEndian, "N", "E", 0)))[:40], wantErr: ErrNotJPEG},
+		{name: "garbage between segments", data: append([]byte{0xFF, markerSOI, 0x00}, scan...), wantErr: ErrNotJPEG},
+		{name: "segment length below two", data: append([]byte{0xFF, markerSOI, 0xFF, markerAPP1, 0x00, 0x01}, scan...), wantErr: ErrNotJPEG},
+		{name: "image without scan", data: []byte{0xFF, markerSOI, 0xFF, markerEOI}, wantErr: ErrNotJPEG},
+		{name: "short tiff", data: buildJPEG(exifSegment([]byte("II*"))), wantErr: ErrMalformed},
+		{name: "unknown byte order", data: buildJPEG(exifSegment([]byte("XX\x2a\x00\x08\x00\x00\x00\x00\x00"))), wantErr: ErrMalformed},
+		{name: "not a tiff", data: buildJPEG(exifSegment([]byte("II\x2b\x00\x08\x00\x00\x00\x00\x00"))), wantErr: ErrMalformed},
+		{name: "ifd0 outside data", data: buildJPEG(exifSegment([]byte("II\x2a\x00\xff\x00\x00\x00"))), wantErr: ErrMalformed},
+		{name: "truncated ifd0", data: buildJPEG(exifSegment([]byte("II\x2a\x00\x08\x00\x00\x00\x05\x00\x0f\x01"))), wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := Read(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("found %v, want %v", found, tt.wantFound)
+			}
+			if !equalMetadata(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadBrokenSubIFD не теряет IFD0, если Exif IFD или GPS IFD указывают за пределы данных
+func TestReadBrokenSubIFD(t *testing.T) {
+	order := binary.LittleEndian
+	data := buildTIFF(order, []tag{asciiTag(tagMake, "Nikon")}, []tag{asciiTag(tagLensModel, "lens")}, []tag{})
+	// Смещения Exif IFD и GPS IFD - последние записи IFD0
+	for i := 1; i <= 2; i++ {
+		at := 8 + 2 + i*12 + 8
+		order.PutUint32(data[at:], 0xFFFF)
+	}
+
+	got, found, err := Read(bytes.NewReader(buildJPEG(exifSegment(data))))
+	if err != nil || !found {
+		t.Fatalf("got %v, found %v", err, found)
+	}
+	if got.CameraMake != "Nikon" || got.LensModel != "" || got.HasGPS {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func equalMetadata(a, b Metadata) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.CameraMake == b.CameraMake && a.CameraModel == b.CameraModel &&
+		a.LensMake == b.LensMake && a.LensModel == b.LensModel &&
+		a.CapturedAt.Equal(b.CapturedAt) && a.Orientation == b.Orientation &&
+		a.HasGPS == b.HasGPS && near(a.Latitude, b.Latitude) && near(a.Longitude, b.Longitude) && near(a.Altitude, b.Altitude)
+}
+
+func TestStrip(t *testing.T) {
+	withGPS := exifSegment(cameraTIFF(binary.BigEndian, "N", "E", 0))
+	withoutGPS := exifSegment(buildTIFF(binary.LittleEndian, []tag{asciiTag(tagMake, "Canon")}, nil, nil))
+	gpsXMP := xmpSegment(`<rdf:Description exif:GPSLatitude="55,45.6N"/>`)
+	plainXMP := xmpSegment(`<rdf:Description xmp:Rating="5"/>`)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		mode         string
+		wantStripped bool
+		// want - ожидаемый результат, nil - файл не должен измениться
+		want    []byte
+		wantErr error
+		check   func(t *testing.T, out []byte)
+	}{
+		{name: "none keeps everything", data: buildJPEG(jfifSegment, withGPS, gpsXMP), mode: StripNone},
+		{name: "gps without coordinates", data: buildJPEG(jfifSegment, withoutGPS, plainXMP), mode: StripGPS},
+		{name: "gps", data: buildJPEG(jfifSegment, withGPS, plainXMP), mode: StripGPS, wantStripped: true,
+			check: func(t *testing.T, out []byte) {
+				metadata, found, err := Read(bytes.NewReader(out))
+				if err != nil || !found {
+					t.Fatalf("read stripped: %v, found %v", err, found)
+				}
+				if metadata.HasGPS || metadata.CameraMake != "Canon" || metadata.Orientation != 6 || metadata.LensModel == "" {
+					t.Fatalf("got %+v", metadata)
+				}
+				// GPS IFD стирается по месту, размер сегмента не меняется
+				if len(out) != len(buildJPEG(jfifSegment, withGPS, plainXMP)) {
+					t.Fatalf("stripped file is %d bytes", len(out))
+				}
+				if bytes.Contains(out, []byte{0, 0, 0, 55, 0, 0, 0, 1, 0, 0, 0, 45}) {
+					t.Fatalf("latitude is still in the file")
+				}
+			}},
+		{name: "gps in xmp", data: buildJPEG(jfifSegment, withoutGPS, gpsXMP), mode: StripGPS, wantStripped: true,
+			want: buildJPEG(jfifSegment, withoutGPS)},
+		{name: "all", data: buildJPEG(jfifSegment, withGPS, plainXMP), mode: StripAll, wantStripped: true,
+			want: buildJPEG(jfifSegment)},
+		{name: "all without metadata", data: buildJPEG(jfifSegment), mode: StripAll},
+		{name: "not a jpeg", data: []byte("GIF89a"), mode: StripAll, wantErr: ErrNotJPEG},
+		{name: "truncated", data: buildJPEG(jfifSegment, withGPS)[:30], mode: StripNone, wantErr: ErrNotJPEG},
+		{name: "malformed exif", data: buildJPEG(exifSegment([]byte("MM\x00\x2a\x00\x00\x10\x00"))), mode: StripGPS, wantErr: ErrMalformed},
+		// StripAll не разбирает EXIF, поэтому и сломанный вырезается
+		{name: "malformed exif removed entirely", data: buildJPEG(exifSegment([]byte("MM\x00\x2a\x00\x00\x10\x00"))), mode: StripAll,
+			wantStripped: true, want: buildJPEG()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stripped, err := Strip(bytes.NewReader(tt.data), &out, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if stripped != tt.wantStripped {
+				t.Fatalf("stripped %v, want %v", stripped, tt.wantStripped)
+			}
+
+			if tt.check != nil {
+				tt.check(t, out.Bytes())
+			} else {
+				want := tt.want
+				if want == nil {
+					want = tt.data
+				}
+				if !bytes.Equal(out.Bytes(), want) {
+					t.Fatalf("got % x\nwant % x", out.Bytes(), want)
+				}
+			}
+			if !bytes.HasSuffix(out.Bytes(), scan) {
+				t.Fatalf("image data changed")
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr error
+	}{
+		{mode: "", want: StripNone},
+		{mode: "none", want: StripNone},
+		{mode: "GPS", want: StripGPS},
+		{mode: "all", want: StripAll},
+		{mode: "everything", wantErr: ErrMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := ParseMode(tt.mode)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	for _, pair := range [][3]string{
+		{"", "", StripNone},
+		{StripNone, StripGPS, StripGPS},
+		{StripAll, StripGPS, StripAll},
+		{StripGPS, "", StripGPS},
+	} {
+		if got := Stronger(pair[0], pair[1]); got != pair[2] {
+			t.Fatalf("Stronger(%q, %q) = %q, want %q", pair[0], pair[1], got, pair[2])
+		}
+	}
+}
+
+// TestTruncatedTIFF обрезает EXIF на каждом байте: разбор не должен паниковать и выходить за пределы данных
+func TestTruncatedTIFF(t *testing.T) {
+	data := cameraTIFF(binary.BigEndian, "N", "E", 0)
+	for n := 0; n < len(data); n++ {
+		file := buildJPEG(exifSegment(data[:n]))
+
+		_, _, err := Read(bytes.NewReader(file))
+		if err != nil && !errors.Is(err, ErrMalformed) {
+			t.Fatalf("read %d bytes: %v", n, err)
+		}
+		_, err = Strip(bytes.NewReader(file), io.Discard, StripGPS)
+		if err != nil && !errors.Is(err, ErrMalformed) {
+			t.Fatalf("strip %d bytes: %v", n, err)
+		}
+	}
+}
@@ -2,18 +2,26 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
-	pb "github.com/Chilibonitsu/protos/gen/go/imageStorage"
+	"imagestorage/internal/compress"
+	"imagestorage/internal/utils"
+
+	pb "imagestorage/contracts/gen/go/imageStorage"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type GrpcClient struct {
-	client pb.GuploadServiceClient
+	client    pb.GuploadServiceClient
+	namespace string
+	stripExif string
 }
 
 func NewGrpcClient(conn *grpc.ClientConn) *GrpcClient {
@@ -22,27 +30,135 @@ func NewGrpcClient(conn *grpc.ClientConn) *GrpcClient {
 	}
 }
 
+// WithNamespace возвращает клиент, который загружает файлы в пространство имен namespace
+func (c *GrpcClient) WithNamespace(namespace string) *GrpcClient {
+	clone := *c
+	clone.namespace = namespace
+	return &clone
+}
+
+// WithStripExif возвращает клиент, который просит сервер вырезать из загружаемых JPEG
+// EXIF по режиму mode: gps - координаты, all - EXIF целиком
+func (c *GrpcClient) WithStripExif(mode string) *GrpcClient {
+	clone := *c
+	clone.stripExif = mode
+	return &clone
+}
+
+const (
+	// uploadSessionHeader должен совпадать с serverStorage.UploadSessionHeader
+	uploadSessionHeader = "x-upload-session-id"
+	// contentEncodingHeader должен совпадать с serverStorage.ContentEncodingHeader
+	contentEncodingHeader = "x-content-encoding"
+	// contentTypeHeader должен совпадать с serverStorage.ContentTypeHeader
+	contentTypeHeader = "x-content-type"
+)
+
+// TODO: в конфиг
+const maxUploadAttempts = 5
+
+// UploadError возвращается, если загрузку не удалось завершить.
+// По SessionID ее можно продолжить через ResumeUpload
+type UploadError struct {
+	SessionID string
+	Err       error
+}
+
+func (e *UploadError) Error() string {
+	if e.SessionID == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("upload session %s: %v", e.SessionID, e.Err)
+}
+
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// UploadFile загружает файл и при обрыве соединения докачивает его с подтвержденного сервером смещения
 func (c *GrpcClient) UploadFile(ctx context.Context, filePath string) error {
+	sessionID, err := c.upload(ctx, filePath, "", 0)
+
+	for attempt := 1; err != nil && attempt < maxUploadAttempts && canResume(ctx, sessionID, err); attempt++ {
+		offset, offsetErr := c.GetUploadOffset(ctx, sessionID)
+		if offsetErr != nil {
+			break
+		}
+		sessionID, err = c.upload(ctx, filePath, sessionID, offset)
+	}
+
+	if err != nil {
+		return &UploadError{SessionID: sessionID, Err: err}
+	}
+	return nil
+}
+
+// ResumeUpload продолжает ранее прерванную загрузку
+func (c *GrpcClient) ResumeUpload(ctx context.Context, filePath string, sessionID string) error {
+	offset, err := c.GetUploadOffset(ctx, sessionID)
+	if err != nil {
+		return &UploadError{SessionID: sessionID, Err: err}
+	}
+
+	if _, err := c.upload(ctx, filePath, sessionID, offset); err != nil {
+		return &UploadError{SessionID: sessionID, Err: err}
+	}
+	return nil
+}
+
+func (c *GrpcClient) GetUploadOffset(ctx context.Context, sessionID string) (int64, error) {
+	response, err := c.client.GetUploadOffset(ctx, &pb.UploadOffsetRequest{SessionId: sessionID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get upload offset: %w", err)
+	}
+	return response.Offset, nil
+}
+
+// upload отправляет файл начиная с offset и возвращает id сессии, выданный сервером
+func (c *GrpcClient) upload(ctx context.Context, filePath string, sessionID string, offset int64) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return sessionID, fmt.Errorf("failed to open file: %v", err)
 	}
 	defer file.Close()
 
+	// Сервер сверяет итоговый sha256 и отклоняет загрузку при расхождении
+	checksum, err := utils.CalculateChecksum(filePath)
+	if err != nil {
+		return sessionID, fmt.Errorf("failed to calculate checksum: %v", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return sessionID, fmt.Errorf("failed to seek file: %v", err)
+	}
+
 	stream, err := c.client.Upload(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create upload stream: %v", err)
+		return sessionID, fmt.Errorf("failed to create upload stream: %w", err)
 	}
 
 	fileInfo := &pb.UploadFileRequest{
 		Data: &pb.UploadFileRequest_FileInfo{
 			FileInfo: &pb.FileUploadInfo{
-				FileName: filepath.Base(filePath),
+				FileName:  filepath.Base(filePath),
+				SessionId: sessionID,
+				Offset:    offset,
+				Sha256:    checksum,
+				Namespace: c.namespace,
+				StripExif: c.stripExif,
 			},
 		},
 	}
 	if err := stream.Send(fileInfo); err != nil {
-		return fmt.Errorf("failed to send file info: %v", err)
+		return sessionID, sendError(stream, "failed to send file info", err)
+	}
+
+	header, err := stream.Header()
+	if err != nil {
+		return sessionID, fmt.Errorf("failed to receive upload session: %w", err)
+	}
+	if ids := header.Get(uploadSessionHeader); len(ids) > 0 {
+		sessionID = ids[0]
 	}
 
 	// Читаем и отправляем данные файла по частям
@@ -53,7 +169,7 @@ func (c *GrpcClient) UploadFile(ctx context.Context, filePath string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("error reading file: %v", err)
+			return sessionID, fmt.Errorf("error reading file: %v", err)
 		}
 
 		chunk := &pb.UploadFileRequest{
@@ -62,21 +178,42 @@ func (c *GrpcClient) UploadFile(ctx context.Context, filePath string) error {
 			},
 		}
 		if err := stream.Send(chunk); err != nil {
-			return fmt.Errorf("failed to send chunk: %v", err)
+			return sessionID, sendError(stream, "failed to send chunk", err)
 		}
 	}
 
 	// Получаем ответ от сервера
 	response, err := stream.CloseAndRecv()
 	if err != nil {
-		return fmt.Errorf("failed to receive response: %v", err)
+		return sessionID, fmt.Errorf("failed to receive response: %w", err)
 	}
 
 	if response.Code != pb.UploadStatusCode_Ok {
-		return fmt.Errorf("upload failed with code: %v", response.Code)
+		return sessionID, fmt.Errorf("upload failed with code: %v", response.Code)
 	}
 
-	return nil
+	return sessionID, nil
+}
+
+// sendError достает настоящий статус, если сервер уже закрыл поток (Send вернул io.EOF)
+func sendError(stream pb.GuploadService_UploadClient, msg string, err error) error {
+	if err == io.EOF {
+		_, err = stream.CloseAndRecv()
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// canResume - повторяем только обрывы связи, а не отказы сервера
+func canResume(ctx context.Context, sessionID string, err error) bool {
+	if sessionID == "" || ctx.Err() != nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.Internal:
+		return true
+	}
+	return false
 }
 
 func (c *GrpcClient) DownloadFile(ctx context.Context, fileName string, outputPath string) error {
@@ -98,13 +235,262 @@ func (c *GrpcClient) DownloadFile(ctx context.Context, fileName string, outputPa
 	}
 	defer file.Close()
 
+	return receiveChunks(stream, file)
+}
+
+// DownloadFileVersion скачивает конкретную версию файла
+func (c *GrpcClient) DownloadFileVersion(ctx context.Context, fileName string, version int64, outputPath string) error {
+	request := &pb.DownloadRequest{
+		FileName: fileName,
+		Version:  version,
+	}
+
+	stream, err := c.client.Download(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to start download: %v", err)
+	}
+
+	file, err := os.Create(filepath.Join(outputPath, fileName))
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	return receiveChunks(stream, file)
+}
+
+// DownloadVariant скачивает вариант последней версии файла, например миниатюру thumb_256,
+// и сохраняет его как <variant>_<fileName>
+func (c *GrpcClient) DownloadVariant(ctx context.Context, fileName string, variant string, outputPath string) error {
+	request := &pb.DownloadRequest{
+		FileName: fileName,
+		Variant:  variant,
+	}
+
+	stream, err := c.client.Download(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to start download: %v", err)
+	}
+
+	file, err := os.Create(filepath.Join(outputPath, variant+"_"+fileName))
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	return receiveChunks(stream, file)
+}
+
+// TransformFile преобразует последнюю версию файла операциями ops и сохраняет результат в outputPath
+// под именем outputName. Возвращает MIME-тип результата
+func (c *GrpcClient) TransformFile(ctx context.Context, fileName string, outputPath string, outputName string, ops ...*pb.ImageOperation) (string, error) {
+	request := &pb.TransformRequest{
+		FileName:   fileName,
+		Operations: ops,
+	}
+
+	stream, err := c.client.Transform(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to start transform: %v", err)
+	}
+
+	header, err := stream.Header()
+	if err != nil {
+		return "", fmt.Errorf("failed to receive transform header: %v", err)
+	}
+
+	var mimeType string
+	if values := header.Get(contentTypeHeader); len(values) > 0 {
+		mimeType = values[0]
+	}
+
+	file, err := os.Create(filepath.Join(outputPath, outputName))
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	return mimeType, receiveChunks(stream, file)
+}
+
+// DownloadFileEncoded скачивает последнюю версию файла, разрешая серверу отдать ее сжатой одним из
+// acceptEncoding без распаковки. Сжатый файл сохраняется с расширением сжатия (.gz, .zst).
+// Возвращает сжатие полученных данных, пустая строка - файл пришел распакованным
+func (c *GrpcClient) DownloadFileEncoded(ctx context.Context, fileName string, outputPath string, acceptEncoding ...string) (string, error) {
+	request := &pb.DownloadRequest{
+		FileName:       fileName,
+		AcceptEncoding: acceptEncoding,
+	}
+
+	stream, err := c.client.Download(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to start download: %v", err)
+	}
+
+	header, err := stream.Header()
+	if err != nil {
+		return "", fmt.Errorf("failed to receive download header: %v", err)
+	}
+
+	var encoding string
+	if values := header.Get(contentEncodingHeader); len(values) > 0 {
+		encoding = values[0]
+	}
+
+	file, err := os.Create(filepath.Join(outputPath, fileName+compress.Extension(encoding)))
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	return encoding, receiveChunks(stream, file)
+}
+
+// DownloadFileRange скачивает length байт начиная с offset (length 0 - до конца файла)
+// и записывает их в локальный файл по тому же смещению. Существующий файл не обрезается
+func (c *GrpcClient) DownloadFileRange(ctx context.Context, fileName string, outputPath string, offset int64, length int64) error {
+	request := &pb.DownloadRequest{
+		FileName: fileName,
+		Offset:   offset,
+		Length:   length,
+	}
+
+	return c.downloadRange(ctx, request, filepath.Join(outputPath, fileName))
+}
+
+func (c *GrpcClient) downloadRange(ctx context.Context, request *pb.DownloadRequest, out string) error {
+	stream, err := c.client.Download(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to start download: %w", err)
+	}
+
+	file, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(request.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek output file: %v", err)
+	}
+
+	return receiveChunks(stream, file)
+}
+
+// downloadStateSuffix - файл рядом с частично скачанным, в нем версия, которую докачивает ResumeDownload
+const downloadStateSuffix = ".download"
+
+// downloadState закрепляет версию докачки, чтобы к скачанной части не приклеилось содержимое новой версии
+type downloadState struct {
+	Version  int64  `json:"version"`
+	Checksum string `json:"checksum"`
+}
+
+// ResumeDownload докачивает файл, начиная с размера уже скачанной части. Версия и sha256 файла
+// запоминаются в <файл>.download при первом запросе: докачивается та же версия, а в конце проверяется sha256.
+// Если проверка не прошла, скачанное удаляется и следующий вызов начинает заново
+func (c *GrpcClient) ResumeDownload(ctx context.Context, fileName string, outputPath string) error {
+	out := filepath.Join(outputPath, fileName)
+	statePath := out + downloadStateSuffix
+
+	state, err := readDownloadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	if state == nil {
+		info, err := c.GetFileInfo(ctx, fileName, 0)
+		if err != nil {
+			return err
+		}
+		state = &downloadState{Version: info.GetVersion(), Checksum: info.GetChecksum()}
+
+		// Уже скачанная часть без записанной версии могла остаться от другой версии, поэтому начинаем заново
+		if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove output file: %v", err)
+		}
+		if err := writeDownloadState(statePath, *state); err != nil {
+			return err
+		}
+	} else {
+		info, err := os.Stat(out)
+		if err == nil {
+			offset = info.Size()
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat output file: %v", err)
+		}
+	}
+
+	request := &pb.DownloadRequest{
+		FileName: fileName,
+		Version:  state.Version,
+		Offset:   offset,
+	}
+	if err := c.downloadRange(ctx, request, out); err != nil {
+		// Локальный файл длиннее версии - докачивать его нечего
+		if status.Code(err) == codes.OutOfRange {
+			discardDownload(out, statePath)
+		}
+		return err
+	}
+
+	checksum, err := utils.CalculateChecksum(out)
+	if err != nil {
+		return fmt.Errorf("failed to hash output file: %v", err)
+	}
+	if checksum != state.Checksum {
+		discardDownload(out, statePath)
+		return fmt.Errorf("downloaded file sha256 %s does not match version %d sha256 %s", checksum, state.Version, state.Checksum)
+	}
+
+	if err := os.Remove(statePath); err != nil {
+		return fmt.Errorf("failed to remove download state: %v", err)
+	}
+	return nil
+}
+
+// readDownloadState возвращает nil, если докачка еще не начиналась
+func readDownloadState(path string) (*downloadState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download state: %v", err)
+	}
+
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse download state %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+func writeDownloadState(path string, state downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode download state: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write download state: %v", err)
+	}
+	return nil
+}
+
+func discardDownload(out string, statePath string) {
+	os.Remove(out)
+	os.Remove(statePath)
+}
+
+func receiveChunks(stream pb.GuploadService_DownloadClient, file *os.File) error {
 	for {
 		chunk, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("error receiving chunk: %v", err)
+			return fmt.Errorf("error receiving chunk: %w", err)
 		}
 
 		if _, err := file.Write(chunk.Content); err != nil {
@@ -115,10 +501,91 @@ func (c *GrpcClient) DownloadFile(ctx context.Context, fileName string, outputPa
 	return nil
 }
 
+// ListFiles возвращает последние версии всех файлов, проходя по всем страницам
 func (c *GrpcClient) ListFiles(ctx context.Context) ([]*pb.FileInfo, error) {
-	response, err := c.client.ListFiles(ctx, &pb.ListFilesRequest{})
+	files, err := c.listAll(ctx, &pb.ListFilesRequest{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %v", err)
 	}
-	return response.Files, nil
+	return files, nil
+}
+
+// GetFileInfo возвращает метаданные версии файла (version 0 - последняя) без скачивания
+func (c *GrpcClient) GetFileInfo(ctx context.Context, fileName string, version int64) (*pb.FileInfo, error) {
+	response, err := c.client.GetFileInfo(ctx, &pb.GetFileInfoRequest{FileName: fileName, Version: version})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+	return response, nil
+}
+
+// ListFilesPage возвращает одну страницу списка файлов и токен следующей
+func (c *GrpcClient) ListFilesPage(ctx context.Context, request *pb.ListFilesRequest) ([]*pb.FileInfo, string, error) {
+	response, err := c.client.ListFiles(ctx, request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list files: %v", err)
+	}
+	return response.Files, response.NextPageToken, nil
+}
+
+func (c *GrpcClient) listAll(ctx context.Context, request *pb.ListFilesRequest) ([]*pb.FileInfo, error) {
+	var files []*pb.FileInfo
+	for {
+		response, err := c.client.ListFiles(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, response.Files...)
+
+		if response.NextPageToken == "" {
+			return files, nil
+		}
+		request.PageToken = response.NextPageToken
+	}
+}
+
+// ListFileVersions возвращает историю версий файла, начиная с последней
+func (c *GrpcClient) ListFileVersions(ctx context.Context, fileName string) ([]*pb.FileInfo, error) {
+	files, err := c.listAll(ctx, &pb.ListFilesRequest{FileName: fileName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file versions: %v", err)
+	}
+	return files, nil
+}
+
+// DeleteFile помечает файл удаленным (version 0 - все версии)
+func (c *GrpcClient) DeleteFile(ctx context.Context, fileName string, version int64) error {
+	_, err := c.client.Delete(ctx, &pb.DeleteRequest{FileName: fileName, Version: version})
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+	return nil
+}
+
+// RestoreFile снимает пометку об удалении (version 0 - все версии)
+func (c *GrpcClient) RestoreFile(ctx context.Context, fileName string, version int64) error {
+	_, err := c.client.Restore(ctx, &pb.RestoreRequest{FileName: fileName, Version: version})
+	if err != nil {
+		return fmt.Errorf("failed to restore file: %v", err)
+	}
+	return nil
+}
+
+// PurgeFiles окончательно удаляет помеченные файлы и возвращает их количество.
+// force без fileName сервер отклоняет, для всех файлов есть PurgeAllFiles
+func (c *GrpcClient) PurgeFiles(ctx context.Context, fileName string, force bool) (int64, error) {
+	response, err := c.client.Purge(ctx, &pb.PurgeRequest{FileName: fileName, Force: force})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge files: %v", err)
+	}
+	return response.Purged, nil
+}
+
+// PurgeAllFiles окончательно удаляет все помеченные файлы, не дожидаясь срока хранения
+func (c *GrpcClient) PurgeAllFiles(ctx context.Context) (int64, error) {
+	response, err := c.client.Purge(ctx, &pb.PurgeRequest{Force: true, AllFiles: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge files: %v", err)
+	}
+	return response.Purged, nil
 }
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	pb "imagestorage/contracts/gen/go/imageStorage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeFiles - версии одного файла. Download обрывается, отдав breakAfter байт (0 - не обрывается)
+type fakeFiles struct {
+	pb.UnimplementedGuploadServiceServer
+
+	mu         sync.Mutex
+	versions   [][]byte
+	checksum   string
+	breakAfter int
+	requested  []int64
+}
+
+func (f *fakeFiles) latest() int64 {
+	return int64(len(f.versions))
+}
+
+func (f *fakeFiles) GetFileInfo(ctx context.Context, request *pb.GetFileInfoRequest) (*pb.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	version := request.GetVersion()
+	if version == 0 {
+		version = f.latest()
+	}
+	checksum := f.checksum
+	if checksum == "" {
+		checksum = sha256Hex(f.versions[version-1])
+	}
+	return &pb.FileInfo{FileName: request.GetFileName(), Version: version, Checksum: checksum}, nil
+}
+
+func (f *fakeFiles) Download(request *pb.DownloadRequest, stream grpc.ServerStreamingServer[pb.DownloadResponse]) error {
+	f.mu.Lock()
+	version := request.GetVersion()
+	if version == 0 {
+		version = f.latest()
+	}
+	f.requested = append(f.requested, request.GetVersion())
+	content, breakAfter := f.versions[version-1], f.breakAfter
+	f.mu.Unlock()
+
+	if request.GetOffset() > int64(len(content)) {
+		return status.Errorf(codes.OutOfRange, "offset %d is beyond file size %d", request.GetOffset(), len(content))
+	}
+	content = content[request.GetOffset():]
+	if breakAfter > 0 && breakAfter < len(content) {
+		if err := stream.Send(&pb.DownloadResponse{Content: content[:breakAfter]}); err != nil {
+			return err
+		}
+		return status.Error(codes.Unavailable, "connection lost")
+	}
+	return stream.Send(&pb.DownloadResponse{Content: content})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestClient(t *testing.T, files *fakeFiles) *GrpcClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterGuploadServiceServer(server, files)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewGrpcClient(conn)
+}
+
+func TestResumeDownloadPinsVersion(t *testing.T) {
+	first := []byte("content of the first version")
+	files := &fakeFiles{versions: [][]byte{first}, breakAfter: 10}
+	c := newTestClient(t, files)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a.txt")
+
+	if err := c.ResumeDownload(context.Background(), "a.txt", dir); status.Code(err) != codes.Unavailable {
+		t.Fatalf("interrupted download: got %v, want %s", err, codes.Unavailable)
+	}
+
+	// Между попытками загрузили новую версию: докачивается та, с которой начали
+	files.mu.Lock()
+	files.versions = append(files.versions, []byte("second version, longer than the first one"))
+	files.breakAfter = 0
+	files.mu.Unlock()
+
+	if err := c.ResumeDownload(context.Background(), "a.txt", dir); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(first) {
+		t.Fatalf("downloaded %q, want %q", got, first)
+	}
+	for _, version := range files.requested {
+		if version != 1 {
+			t.Fatalf("requested versions %v, want only 1", files.requested)
+		}
+	}
+	if _, err := os.Stat(out + downloadStateSuffix); !os.IsNotExist(err) {
+		t.Fatalf("download state kept: %v", err)
+	}
+}
+
+func TestResumeDownloadChecksumMismatch(t *testing.T) {
+	files := &fakeFiles{versions: [][]byte{[]byte("content")}, checksum: sha256Hex([]byte("other content"))}
+	c := newTestClient(t, files)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a.txt")
+
+	if err := c.ResumeDownload(context.Background(), "a.txt", dir); err == nil {
+		t.Fatalf("download with a wrong sha256 succeeded")
+	}
+	// Следующая попытка начнет заново
+	for _, path := range []string{out, out + downloadStateSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s kept: %v", path, err)
+		}
+	}
+}
+
+// Часть без записанной версии могла остаться от другой версии и скачивается заново
+func TestResumeDownloadWithoutState(t *testing.T) {
+	content := []byte("current content")
+	files := &fakeFiles{versions: [][]byte{content}}
+	c := newTestClient(t, files)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(out, []byte("stale prefix of another version"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ResumeDownload(context.Background(), "a.txt", dir); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil || string(got) != string(content) {
+		t.Fatalf("downloaded %q, %v", got, err)
+	}
+}
@@ -0,0 +1,48 @@
+package serverStorage
+
+import (
+	"imagestorage/internal/storage/catalog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limits - ограничения на размер файла и квоты пространств имен. 0 - без ограничения
+type Limits struct {
+	MaxFileSize         int64
+	QuotaBytes          int64
+	QuotaFiles          int64
+	NamespaceQuotaBytes map[string]int64
+	NamespaceQuotaFiles map[string]int64
+}
+
+func (l Limits) quota(namespace string) (int64, int64) {
+	quotaBytes, quotaFiles := l.QuotaBytes, l.QuotaFiles
+	if q, ok := l.NamespaceQuotaBytes[namespace]; ok {
+		quotaBytes = q
+	}
+	if q, ok := l.NamespaceQuotaFiles[namespace]; ok {
+		quotaFiles = q
+	}
+	return quotaBytes, quotaFiles
+}
+
+// checkQuota проверяет, что загрузка еще uploaded байт не выходит за лимиты.
+// usage не включает текущую загрузку
+func (l Limits) checkQuota(namespace string, usage catalog.Usage, uploaded int64) error {
+	if l.MaxFileSize > 0 && uploaded > l.MaxFileSize {
+		return status.Errorf(codes.ResourceExhausted, "file size exceeds the maximum allowed size of %d bytes", l.MaxFileSize)
+	}
+
+	quotaBytes, quotaFiles := l.quota(namespace)
+
+	if quotaFiles > 0 && usage.Files+1 > quotaFiles {
+		return status.Errorf(codes.ResourceExhausted, "namespace %q file quota of %d files exceeded", namespace, quotaFiles)
+	}
+
+	if quotaBytes > 0 && usage.Bytes+uploaded > quotaBytes {
+		return status.Errorf(codes.ResourceExhausted, "namespace %q quota of %d bytes exceeded", namespace, quotaBytes)
+	}
+
+	return nil
+}
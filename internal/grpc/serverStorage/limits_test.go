@@ -0,0 +1,94 @@
+package serverStorage
+
+import (
+	"bytes"
+	"testing"
+
+	pb "imagestorage/contracts/gen/go/imageStorage"
+	"imagestorage/internal/storage/catalog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckQuota(t *testing.T) {
+	limits := Limits{
+		MaxFileSize:         1000,
+		QuotaBytes:          5000,
+		QuotaFiles:          10,
+		NamespaceQuotaBytes: map[string]int64{"small": 100, "unlimited": 0},
+		NamespaceQuotaFiles: map[string]int64{"small": 2, "unlimited": 0},
+	}
+
+	tests := []struct {
+		name      string
+		limits    Limits
+		namespace string
+		usage     catalog.Usage
+		uploaded  int64
+		want      codes.Code
+	}{
+		{name: "within defaults", limits: limits, usage: catalog.Usage{Files: 3, Bytes: 1000}, uploaded: 500, want: codes.OK},
+		{name: "max file size", limits: limits, uploaded: 1001, want: codes.ResourceExhausted},
+		{name: "exactly max file size", limits: limits, uploaded: 1000, want: codes.OK},
+		{name: "default byte quota", limits: limits, usage: catalog.Usage{Bytes: 4500}, uploaded: 501, want: codes.ResourceExhausted},
+		{name: "default byte quota filled exactly", limits: limits, usage: catalog.Usage{Bytes: 4500}, uploaded: 500, want: codes.OK},
+		{name: "default file quota", limits: limits, usage: catalog.Usage{Files: 10}, want: codes.ResourceExhausted},
+		{name: "last file within file quota", limits: limits, usage: catalog.Usage{Files: 9}, want: codes.OK},
+		{name: "namespace byte quota", limits: limits, namespace: "small", usage: catalog.Usage{Bytes: 60}, uploaded: 41, want: codes.ResourceExhausted},
+		{name: "namespace file quota", limits: limits, namespace: "small", usage: catalog.Usage{Files: 2}, want: codes.ResourceExhausted},
+		{name: "namespace without quota", limits: limits, namespace: "unlimited", usage: catalog.Usage{Files: 100, Bytes: 1 << 30}, uploaded: 1000, want: codes.OK},
+		{name: "namespace still has max file size", limits: limits, namespace: "unlimited", uploaded: 1001, want: codes.ResourceExhausted},
+		{name: "no limits", usage: catalog.Usage{Files: 1 << 20, Bytes: 1 << 40}, uploaded: 1 << 40, want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.checkQuota(tt.namespace, tt.usage, tt.uploaded)
+			if status.Code(err) != tt.want {
+				t.Fatalf("got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadQuota(t *testing.T) {
+	limits := Limits{NamespaceQuotaBytes: map[string]int64{"small": 1000}, NamespaceQuotaFiles: map[string]int64{"small": 3}}
+	server, st := newTestServer(t, limits, Policy{})
+	chunk := bytes.Repeat([]byte("x"), 300)
+
+	steps := []struct {
+		name     string
+		fileName string
+		chunks   [][]byte
+		broken   bool
+		want     codes.Code
+	}{
+		{name: "first file", fileName: "a.txt", chunks: [][]byte{chunk}, want: codes.OK},
+		// Незавершенная загрузка занимает в квоте и файл, и принятые байты
+		{name: "interrupted upload", fileName: "b.txt", chunks: [][]byte{chunk, chunk}, broken: true, want: codes.Internal},
+		{name: "over byte quota", fileName: "c.txt", chunks: [][]byte{chunk, chunk}, want: codes.ResourceExhausted},
+		{name: "within byte quota", fileName: "c.txt", chunks: [][]byte{chunk[:100]}, want: codes.OK},
+		{name: "over file quota", fileName: "d.txt", chunks: [][]byte{chunk[:1]}, want: codes.ResourceExhausted},
+		{name: "new version counts as a file", fileName: "a.txt", chunks: [][]byte{chunk[:1]}, want: codes.ResourceExhausted},
+	}
+
+	for _, step := range steps {
+		var broken error
+		if step.broken {
+			broken = errBroken
+		}
+		_, err := upload(server, &pb.FileUploadInfo{FileName: step.fileName, Namespace: "small"}, broken, step.chunks...)
+		if status.Code(err) != step.want {
+			t.Fatalf("%s: got %v, want %s", step.name, err, step.want)
+		}
+	}
+
+	usage, err := st.GetNamespaceUsage("small", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Files != 3 || usage.Bytes != 1000 {
+		t.Fatalf("usage %+v, want 3 files and 1000 bytes", usage)
+	}
+}
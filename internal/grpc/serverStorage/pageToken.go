@@ -0,0 +1,48 @@
+package serverStorage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// pageToken - курсор ListFiles. Сортировка хранится в токене,
+// чтобы токен нельзя было применить к запросу с другим порядком
+type pageToken struct {
+	SortBy     catalog.SortField `json:"s"`
+	Descending bool              `json:"d"`
+	Value      string            `json:"v"`
+	ID         int64             `json:"id"`
+}
+
+func encodePageToken(sortBy catalog.SortField, descending bool, cursor catalog.Cursor) string {
+	data, _ := json.Marshal(pageToken{
+		SortBy:     sortBy,
+		Descending: descending,
+		Value:      cursor.Value,
+		ID:         cursor.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string, sortBy catalog.SortField, descending bool) (*catalog.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+
+	var t pageToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, errInvalidPageToken
+	}
+
+	if t.SortBy != sortBy || t.Descending != descending {
+		return nil, errInvalidPageToken
+	}
+
+	return &catalog.Cursor{Value: t.Value, ID: t.ID}, nil
+}
@@ -0,0 +1,68 @@
+package serverStorage
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+func TestPageToken(t *testing.T) {
+	cursor := catalog.Cursor{Value: "photos/2024/a.png", ID: 42}
+	token := encodePageToken(catalog.SortByName, true, cursor)
+
+	tests := []struct {
+		name       string
+		token      string
+		sortBy     catalog.SortField
+		descending bool
+		wantErr    bool
+	}{
+		{name: "same order", token: token, sortBy: catalog.SortByName, descending: true},
+		{name: "other field", token: token, sortBy: catalog.SortBySize, descending: true, wantErr: true},
+		{name: "other direction", token: token, sortBy: catalog.SortByName, wantErr: true},
+		{name: "not base64", token: "%%%", sortBy: catalog.SortByName, descending: true, wantErr: true},
+		{name: "not json", token: base64.RawURLEncoding.EncodeToString([]byte("cursor")), sortBy: catalog.SortByName, descending: true, wantErr: true},
+		{name: "padded base64", token: base64.URLEncoding.EncodeToString([]byte(`{"s":"filename","d":true}`)), sortBy: catalog.SortByName, descending: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePageToken(tt.token, tt.sortBy, tt.descending)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidPageToken) {
+					t.Fatalf("got %v, want %v", err, errInvalidPageToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != cursor {
+				t.Fatalf("got %+v, want %+v", *got, cursor)
+			}
+		})
+	}
+}
+
+func TestPageTokenCursorValues(t *testing.T) {
+	files := []catalog.FileInfo{
+		{ID: 1, FileName: "a.png", Size: 1 << 40, Version: 7},
+		{ID: 2, FileName: "имя с пробелом.jpg", Size: 0, Version: 1},
+	}
+	sorts := []catalog.SortField{catalog.SortByName, catalog.SortByCreated, catalog.SortBySize, catalog.SortByVersion}
+
+	for _, file := range files {
+		for _, sortBy := range sorts {
+			cursor := catalog.CursorFor(file, sortBy)
+			got, err := decodePageToken(encodePageToken(sortBy, false, cursor), sortBy, false)
+			if err != nil {
+				t.Fatalf("%s by %s: %v", file.FileName, sortBy, err)
+			}
+			if *got != cursor {
+				t.Fatalf("%s by %s: got %+v, want %+v", file.FileName, sortBy, *got, cursor)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package serverStorage
+
+import (
+	"errors"
+
+	"imagestorage/internal/exif"
+	"imagestorage/internal/mimetype"
+	"imagestorage/internal/storage/catalog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy - как сервер обращается с содержимым загрузок
+type Policy struct {
+	// StripExif - что вырезать из EXIF загруженных JPEG (exif.StripNone, exif.StripGPS, exif.StripAll).
+	// Загрузка может попросить вырезать больше, но не меньше
+	StripExif string
+	// ContentTypeMismatch - что делать, если расширение противоречит содержимому
+	// (mimetype.MismatchFlag, mimetype.MismatchReject)
+	ContentTypeMismatch string
+}
+
+// stripMode - режим вырезания EXIF для загрузки, которая просит requested
+func (p Policy) stripMode(requested string) (string, error) {
+	mode, err := exif.ParseMode(requested)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return exif.Stronger(p.StripExif, mode), nil
+}
+
+// contentType выбирает MIME-тип загрузки для каталога по типу из расширения declared и по содержимому sniffed.
+// При расхождении возвращает тип по содержимому и declared, чтобы отметить файл, или отклоняет загрузку
+func (p Policy) contentType(declared string, sniffed string) (string, string, error) {
+	mimeType, ok := mimetype.Resolve(declared, sniffed)
+	if ok {
+		return mimeType, "", nil
+	}
+	if p.ContentTypeMismatch == mimetype.MismatchReject {
+		return "", "", status.Errorf(codes.InvalidArgument, "file content is %s, but its extension says %s", sniffed, declared)
+	}
+	return mimeType, declared, nil
+}
+
+// processExif читает EXIF загруженного JPEG и вырезает из файла то, что требует режим strip.
+// Возвращает метаданные для каталога (nil, если их нет) и sha256 и размер того, что будет сохранено.
+// Вырезанные координаты в каталог тоже не попадают
+func (s *serverAPI) processExif(sessionID string, fileName string, strip string, checksum string, size int64) (*catalog.Exif, string, int64, error) {
+	var metadata *catalog.Exif
+	fileExif, found, err := s.diskSaver.ReadExif(sessionID)
+	switch {
+	case err != nil:
+		// Сломанный EXIF не мешает сохранить файл
+		s.log.Warnf("failed to read EXIF of %s: %v", fileName, err)
+	case found:
+		if strip != exif.StripNone {
+			fileExif.HasGPS = false
+			fileExif.Latitude, fileExif.Longitude, fileExif.Altitude = 0, 0, 0
+		}
+		metadata = &fileExif
+	}
+
+	if strip == exif.StripNone {
+		return metadata, checksum, size, nil
+	}
+
+	stripped, strippedChecksum, strippedSize, err := s.diskSaver.StripExif(sessionID, strip)
+	if errors.Is(err, exif.ErrMalformed) && strip == exif.StripGPS {
+		// Координаты в сломанном EXIF не найти, поэтому он вырезается целиком
+		s.log.Warnf("EXIF of %s is malformed, stripping it entirely: %v", fileName, err)
+		stripped, strippedChecksum, strippedSize, err = s.diskSaver.StripExif(sessionID, exif.StripAll)
+	}
+	if errors.Is(err, exif.ErrNotJPEG) {
+		// Файл с расширением JPEG оказался не JPEG: вырезать нечего
+		return metadata, checksum, size, nil
+	}
+	if err != nil {
+		// Без вырезания файл не сохраняется, иначе метаданные попадут в хранилище
+		s.log.Errorf("failed to strip EXIF of %s: %v", fileName, err)
+		return nil, "", 0, status.Errorf(codes.Internal, "failed to strip EXIF: %v", err)
+	}
+	if !stripped {
+		return metadata, checksum, size, nil
+	}
+
+	return metadata, strippedChecksum, strippedSize, nil
+}
+
+// saveExif записывает метаданные EXIF сохраненной версии файла. Без них загрузка все равно успешна
+func (s *serverAPI) saveExif(fileName string, version int64, metadata catalog.Exif) {
+	file, err := s.storage.GetFileInfo(fileName, version)
+	if err != nil {
+		s.log.Errorf("failed to find %s to save EXIF: %v", fileName, err)
+		return
+	}
+	if err := s.storage.SaveFileExif(file.ID, metadata); err != nil {
+		s.log.Errorf("failed to save EXIF of %s: %v", fileName, err)
+	}
+}
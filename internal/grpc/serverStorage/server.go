@@ -2,46 +2,116 @@ package serverStorage
 
 import (
 	"context"
-	"imagestorage/internal/storage/sqlite"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"imagestorage/internal/compress"
+	"imagestorage/internal/imaging"
+	"imagestorage/internal/mimetype"
+	"imagestorage/internal/storage/blob"
 	"imagestorage/internal/utils"
 	"io"
-	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	pb "github.com/Chilibonitsu/protos/gen/go/imageStorage"
+	pb "imagestorage/contracts/gen/go/imageStorage"
+	"imagestorage/internal/storage/catalog"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 type Storage interface {
-	SaveImage(imageName string, size int, mimeType string, checksum string, createdAt time.Time) error
-	ListFiles() ([]sqlite.FileInfo, error)
+	SaveImage(imageName string, namespace string, pathToFile string, size int, mimeType string, declaredMimeType string, checksum string, stored catalog.StoredBlob, createdAt time.Time) (int64, bool, error)
+	ListFiles(filter catalog.ListFilesFilter) ([]catalog.FileInfo, error)
 	FindFileByName(fileName string) (string, error)
+	GetFileInfo(fileName string, version int64) (catalog.FileInfo, error)
+
+	CreateUploadSession(sessionID string, fileName string, namespace string) error
+	GetUploadSession(sessionID string) (catalog.UploadSession, error)
+	UpdateUploadOffset(sessionID string, offset int64, hashState []byte) error
+	DeleteUploadSession(sessionID string) error
+	GetNamespaceUsage(namespace string, excludeSessionID string) (catalog.Usage, error)
+
+	FindFileLocation(fileName string, version int64) (catalog.FileLocation, error)
+
+	SoftDeleteFile(fileName string, version int64) (int64, error)
+	RestoreFile(fileName string, version int64) (int64, error)
+	DiscardFile(fileName string, version int64) (bool, error)
+
+	ListBlobMoves(checksum string) ([]catalog.TierMove, error)
+	ListBlobVariants(checksum string) ([]catalog.Variant, error)
+
+	SaveFileExif(fileID int64, exif catalog.Exif) error
+	GetFileExif(fileID int64) (catalog.Exif, error)
 }
 
 type ImageSaver interface {
-	DiskSave(ctx context.Context, imageName string, imageData []byte) error
-	DeleteFile(log *logrus.Logger, imageName string, success bool)
+	DiskSave(ctx context.Context, stageName string, imageData []byte) error
+	DeleteFile(log *logrus.Logger, stageName string, success bool)
+	Truncate(stageName string, size int64) error
+	StoreBlob(stageName string, checksum string, mimeType string, commit func(stored catalog.StoredBlob) (bool, error), discard func() error) error
+	BlobKey(checksum string) string
+	StatBlob(ctx context.Context, key string) (int64, error)
+	ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error)
+	ReadVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, offset int64, length int64) (io.ReadCloser, error)
+	ReadExif(stageName string) (catalog.Exif, bool, error)
+	StripExif(stageName string, mode string) (bool, string, int64, error)
+	DetectType(stageName string) (string, error)
+}
+
+type Purger interface {
+	Purge(fileName string, force bool) (int64, error)
+}
+
+// AccessTracker отмечает скачивания файлов, по ним blob-ы переходят между хранилищами
+type AccessTracker interface {
+	Accessed(location catalog.FileLocation)
+}
+
+// Thumbnailer строит миниатюры загруженного файла
+type Thumbnailer interface {
+	Generate(ctx context.Context, fileName string, version int64, mimeType string) (int, error)
+}
+
+// Transformer преобразует изображения по списку операций
+type Transformer interface {
+	Transform(ctx context.Context, fileName string, version int64, ops []imaging.Operation) (io.ReadCloser, string, error)
 }
 
 type serverAPI struct {
 	pb.UnimplementedGuploadServiceServer
-	log       *logrus.Logger
-	storage   Storage
-	diskSaver ImageSaver
+	log        *logrus.Logger
+	storage    Storage
+	diskSaver  ImageSaver
+	purger     Purger
+	access     AccessTracker
+	thumbnails Thumbnailer
+	transforms Transformer
+	limits     Limits
+	policy     Policy
 }
 
-func RegisterServer(gRPC *grpc.Server, log *logrus.Logger, storage Storage, diskSaver ImageSaver) {
-	server := &serverAPI{storage: storage, log: log, diskSaver: diskSaver}
+func RegisterServer(gRPC *grpc.Server, log *logrus.Logger, storage Storage, diskSaver ImageSaver, purger Purger, access AccessTracker, thumbnails Thumbnailer, transforms Transformer, limits Limits, policy Policy) {
+	server := &serverAPI{storage: storage, log: log, diskSaver: diskSaver, purger: purger, access: access, thumbnails: thumbnails, transforms: transforms, limits: limits, policy: policy}
 	pb.RegisterGuploadServiceServer(gRPC, server)
 }
 
-// TODO conf
-const maxImageSize = 1024 * 1024 * 10 // 10MB
+const (
+	// UploadSessionHeader - ключ метаданных, в котором сервер отдает id сессии загрузки
+	UploadSessionHeader = "x-upload-session-id"
+	// ContentEncodingHeader - ключ метаданных со сжатием, если Download отдает файл без распаковки
+	ContentEncodingHeader = "x-content-encoding"
+	// ContentTypeHeader - ключ метаданных с MIME-типом результата Transform
+	ContentTypeHeader = "x-content-type"
+)
 
 func (s *serverAPI) Upload(stream pb.GuploadService_UploadServer) error {
 	op := "internal.grpc.ServerStorage.Upload"
@@ -58,45 +128,112 @@ func (s *serverAPI) Upload(stream pb.GuploadService_UploadServer) error {
 		return status.Errorf(codes.Unknown, "failed to receive image info: %v", err)
 	}
 
-	if fileInfo := req.GetData().(*pb.UploadFileRequest_FileInfo); fileInfo != nil {
-		fileName = fileInfo.FileInfo.GetFileName()
-	} else {
+	fileInfo, ok := req.GetData().(*pb.UploadFileRequest_FileInfo)
+	if !ok {
 		return status.Errorf(codes.InvalidArgument, "missing file info")
 	}
+	fileName = fileInfo.FileInfo.GetFileName()
+	sessionID := fileInfo.FileInfo.GetSessionId()
+	offset := fileInfo.FileInfo.GetOffset()
+	expectedChecksum := strings.ToLower(fileInfo.FileInfo.GetSha256())
+	namespace := fileInfo.FileInfo.GetNamespace()
 
 	s.log.Info("Received file name: ", fileName, op)
-	ok := utils.CheckFileName(fileName)
+	ok = utils.CheckFileName(fileName)
 	if !ok {
 		return status.Errorf(codes.InvalidArgument, "invalid file name")
 	}
 
-	fileExtension := utils.GetExt(fileName)
-	s.log.Infof("Recived file name: %s, extension %s", fileName, fileExtension)
+	if len(namespace) > 255 {
+		return status.Errorf(codes.InvalidArgument, "namespace is too long")
+	}
 
-	//Что делать с файлами с одинаковым названием?
-	//Если в базе есть файл с таким названием, то мы его не загружаем
-	//
+	if expectedChecksum != "" && !utils.IsChecksum(expectedChecksum) {
+		return status.Errorf(codes.InvalidArgument, "invalid sha256: %s", expectedChecksum)
+	}
 
-	findFileName, err := s.storage.FindFileByName(fileName)
+	stripExif, err := s.policy.stripMode(fileInfo.FileInfo.GetStripExif())
 	if err != nil {
-		return status.Errorf(codes.Internal, "internal error: %v", err)
+		return err
 	}
 
-	if len(findFileName) > 0 {
-		return status.Errorf(codes.AlreadyExists, "file already exists: %s", findFileName)
+	// Хеш считаем по мере приема чанков, при докачке восстанавливаем его из сессии
+	var hasher hash.Hash
+	if sessionID == "" {
+		sessionID, err = s.openUploadSession(fileName, namespace, offset)
+		hasher = sha256.New()
+	} else {
+		namespace, hasher, err = s.resumeUploadSession(sessionID, fileName, namespace, offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	// id сессии отдаем сразу в заголовке, чтобы клиент мог докачать файл после обрыва
+	if err := stream.SendHeader(metadata.Pairs(UploadSessionHeader, sessionID)); err != nil {
+		s.log.Errorf("failed to send upload session header: %v", err)
+		return status.Errorf(codes.Internal, "failed to send upload session: %v", err)
 	}
-	//
 
-	var imageSize int
-	imageSize = 0
+	imageSize := offset
 
 	var success bool //Если запрос не дойдет до конца, то удалим файл(если мы получим не все данные, ошибка в базе etc)
 	success = false
+	// Если оборвался поток, файл и сессию оставляем для докачки
+	keepSession := false
+
+	// Квоты проверяем до приема данных и на каждом чанке по накопленному размеру
+	usage, err := s.storage.GetNamespaceUsage(namespace, sessionID)
+	if err != nil {
+		s.log.Errorf("failed to get namespace usage: %v", err)
+		return status.Errorf(codes.Internal, "failed to get namespace usage: %v", err)
+	}
 
 	defer func() {
-		defer s.diskSaver.DeleteFile(s.log, fileName, success)
+		if keepSession {
+			s.log.Infof("upload session %s interrupted at offset %d", sessionID, imageSize)
+			return
+		}
+		s.diskSaver.DeleteFile(s.log, sessionID, success)
+		if !success {
+			if err := s.storage.DeleteUploadSession(sessionID); err != nil {
+				s.log.Errorf("failed to delete upload session: %v", err)
+			}
+		}
 	}()
 
+	if err := s.limits.checkQuota(namespace, usage, imageSize); err != nil {
+		return err
+	}
+
+	// MIME-тип определяется по первым байтам содержимого, расширение только сверяется с ним.
+	// От типа зависят сжатие, EXIF и миниатюры. Тип проверяется, как только на диске есть
+	// mimetype.SniffLen байт, чтобы отклонить загрузку, не принимая остальное
+	var mimeType, declaredMimeType string
+	sniffed := false
+	detectType := func() error {
+		sniffed = true
+		detected, err := s.diskSaver.DetectType(sessionID)
+		if err != nil {
+			s.log.Errorf("failed to detect content type of %s: %v", fileName, err)
+			return status.Errorf(codes.Internal, "failed to detect content type: %v", err)
+		}
+		mimeType, declaredMimeType, err = s.policy.contentType(mimetype.ByExtension(fileName), detected)
+		if err != nil {
+			s.log.Warnf("upload of %s rejected: %v", fileName, err)
+			return err
+		}
+		if declaredMimeType != "" {
+			s.log.Warnf("content of %s is %s, but its extension says %s", fileName, mimeType, declaredMimeType)
+		}
+		return nil
+	}
+	if imageSize >= mimetype.SniffLen {
+		if err := detectType(); err != nil {
+			return err
+		}
+	}
+
 	for {
 		s.log.Info("Waiting for file data...")
 		req, err := stream.Recv()
@@ -106,41 +243,107 @@ func (s *serverAPI) Upload(stream pb.GuploadService_UploadServer) error {
 		}
 		if err != nil {
 			s.log.Error(op, err)
+			keepSession = true
 			return status.Errorf(codes.Internal, "failed to receive file data: %v", err)
 		}
 
 		chunk := req.GetContent()
 		size := len(chunk)
 
-		if size > maxImageSize {
-			return status.Errorf(codes.InvalidArgument, "file size exceeds the maximum allowed size")
+		if err := s.limits.checkQuota(namespace, usage, imageSize+int64(size)); err != nil {
+			s.log.Warnf("upload of %s rejected: %v", fileName, err)
+			return err
 		}
 
-		imageSize += len(chunk)
-
-		err = s.diskSaver.DiskSave(ctx, fileName, chunk)
+		// Данные копятся в staging-файле сессии и видны под именем файла только после StoreBlob
+		err = s.diskSaver.DiskSave(ctx, sessionID, chunk)
 		if err != nil {
 			s.log.Errorf("failed to save image: %v", err)
 			return status.Errorf(codes.Internal, "failed to save image: %v", err)
 		}
 
+		imageSize += int64(size)
+		hasher.Write(chunk)
+
+		hashState, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to save checksum state: %v", err)
+		}
+
+		err = s.storage.UpdateUploadOffset(sessionID, imageSize, hashState)
+		if err != nil {
+			s.log.Errorf("failed to update upload offset: %v", err)
+			return status.Errorf(codes.Internal, "failed to update upload offset: %v", err)
+		}
+
+		if !sniffed && imageSize >= mimetype.SniffLen {
+			if err := detectType(); err != nil {
+				return err
+			}
+		}
+	}
+
+	checksumm := hex.EncodeToString(hasher.Sum(nil))
+	if expectedChecksum != "" && checksumm != expectedChecksum {
+		s.log.Errorf("checksum mismatch for %s: expected %s, got %s", fileName, expectedChecksum, checksumm)
+		return status.Errorf(codes.DataLoss, "checksum mismatch: expected %s, got %s", expectedChecksum, checksumm)
 	}
 
-	checksumm, err := utils.CalculateChecksum(fileName)
-	if err != nil {
-		s.log.Errorf("failed to calculate checksum: %v", err)
+	if !sniffed {
+		if err := detectType(); err != nil {
+			return err
+		}
+	}
+
+	// sha256 клиента сверяется с тем, что он отправил, а сохраняется и дедуплицируется файл без вырезанного EXIF
+	var fileExif *catalog.Exif
+	if mimeType == "image/jpeg" {
+		fileExif, checksumm, imageSize, err = s.processExif(sessionID, fileName, stripExif, checksumm, imageSize)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = s.storage.SaveImage(fileName, imageSize, fileExtension, checksumm, time.Now())
+	// Одинаковое содержимое хранится на диске один раз, запись в files ссылается на blob.
+	// Staging-файл переносится в blob только после коммита записи в базе
+	var version int64
+	blobKey := s.diskSaver.BlobKey(checksumm)
+	err = s.diskSaver.StoreBlob(sessionID, checksumm, mimeType, func(stored catalog.StoredBlob) (bool, error) {
+		var created bool
+		version, created, err = s.storage.SaveImage(fileName, namespace, blobKey, int(imageSize), mimeType, declaredMimeType, checksumm, stored, time.Now())
+		return created, err
+	}, func() error {
+		// Версия уже записана, но содержимое до хранилища не дошло: она удаляется вместе с записью blob-а,
+		// иначе следующая загрузка того же содержимого сочтет его сохраненным
+		_, err := s.storage.DiscardFile(fileName, version)
+		return err
+	})
 
 	if err != nil {
 		s.log.Errorf("failed to save image info: %v", err)
 		return status.Errorf(codes.Internal, "failed to save image info: %v", err)
 	}
 
+	success = true
+
+	if err := s.storage.DeleteUploadSession(sessionID); err != nil {
+		s.log.Errorf("failed to delete upload session: %v", err)
+	}
+
+	if fileExif != nil {
+		s.saveExif(fileName, version, *fileExif)
+	}
+
+	// Файл уже сохранен: без миниатюр загрузка все равно успешна, галерея покажет оригинал
+	if _, err := s.thumbnails.Generate(context.Background(), fileName, version, mimeType); err != nil {
+		s.log.Errorf("failed to generate thumbnails of %s: %v", fileName, err)
+	}
+
 	response := &pb.UploadResponse{
 		Message: "File uploaded successfully",
+		Id:      sessionID,
 		Code:    pb.UploadStatusCode_Ok,
+		Version: version,
 	}
 
 	err = stream.SendAndClose(response)
@@ -149,69 +352,570 @@ func (s *serverAPI) Upload(stream pb.GuploadService_UploadServer) error {
 		return status.Errorf(codes.Internal, "failed to send response: %v", err)
 	}
 
-	s.log.Info("File uploaded successfully ", fileName, "size KB: ", imageSize)
+	s.log.Info("File uploaded successfully ", fileName, " version: ", version, " size KB: ", imageSize)
 
-	success = true
 	return err
 }
 
+// openUploadSession заводит новую сессию загрузки для файла
+func (s *serverAPI) openUploadSession(fileName string, namespace string, offset int64) (string, error) {
+	if offset != 0 {
+		return "", status.Errorf(codes.InvalidArgument, "offset requires an upload session id")
+	}
+
+	// Повторная загрузка файла с тем же именем создает его новую версию
+	findFileName, err := s.storage.FindFileByName(fileName)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "internal error: %v", err)
+	}
+
+	if len(findFileName) > 0 {
+		s.log.Infof("File %s already exists, uploading a new version", findFileName)
+	}
+
+	sessionID, err := utils.NewSessionID()
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to create upload session: %v", err)
+	}
+
+	// У каждой загрузки своя сессия и свой staging-файл, поэтому брошенная загрузка не мешает новым версиям
+	if err := s.storage.CreateUploadSession(sessionID, fileName, namespace); err != nil {
+		s.log.Errorf("failed to create upload session: %v", err)
+		return "", status.Errorf(codes.Internal, "failed to create upload session: %v", err)
+	}
+
+	return sessionID, nil
+}
+
+// resumeUploadSession проверяет, что клиент продолжает сессию с сохраненного смещения,
+// отрезает байты, записанные на диск после последнего подтвержденного смещения,
+// и восстанавливает хеш уже принятых данных. Возвращает пространство имен сессии
+func (s *serverAPI) resumeUploadSession(sessionID string, fileName string, namespace string, offset int64) (string, hash.Hash, error) {
+	session, err := s.storage.GetUploadSession(sessionID)
+	if err != nil {
+		if errors.Is(err, catalog.ErrSessionNotFound) {
+			return "", nil, status.Errorf(codes.NotFound, "upload session not found: %s", sessionID)
+		}
+		return "", nil, status.Errorf(codes.Internal, "internal error: %v", err)
+	}
+
+	if session.FileName != fileName {
+		return "", nil, status.Errorf(codes.InvalidArgument, "upload session %s belongs to another file", sessionID)
+	}
+
+	if namespace != "" && session.Namespace != namespace {
+		return "", nil, status.Errorf(codes.InvalidArgument, "upload session %s belongs to another namespace", sessionID)
+	}
+
+	if session.Offset != offset {
+		return "", nil, status.Errorf(codes.FailedPrecondition, "upload session %s expects offset %d", sessionID, session.Offset)
+	}
+
+	hasher := sha256.New()
+	if len(session.HashState) > 0 {
+		if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState); err != nil {
+			return "", nil, status.Errorf(codes.Internal, "failed to restore checksum state: %v", err)
+		}
+	} else if session.Offset > 0 {
+		return "", nil, status.Errorf(codes.FailedPrecondition, "upload session %s has no checksum state, restart the upload", sessionID)
+	}
+
+	if err := s.diskSaver.Truncate(sessionID, session.Offset); err != nil {
+		s.log.Errorf("failed to prepare file for resume: %v", err)
+		return "", nil, status.Errorf(codes.Internal, "failed to resume upload: %v", err)
+	}
+
+	s.log.Infof("Resuming upload session %s for %s at offset %d", sessionID, fileName, offset)
+	return session.Namespace, hasher, nil
+}
+
+func (s *serverAPI) GetUploadOffset(ctx context.Context, req *pb.UploadOffsetRequest) (*pb.UploadOffsetResponse, error) {
+	sessionID := req.GetSessionId()
+	if sessionID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "session id is required")
+	}
+
+	session, err := s.storage.GetUploadSession(sessionID)
+	if err != nil {
+		if errors.Is(err, catalog.ErrSessionNotFound) {
+			return nil, status.Errorf(codes.NotFound, "upload session not found: %s", sessionID)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get upload session: %v", err)
+	}
+
+	return &pb.UploadOffsetResponse{
+		SessionId: session.ID,
+		FileName:  session.FileName,
+		Offset:    session.Offset,
+	}, nil
+}
+
 func (s *serverAPI) Download(req *pb.DownloadRequest, stream pb.GuploadService_DownloadServer) error {
-	//ctx := stream.Context()
+	ctx := stream.Context()
 
 	fileName := req.GetFileName()
 	if fileName == "" {
 		return status.Errorf(codes.InvalidArgument, "file name is required")
 	}
 
-	fileName = filepath.Join(os.Getenv("PATH_TO_SAVED"), fileName)
-	file, err := os.Open(fileName)
+	offset := req.GetOffset()
+	length := req.GetLength()
+	if offset < 0 || length < 0 {
+		return status.Errorf(codes.InvalidArgument, "offset and length must not be negative")
+	}
+
+	version := req.GetVersion()
+	if version < 0 {
+		return status.Errorf(codes.InvalidArgument, "version must not be negative")
+	}
+
+	// path_to_file - ключ содержимого в хранилище, и для blob-ов, и для старых файлов
+	location, err := s.storage.FindFileLocation(fileName, version)
+	if err != nil {
+		if errors.Is(err, catalog.ErrFileNotFound) {
+			return status.Errorf(codes.NotFound, "file not found: %s", fileName)
+		}
+		return status.Errorf(codes.Internal, "failed to find file: %v", err)
+	}
+	key := location.Path
+
+	if name := req.GetVariant(); name != "" {
+		return s.downloadVariant(ctx, stream, location, name, offset, length)
+	}
+
+	// Для сжатых и зашифрованных файлов offset и length считаются по исходному содержимому
+	size := location.Size
+	if location.Encoding == compress.None && location.KeyID == "" {
+		size, err = s.diskSaver.StatBlob(ctx, key)
+		if err != nil {
+			if errors.Is(err, blob.ErrNotFound) {
+				return status.Errorf(codes.NotFound, "file not found: %s", fileName)
+			}
+			return status.Errorf(codes.Internal, "failed to stat file: %v", err)
+		}
+	}
+
+	// offset == size допустим: пустой ответ для уже докачанного файла
+	if offset > size {
+		return status.Errorf(codes.OutOfRange, "offset %d is beyond file size %d", offset, size)
+	}
+	if offset == size {
+		return nil
+	}
 
+	stored := location.StoredBlob
+	if stored.Encoding != compress.None && offset == 0 && length == 0 && slices.Contains(req.GetAcceptEncoding(), stored.Encoding) {
+		// Клиент распакует сам: отдаем сжатое содержимое без распаковки
+		if err := stream.SendHeader(metadata.Pairs(ContentEncodingHeader, stored.Encoding)); err != nil {
+			return status.Errorf(codes.Internal, "failed to send content encoding: %v", err)
+		}
+		stored.Encoding = compress.None
+	}
+
+	reader, err := s.diskSaver.ReadBlob(ctx, key, stored, offset, length)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, blob.ErrNotFound) {
 			return status.Errorf(codes.NotFound, "file not found: %s", fileName)
 		}
 		return status.Errorf(codes.Internal, "failed to open file: %v", err)
 	}
-	defer file.Close()
+	defer reader.Close()
+
+	if err := sendContent(stream, reader); err != nil {
+		return err
+	}
+
+	s.access.Accessed(location)
 
+	return nil
+}
+
+// downloadVariant отдает диапазон варианта файла. Скачивание варианта не считается скачиванием
+// оригинала и не возвращает его из холодного хранилища
+func (s *serverAPI) downloadVariant(ctx context.Context, stream pb.GuploadService_DownloadServer, location catalog.FileLocation, name string, offset int64, length int64) error {
+	var variants []catalog.Variant
+	if location.Checksum != "" {
+		var err error
+		variants, err = s.storage.ListBlobVariants(location.Checksum)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to list variants: %v", err)
+		}
+	}
+
+	index := slices.IndexFunc(variants, func(v catalog.Variant) bool { return v.Name == name })
+	if index < 0 {
+		return status.Errorf(codes.NotFound, "variant %s of %s not found", name, location.FileName)
+	}
+	variant := variants[index]
+
+	if offset > variant.Size {
+		return status.Errorf(codes.OutOfRange, "offset %d is beyond variant size %d", offset, variant.Size)
+	}
+	if offset == variant.Size {
+		return nil
+	}
+
+	reader, err := s.diskSaver.ReadVariant(ctx, location.StoredBlob, variant, offset, length)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return status.Errorf(codes.NotFound, "variant %s of %s not found", name, location.FileName)
+		}
+		return status.Errorf(codes.Internal, "failed to open variant: %v", err)
+	}
+	defer reader.Close()
+
+	return sendContent(stream, reader)
+}
+
+func sendContent(stream pb.GuploadService_DownloadServer, reader io.Reader) error {
 	//TODO: брать из конфига
 	buffer := make([]byte, 1024*64)
 	for {
-		n, err := file.Read(buffer)
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			chunk := &pb.DownloadResponse{
+				Content: buffer[:n],
+			}
+
+			if err := stream.Send(chunk); err != nil {
+				return status.Errorf(codes.Internal, "failed to send chunk: %v", err)
+			}
+		}
 		if err != nil {
 			if err == io.EOF {
-				break
+				return nil
 			}
 			return status.Errorf(codes.Internal, "failed to read file: %v", err)
 		}
+	}
+}
 
-		chunk := &pb.DownloadResponse{
-			Content: buffer[:n],
-		}
+// TODO conf
+const maxTransformOperations = 16
+
+func (s *serverAPI) Transform(req *pb.TransformRequest, stream pb.GuploadService_TransformServer) error {
+	fileName := req.GetFileName()
+	if fileName == "" {
+		return status.Errorf(codes.InvalidArgument, "file name is required")
+	}
+
+	version := req.GetVersion()
+	if version < 0 {
+		return status.Errorf(codes.InvalidArgument, "version must not be negative")
+	}
+
+	if len(req.GetOperations()) > maxTransformOperations {
+		return status.Errorf(codes.InvalidArgument, "at most %d operations are allowed", maxTransformOperations)
+	}
+	ops, err := transformOperations(req.GetOperations())
+	if err != nil {
+		return err
+	}
 
-		if err := stream.Send(chunk); err != nil {
-			return status.Errorf(codes.Internal, "failed to send chunk: %v", err)
+	reader, mimeType, err := s.transforms.Transform(stream.Context(), fileName, version, ops)
+	if err != nil {
+		switch {
+		case errors.Is(err, catalog.ErrFileNotFound), errors.Is(err, blob.ErrNotFound):
+			return status.Errorf(codes.NotFound, "file not found: %s", fileName)
+		case errors.Is(err, imaging.ErrInvalidOperation):
+			return status.Errorf(codes.InvalidArgument, "%v", err)
+		case errors.Is(err, imaging.ErrTooLarge):
+			return status.Errorf(codes.ResourceExhausted, "%v", err)
+		case errors.Is(err, imaging.ErrUnsupported):
+			return status.Errorf(codes.FailedPrecondition, "%s is not a JPEG, PNG or GIF image", fileName)
 		}
+		return status.Errorf(codes.Internal, "failed to transform file: %v", err)
 	}
+	defer reader.Close()
 
-	return nil
+	if err := stream.SendHeader(metadata.Pairs(ContentTypeHeader, mimeType)); err != nil {
+		return status.Errorf(codes.Internal, "failed to send content type: %v", err)
+	}
+
+	return sendContent(stream, reader)
 }
 
+func transformOperations(operations []*pb.ImageOperation) ([]imaging.Operation, error) {
+	ops := make([]imaging.Operation, 0, len(operations))
+	for i, operation := range operations {
+		var op imaging.Operation
+		switch o := operation.GetOp().(type) {
+		case *pb.ImageOperation_Resize:
+			op = imaging.Operation{Kind: imaging.OpResize, Width: int(o.Resize.GetWidth()), Height: int(o.Resize.GetHeight())}
+		case *pb.ImageOperation_Crop:
+			op = imaging.Operation{Kind: imaging.OpCrop, X: int(o.Crop.GetX()), Y: int(o.Crop.GetY()),
+				Width: int(o.Crop.GetWidth()), Height: int(o.Crop.GetHeight())}
+		case *pb.ImageOperation_Rotate:
+			op = imaging.Operation{Kind: imaging.OpRotate, Degrees: int(o.Rotate.GetDegrees())}
+		case *pb.ImageOperation_Flip:
+			op = imaging.Operation{Kind: imaging.OpFlip, Horizontal: o.Flip.GetHorizontal()}
+		case *pb.ImageOperation_Format:
+			op = imaging.Operation{Kind: imaging.OpFormat, Format: strings.ToLower(o.Format.GetFormat()),
+				Quality: int(o.Format.GetQuality())}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "operation %d is empty", i)
+		}
+
+		if err := op.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "operation %d: %v", i, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// TODO conf
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
 func (s *serverAPI) ListFiles(ctx context.Context, req *pb.ListFilesRequest) (*pb.ListFilesResponse, error) {
-	files, err := s.storage.ListFiles()
+	filter, err := listFilesFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := filter.Limit
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	filter.Limit++
+
+	files, err := s.storage.ListFiles(filter)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list files: %v", err)
 	}
 
+	var nextPageToken string
+	if len(files) > pageSize {
+		files = files[:pageSize]
+		sortBy, descending := filter.Sort()
+		nextPageToken = encodePageToken(sortBy, descending, catalog.CursorFor(files[len(files)-1], sortBy))
+	}
+
 	var fileInfos []*pb.FileInfo
 	for _, dbFileInfo := range files {
-		fileInfo := &pb.FileInfo{
-			FileName:  dbFileInfo.FileName,
-			CreatedAt: dbFileInfo.CreatedAt.String(),
-			UpdatedAt: dbFileInfo.CreatedAt.String(),
+		fileInfos = append(fileInfos, toFileInfo(dbFileInfo))
+	}
+
+	return &pb.ListFilesResponse{Files: fileInfos, NextPageToken: nextPageToken}, nil
+}
+
+func (s *serverAPI) GetFileInfo(ctx context.Context, req *pb.GetFileInfoRequest) (*pb.FileInfo, error) {
+	fileName := req.GetFileName()
+	if fileName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "file name is required")
+	}
+
+	if req.GetVersion() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "version must not be negative")
+	}
+
+	file, err := s.storage.GetFileInfo(fileName, req.GetVersion())
+	if err != nil {
+		if errors.Is(err, catalog.ErrFileNotFound) {
+			return nil, status.Errorf(codes.NotFound, "file not found: %s", fileName)
 		}
-		fileInfos = append(fileInfos, fileInfo)
+		return nil, status.Errorf(codes.Internal, "failed to get file info: %v", err)
+	}
+
+	fileInfo := toFileInfo(file)
+
+	fileExif, err := s.storage.GetFileExif(file.ID)
+	switch {
+	case err == nil:
+		fileInfo.Exif = toFileExif(fileExif)
+	case !errors.Is(err, catalog.ErrExifNotFound):
+		return nil, status.Errorf(codes.Internal, "failed to get EXIF: %v", err)
+	}
+
+	if file.Checksum != "" {
+		moves, err := s.storage.ListBlobMoves(file.Checksum)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list tier moves: %v", err)
+		}
+		for _, move := range moves {
+			fileInfo.Moves = append(fileInfo.Moves, &pb.TierMove{
+				FromTier: move.FromTier,
+				ToTier:   move.ToTier,
+				MovedAt:  move.MovedAt.String(),
+			})
+		}
+
+		variants, err := s.storage.ListBlobVariants(file.Checksum)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list variants: %v", err)
+		}
+		for _, variant := range variants {
+			fileInfo.Variants = append(fileInfo.Variants, &pb.FileVariant{
+				Name:     variant.Name,
+				MimeType: variant.MimeType,
+				Width:    int32(variant.Width),
+				Height:   int32(variant.Height),
+				Size:     variant.Size,
+			})
+		}
+	}
+
+	return fileInfo, nil
+}
+
+func toFileInfo(file catalog.FileInfo) *pb.FileInfo {
+	fileInfo := &pb.FileInfo{
+		Id:               strconv.FormatInt(file.ID, 10),
+		FileName:         file.FileName,
+		CreatedAt:        file.CreatedAt.String(),
+		UpdatedAt:        file.UpdatedAt.String(),
+		Version:          file.Version,
+		Size:             file.Size,
+		MimeType:         file.MimeType,
+		DeclaredMimeType: file.DeclaredMimeType,
+		Checksum:         file.Checksum,
+		Encoding:         file.Encoding,
+		StoredSize:       file.StoredSize,
+		Tier:             file.Tier,
+	}
+	if !file.DeletedAt.IsZero() {
+		fileInfo.DeletedAt = file.DeletedAt.String()
+	}
+	if !file.LastAccessedAt.IsZero() {
+		fileInfo.LastAccessedAt = file.LastAccessedAt.String()
+	}
+	return fileInfo
+}
+
+func toFileExif(exif catalog.Exif) *pb.FileExif {
+	fileExif := &pb.FileExif{
+		CameraMake:  exif.CameraMake,
+		CameraModel: exif.CameraModel,
+		LensMake:    exif.LensMake,
+		LensModel:   exif.LensModel,
+		Orientation: int32(exif.Orientation),
+		HasGps:      exif.HasGPS,
+		Latitude:    exif.Latitude,
+		Longitude:   exif.Longitude,
+		Altitude:    exif.Altitude,
+	}
+	if !exif.CapturedAt.IsZero() {
+		fileExif.CapturedAt = exif.CapturedAt.String()
+	}
+	return fileExif
+}
+
+func listFilesFilter(req *pb.ListFilesRequest) (catalog.ListFilesFilter, error) {
+	filter := catalog.ListFilesFilter{
+		FileName:       req.GetFileName(),
+		IncludeDeleted: req.GetIncludeDeleted(),
+		NamePrefix:     req.GetNamePrefix(),
+		MimeType:       req.GetMimeType(),
+		MimeMismatch:   req.GetMimeMismatch(),
+		MinSize:        req.GetMinSize(),
+		MaxSize:        req.GetMaxSize(),
+		Descending:     req.GetDescending(),
+		Limit:          int(req.GetPageSize()),
+	}
+
+	if filter.Limit < 0 {
+		return filter, status.Errorf(codes.InvalidArgument, "page size must not be negative")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+
+	if filter.MinSize < 0 || filter.MaxSize < 0 || (filter.MaxSize > 0 && filter.MinSize > filter.MaxSize) {
+		return filter, status.Errorf(codes.InvalidArgument, "invalid size range")
+	}
+
+	switch req.GetSortBy() {
+	case pb.SortField_SortDefault:
+		filter.SortBy = catalog.SortDefault
+	case pb.SortField_SortByName:
+		filter.SortBy = catalog.SortByName
+	case pb.SortField_SortByCreatedAt:
+		filter.SortBy = catalog.SortByCreated
+	case pb.SortField_SortBySize:
+		filter.SortBy = catalog.SortBySize
+	default:
+		return filter, status.Errorf(codes.InvalidArgument, "unknown sort field: %v", req.GetSortBy())
+	}
+
+	var err error
+	if req.GetCreatedAfter() != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, req.GetCreatedAfter()); err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "invalid created_after: %v", err)
+		}
+	}
+	if req.GetCreatedBefore() != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, req.GetCreatedBefore()); err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "invalid created_before: %v", err)
+		}
+	}
+
+	if req.GetPageToken() != "" {
+		sortBy, descending := filter.Sort()
+		if filter.After, err = decodePageToken(req.GetPageToken(), sortBy, descending); err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	return filter, nil
+}
+
+func (s *serverAPI) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	fileName := req.GetFileName()
+	if fileName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "file name is required")
+	}
+
+	deleted, err := s.storage.SoftDeleteFile(fileName, req.GetVersion())
+	if err != nil {
+		s.log.Errorf("failed to delete file: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to delete file: %v", err)
+	}
+
+	if deleted == 0 {
+		return nil, status.Errorf(codes.NotFound, "file not found: %s", fileName)
+	}
+
+	s.log.Infof("File %s marked deleted, versions: %d", fileName, deleted)
+	return &pb.DeleteResponse{Deleted: deleted}, nil
+}
+
+func (s *serverAPI) Restore(ctx context.Context, req *pb.RestoreRequest) (*pb.RestoreResponse, error) {
+	fileName := req.GetFileName()
+	if fileName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "file name is required")
+	}
+
+	restored, err := s.storage.RestoreFile(fileName, req.GetVersion())
+	if err != nil {
+		s.log.Errorf("failed to restore file: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to restore file: %v", err)
+	}
+
+	if restored == 0 {
+		return nil, status.Errorf(codes.NotFound, "deleted file not found: %s", fileName)
+	}
+
+	s.log.Infof("File %s restored, versions: %d", fileName, restored)
+	return &pb.RestoreResponse{Restored: restored}, nil
+}
+
+func (s *serverAPI) Purge(ctx context.Context, req *pb.PurgeRequest) (*pb.PurgeResponse, error) {
+	// Случайный Force без имени файла не должен стирать все удаленные файлы разом
+	if req.GetForce() && req.GetFileName() == "" && !req.GetAllFiles() {
+		return nil, status.Errorf(codes.InvalidArgument, "forced purge needs a file name or AllFiles")
+	}
+	if req.GetAllFiles() && req.GetFileName() != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "AllFiles and a file name are mutually exclusive")
+	}
+
+	purged, err := s.purger.Purge(req.GetFileName(), req.GetForce())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to purge files: %v", err)
 	}
 
-	return &pb.ListFilesResponse{Files: fileInfos}, nil
+	return &pb.PurgeResponse{Purged: purged}, nil
 }
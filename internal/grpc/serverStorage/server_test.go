@@ -0,0 +1,766 @@
+package serverStorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "imagestorage/contracts/gen/go/imageStorage"
+	"imagestorage/internal/compress"
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/services/purgeService"
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/bolt"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// uploadStream - поток Upload без сети: отдает requests, затем err (nil - io.EOF)
+type uploadStream struct {
+	grpc.ServerStream
+	requests []*pb.UploadFileRequest
+	err      error
+	header   metadata.MD
+	response *pb.UploadResponse
+}
+
+func (s *uploadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *uploadStream) Recv() (*pb.UploadFileRequest, error) {
+	if len(s.requests) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *uploadStream) SendHeader(md metadata.MD) error {
+	s.header = md
+	return nil
+}
+
+func (s *uploadStream) SendAndClose(response *pb.UploadResponse) error {
+	s.response = response
+	return nil
+}
+
+// downloadStream - поток Download без сети, копит отправленное содержимое
+type downloadStream struct {
+	grpc.ServerStream
+	header  metadata.MD
+	content bytes.Buffer
+}
+
+func (s *downloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *downloadStream) SendHeader(md metadata.MD) error {
+	s.header = md
+	return nil
+}
+
+func (s *downloadStream) Send(response *pb.DownloadResponse) error {
+	s.content.Write(response.GetContent())
+	return nil
+}
+
+func download(s *serverAPI, req *pb.DownloadRequest) (*downloadStream, error) {
+	stream := &downloadStream{}
+	return stream, s.Download(req, stream)
+}
+
+type noAccess struct{}
+
+func (noAccess) Accessed(location catalog.FileLocation) {}
+
+type noThumbnails struct{}
+
+func (noThumbnails) Generate(ctx context.Context, fileName string, version int64, mimeType string) (int, error) {
+	return 0, nil
+}
+
+func newTestServer(t *testing.T, limits Limits, policy Policy) (*serverAPI, *bolt.Storage) {
+	t.Helper()
+	return newTestServerWith(t, limits, policy, nil, func(saveDir string) blob.Store { return blob.NewFileStore(saveDir) })
+}
+
+// newTestServerWith - сервер с политикой сжатия compression и хранилищем blob-ов, которое blobs строит в каталоге данных
+func newTestServerWith(t *testing.T, limits Limits, policy Policy, compression compress.Policy, blobs func(saveDir string) blob.Store) (*serverAPI, *bolt.Storage) {
+	t.Helper()
+
+	dir := t.TempDir()
+	st, err := bolt.New(filepath.Join(dir, "catalog.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	saveDir := filepath.Join(dir, "images")
+	images := imageService.NewImageService(log, saveDir, blobs(saveDir), compression, nil, st, nil, st)
+
+	server := &serverAPI{
+		log:        log,
+		storage:    st,
+		diskSaver:  images,
+		purger:     purgeService.NewPurgeService(log, st, images, time.Hour, time.Hour),
+		access:     noAccess{},
+		thumbnails: noThumbnails{},
+		limits:     limits,
+		policy:     policy,
+	}
+	return server, st
+}
+
+func fileInfoRequest(info *pb.FileUploadInfo) *pb.UploadFileRequest {
+	return &pb.UploadFileRequest{Data: &pb.UploadFileRequest_FileInfo{FileInfo: info}}
+}
+
+func contentRequests(chunks ...[]byte) []*pb.UploadFileRequest {
+	var requests []*pb.UploadFileRequest
+	for _, chunk := range chunks {
+		requests = append(requests, &pb.UploadFileRequest{Data: &pb.UploadFileRequest_Content{Content: chunk}})
+	}
+	return requests
+}
+
+// upload отправляет info и chunks, поток обрывается ошибкой broken, если она задана
+func upload(s *serverAPI, info *pb.FileUploadInfo, broken error, chunks ...[]byte) (*uploadStream, error) {
+	stream := &uploadStream{
+		requests: append([]*pb.UploadFileRequest{fileInfoRequest(info)}, contentRequests(chunks...)...),
+		err:      broken,
+	}
+	return stream, s.Upload(stream)
+}
+
+func sessionOf(stream *uploadStream) string {
+	if values := stream.header.Get(UploadSessionHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var errBroken = status.Error(codes.Canceled, "connection lost")
+
+func TestUploadResume(t *testing.T) {
+	first := bytes.Repeat([]byte("first part of the file\n"), 40)
+	second := bytes.Repeat([]byte("second part of the file\n"), 40)
+
+	tests := []struct {
+		name      string
+		fileName  string
+		namespace string
+		session   string
+		offset    int64
+		want      codes.Code
+	}{
+		{name: "resume from saved offset", fileName: "notes.txt", namespace: "team", offset: int64(len(first)), want: codes.OK},
+		{name: "namespace taken from session", fileName: "notes.txt", offset: int64(len(first)), want: codes.OK},
+		{name: "offset behind saved one", fileName: "notes.txt", namespace: "team", offset: 10, want: codes.FailedPrecondition},
+		{name: "offset ahead of saved one", fileName: "notes.txt", namespace: "team", offset: int64(len(first)) + 1, want: codes.FailedPrecondition},
+		{name: "session of another file", fileName: "other.txt", namespace: "team", offset: int64(len(first)), want: codes.InvalidArgument},
+		{name: "session of another namespace", fileName: "notes.txt", namespace: "guests", offset: int64(len(first)), want: codes.InvalidArgument},
+		{name: "unknown session", fileName: "notes.txt", session: "0123456789abcdef", offset: int64(len(first)), want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, st := newTestServer(t, Limits{}, Policy{})
+
+			stream, err := upload(server, &pb.FileUploadInfo{FileName: "notes.txt", Namespace: "team"}, errBroken, first)
+			if status.Code(err) != codes.Internal {
+				t.Fatalf("interrupted upload: %v", err)
+			}
+			sessionID := sessionOf(stream)
+			if sessionID == "" {
+				t.Fatalf("no session id in header")
+			}
+
+			offset, err := server.GetUploadOffset(context.Background(), &pb.UploadOffsetRequest{SessionId: sessionID})
+			if err != nil {
+				t.Fatalf("get upload offset: %v", err)
+			}
+			if offset.Offset != int64(len(first)) {
+				t.Fatalf("saved offset %d, want %d", offset.Offset, len(first))
+			}
+
+			resumed := sessionID
+			if tt.session != "" {
+				resumed = tt.session
+			}
+			info := &pb.FileUploadInfo{FileName: tt.fileName, Namespace: tt.namespace, SessionId: resumed, Offset: tt.offset,
+				Sha256: checksumOf(append(first, second...))}
+			stream, err = upload(server, info, nil, second)
+			if status.Code(err) != tt.want {
+				t.Fatalf("resume: got %v, want %s", err, tt.want)
+			}
+			if tt.want != codes.OK {
+				return
+			}
+
+			if stream.response.GetVersion() != 1 {
+				t.Fatalf("version %d, want 1", stream.response.GetVersion())
+			}
+			file, err := st.GetFileInfo("notes.txt", 0)
+			if err != nil {
+				t.Fatalf("get file info: %v", err)
+			}
+			if file.Size != int64(len(first)+len(second)) || file.Checksum != checksumOf(append(first, second...)) {
+				t.Fatalf("stored %d bytes with sha256 %s", file.Size, file.Checksum)
+			}
+			if _, err := st.GetUploadSession(sessionID); !errors.Is(err, catalog.ErrSessionNotFound) {
+				t.Fatalf("session left after upload: %v", err)
+			}
+		})
+	}
+}
+
+// Брошенная загрузка не мешает загружать новые версии того же файла
+func TestUploadSessionsOfOneFile(t *testing.T) {
+	server, st := newTestServer(t, Limits{}, Policy{})
+	data := []byte("interrupted upload")
+
+	stream, err := upload(server, &pb.FileUploadInfo{FileName: "a.txt"}, errBroken, data)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("interrupted upload: %v", err)
+	}
+	interrupted, err := st.GetUploadSession(sessionOf(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err = upload(server, &pb.FileUploadInfo{FileName: "a.txt"}, nil, data)
+	if err != nil {
+		t.Fatalf("second session of a.txt: %v", err)
+	}
+	if stream.response.GetVersion() != 1 {
+		t.Fatalf("version %d, want 1", stream.response.GetVersion())
+	}
+	if _, err := upload(server, &pb.FileUploadInfo{FileName: "c.txt", Offset: 5}, nil, data); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("offset without session: got %v, want %s", err, codes.InvalidArgument)
+	}
+
+	info := &pb.FileUploadInfo{FileName: "a.txt", SessionId: interrupted.ID, Offset: interrupted.Offset}
+	stream, err = upload(server, info, nil, data[interrupted.Offset:])
+	if err != nil {
+		t.Fatalf("finish the interrupted upload: %v", err)
+	}
+	if stream.response.GetVersion() != 2 {
+		t.Fatalf("version %d, want 2", stream.response.GetVersion())
+	}
+}
+
+var errPut = errors.New("disk is full")
+
+// failingPuts отказывает в Put, пока fail. PutFile у него нет, поэтому все записи идут через Put
+type failingPuts struct {
+	blob.Store
+	fail bool
+}
+
+func (s *failingPuts) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if s.fail {
+		return errPut
+	}
+	return s.Store.Put(ctx, key, r, size)
+}
+
+func TestUploadPutFailed(t *testing.T) {
+	data := []byte("content that did not reach the blob store")
+	store := &failingPuts{fail: true}
+	server, st := newTestServerWith(t, Limits{}, Policy{}, nil, func(saveDir string) blob.Store {
+		store.Store = blob.NewFileStore(saveDir)
+		return store
+	})
+
+	if _, err := upload(server, &pb.FileUploadInfo{FileName: "a.txt"}, nil, data); status.Code(err) != codes.Internal {
+		t.Fatalf("upload with a failing put: got %v, want %s", err, codes.Internal)
+	}
+	// Версия без содержимого не остается в каталоге даже удаленной: ее blob больше не считается сохраненным
+	if _, err := st.FindFileLocation("a.txt", 0); !errors.Is(err, catalog.ErrFileNotFound) {
+		t.Fatalf("version without content: %v", err)
+	}
+	if deleted, err := st.ListDeletedFiles(time.Now().Add(time.Hour), "a.txt"); err != nil || len(deleted) != 0 {
+		t.Fatalf("deleted versions %v, %v", deleted, err)
+	}
+
+	store.fail = false
+	stream, err := upload(server, &pb.FileUploadInfo{FileName: "b.txt"}, nil, data)
+	if err != nil {
+		t.Fatalf("upload of the same content: %v", err)
+	}
+	if stream.response.GetCode() != pb.UploadStatusCode_Ok {
+		t.Fatalf("got %v", stream.response)
+	}
+
+	location, err := st.FindFileLocation("b.txt", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := store.Get(context.Background(), location.Path)
+	if err != nil {
+		t.Fatalf("blob of the re-upload: %v", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("stored %q, %v", got, err)
+	}
+}
+
+func TestUploadChecksum(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 300)
+	sum := checksumOf(data)
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		// interrupted - после скольких чанков обрывается первый поток, 0 - без обрыва
+		interrupted int
+		sha256      string
+		want        codes.Code
+	}{
+		{name: "single chunk", chunks: [][]byte{data}, sha256: sum, want: codes.OK},
+		{name: "many chunks", chunks: [][]byte{data[:1], data[1:700], data[700:2999], data[2999:]}, sha256: sum, want: codes.OK},
+		{name: "upper case sha256", chunks: [][]byte{data}, sha256: strings.ToUpper(sum), want: codes.OK},
+		{name: "no sha256", chunks: [][]byte{data}, want: codes.OK},
+		{name: "resumed after first chunk", chunks: [][]byte{data[:1000], data[1000:]}, interrupted: 1, sha256: sum, want: codes.OK},
+		{name: "resumed twice", chunks: [][]byte{data[:10], data[10:20], data[20:]}, interrupted: 2, sha256: sum, want: codes.OK},
+		{name: "mismatch", chunks: [][]byte{data}, sha256: checksumOf(data[1:]), want: codes.DataLoss},
+		{name: "mismatch after resume", chunks: [][]byte{data[:1000], data[1000:]}, interrupted: 1, sha256: checksumOf(data[1:]), want: codes.DataLoss},
+		{name: "not a sha256", chunks: [][]byte{data}, sha256: "abc", want: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, st := newTestServer(t, Limits{}, Policy{})
+
+			info := &pb.FileUploadInfo{FileName: "digits.txt", Sha256: tt.sha256}
+			chunks := tt.chunks
+			var offset int64
+			for sent := 0; sent < tt.interrupted; sent++ {
+				stream, err := upload(server, info, errBroken, chunks[0])
+				if status.Code(err) != codes.Internal {
+					t.Fatalf("interrupted upload: %v", err)
+				}
+				offset += int64(len(chunks[0]))
+				chunks = chunks[1:]
+				info = &pb.FileUploadInfo{FileName: "digits.txt", Sha256: tt.sha256, SessionId: sessionOf(stream), Offset: offset}
+			}
+
+			_, err := upload(server, info, nil, chunks...)
+			if status.Code(err) != tt.want {
+				t.Fatalf("upload: got %v, want %s", err, tt.want)
+			}
+
+			file, err := st.GetFileInfo("digits.txt", 0)
+			if tt.want != codes.OK {
+				if !errors.Is(err, catalog.ErrFileNotFound) {
+					t.Fatalf("rejected upload is in the catalog: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get file info: %v", err)
+			}
+			if file.Checksum != sum {
+				t.Fatalf("stored sha256 %s, want %s", file.Checksum, sum)
+			}
+		})
+	}
+}
+
+func TestUploadHashState(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	tests := []struct {
+		name string
+		// state - что записать в сессию вместо сохраненного состояния хеша, nil - оставить как есть
+		state []byte
+		want  codes.Code
+	}{
+		{name: "saved state", want: codes.OK},
+		{name: "no state", state: []byte{}, want: codes.FailedPrecondition},
+		{name: "corrupt state", state: []byte("not a sha256 state"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, st := newTestServer(t, Limits{}, Policy{})
+
+			stream, err := upload(server, &pb.FileUploadInfo{FileName: "fox.txt"}, errBroken, data[:16])
+			if status.Code(err) != codes.Internal {
+				t.Fatalf("interrupted upload: %v", err)
+			}
+			sessionID := sessionOf(stream)
+
+			session, err := st.GetUploadSession(sessionID)
+			if err != nil {
+				t.Fatalf("get upload session: %v", err)
+			}
+			hasher := sha256.New()
+			if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState); err != nil {
+				t.Fatalf("saved hash state: %v", err)
+			}
+			hasher.Write(data[16:])
+			if got := hex.EncodeToString(hasher.Sum(nil)); got != checksumOf(data) {
+				t.Fatalf("restored hash gives %s, want %s", got, checksumOf(data))
+			}
+
+			if tt.state != nil {
+				if err := st.UpdateUploadOffset(sessionID, session.Offset, tt.state); err != nil {
+					t.Fatalf("update upload offset: %v", err)
+				}
+			}
+
+			info := &pb.FileUploadInfo{FileName: "fox.txt", SessionId: sessionID, Offset: 16, Sha256: checksumOf(data)}
+			if _, err := upload(server, info, nil, data[16:]); status.Code(err) != tt.want {
+				t.Fatalf("resume: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	data := []byte("0123456789abcdef")
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int64
+		want   string
+		code   codes.Code
+	}{
+		{name: "whole file", want: string(data)},
+		{name: "range inside", offset: 3, length: 4, want: "3456"},
+		{name: "up to the end", offset: 10, want: "abcdef"},
+		{name: "length past the end", offset: 12, length: 100, want: "cdef"},
+		{name: "offset at the end", offset: int64(len(data)), want: ""},
+		{name: "offset past the end", offset: int64(len(data)) + 1, code: codes.OutOfRange},
+		{name: "negative offset", offset: -1, code: codes.InvalidArgument},
+		{name: "negative length", length: -1, code: codes.InvalidArgument},
+	}
+
+	server, _ := newTestServer(t, Limits{}, Policy{})
+	if _, err := upload(server, &pb.FileUploadInfo{FileName: "digits.txt"}, nil, data); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := download(server, &pb.DownloadRequest{FileName: "digits.txt", Offset: tt.offset, Length: tt.length})
+			if status.Code(err) != tt.code {
+				t.Fatalf("download: got %v, want %s", err, tt.code)
+			}
+			if tt.code != codes.OK {
+				return
+			}
+			if got := stream.content.String(); got != tt.want {
+				t.Fatalf("content %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := download(server, &pb.DownloadRequest{FileName: "missing.txt"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("download of a missing file: got %v, want %s", err, codes.NotFound)
+	}
+}
+
+func TestDeleteRestorePurge(t *testing.T) {
+	server, _ := newTestServer(t, Limits{}, Policy{})
+	ctx := context.Background()
+	for _, name := range []string{"a.txt", "a.txt", "b.txt"} {
+		if _, err := upload(server, &pb.FileUploadInfo{FileName: name}, nil, []byte("content of "+name)); err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+	}
+
+	steps := []struct {
+		name  string
+		call  func() (int64, error)
+		code  codes.Code
+		count int64
+		// visible - файлы, которые после шага можно скачать
+		visible map[string]bool
+	}{
+		{
+			name: "delete latest version",
+			call: func() (int64, error) {
+				r, err := server.Delete(ctx, &pb.DeleteRequest{FileName: "a.txt", Version: 2})
+				return r.GetDeleted(), err
+			},
+			count: 1, visible: map[string]bool{"a.txt": true, "b.txt": true},
+		},
+		{
+			name: "delete all versions",
+			call: func() (int64, error) {
+				r, err := server.Delete(ctx, &pb.DeleteRequest{FileName: "a.txt"})
+				return r.GetDeleted(), err
+			},
+			count: 1, visible: map[string]bool{"b.txt": true},
+		},
+		{
+			name: "delete missing file",
+			call: func() (int64, error) {
+				r, err := server.Delete(ctx, &pb.DeleteRequest{FileName: "c.txt"})
+				return r.GetDeleted(), err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "delete without name",
+			call: func() (int64, error) {
+				r, err := server.Delete(ctx, &pb.DeleteRequest{})
+				return r.GetDeleted(), err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "restore first version",
+			call: func() (int64, error) {
+				r, err := server.Restore(ctx, &pb.RestoreRequest{FileName: "a.txt", Version: 1})
+				return r.GetRestored(), err
+			},
+			count: 1, visible: map[string]bool{"a.txt": true, "b.txt": true},
+		},
+		{
+			name: "restore file that is not deleted",
+			call: func() (int64, error) {
+				r, err := server.Restore(ctx, &pb.RestoreRequest{FileName: "b.txt"})
+				return r.GetRestored(), err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "forced purge without a file name",
+			call: func() (int64, error) {
+				r, err := server.Purge(ctx, &pb.PurgeRequest{Force: true})
+				return r.GetPurged(), err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "AllFiles with a file name",
+			call: func() (int64, error) {
+				r, err := server.Purge(ctx, &pb.PurgeRequest{FileName: "a.txt", Force: true, AllFiles: true})
+				return r.GetPurged(), err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "purge within grace period",
+			call: func() (int64, error) {
+				r, err := server.Purge(ctx, &pb.PurgeRequest{})
+				return r.GetPurged(), err
+			},
+			count: 0, visible: map[string]bool{"a.txt": true, "b.txt": true},
+		},
+		{
+			name: "forced purge of all files",
+			call: func() (int64, error) {
+				r, err := server.Purge(ctx, &pb.PurgeRequest{Force: true, AllFiles: true})
+				return r.GetPurged(), err
+			},
+			count: 1, visible: map[string]bool{"a.txt": true, "b.txt": true},
+		},
+		{
+			name: "restore purged version",
+			call: func() (int64, error) {
+				r, err := server.Restore(ctx, &pb.RestoreRequest{FileName: "a.txt", Version: 2})
+				return r.GetRestored(), err
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, step := range steps {
+		count, err := step.call()
+		if status.Code(err) != step.code {
+			t.Fatalf("%s: got %v, want %s", step.name, err, step.code)
+		}
+		if step.code != codes.OK {
+			continue
+		}
+		if count != step.count {
+			t.Fatalf("%s: changed %d versions, want %d", step.name, count, step.count)
+		}
+		for _, name := range []string{"a.txt", "b.txt"} {
+			_, err := download(server, &pb.DownloadRequest{FileName: name})
+			if visible := err == nil; visible != step.visible[name] {
+				t.Fatalf("%s: %s visible %v (%v), want %v", step.name, name, visible, err, step.visible[name])
+			}
+		}
+	}
+}
+
+func TestGetFileInfo(t *testing.T) {
+	server, st := newTestServer(t, Limits{}, Policy{})
+	first, second := []byte("first version"), []byte("second, longer version")
+	for _, data := range [][]byte{first, second} {
+		if _, err := upload(server, &pb.FileUploadInfo{FileName: "notes.txt"}, nil, data); err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+	}
+
+	// История переносов и варианты blob-а попадают только в ответ GetFileInfo
+	sum := checksumOf(second)
+	if _, err := st.SaveBlobVariant(catalog.Variant{Checksum: sum, Name: "thumb_64", Path: "variants/" + sum, MimeType: "image/png", Width: 64, Height: 32, Size: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.MoveBlobTier(sum, catalog.TierHot, catalog.TierCold, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		fileName string
+		version  int64
+		code     codes.Code
+		want     []byte
+		variants int
+		moves    int
+	}{
+		{name: "latest version", fileName: "notes.txt", want: second, variants: 1, moves: 1},
+		{name: "first version", fileName: "notes.txt", version: 1, want: first},
+		{name: "missing version", fileName: "notes.txt", version: 3, code: codes.NotFound},
+		{name: "missing file", fileName: "other.txt", code: codes.NotFound},
+		{name: "negative version", fileName: "notes.txt", version: -1, code: codes.InvalidArgument},
+		{name: "no file name", code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := server.GetFileInfo(context.Background(), &pb.GetFileInfoRequest{FileName: tt.fileName, Version: tt.version})
+			if status.Code(err) != tt.code {
+				t.Fatalf("get file info: got %v, want %s", err, tt.code)
+			}
+			if tt.code != codes.OK {
+				return
+			}
+
+			if info.GetFileName() != tt.fileName || info.GetSize() != int64(len(tt.want)) || info.GetChecksum() != checksumOf(tt.want) {
+				t.Fatalf("got %s v%d, %d bytes, sha256 %s", info.GetFileName(), info.GetVersion(), info.GetSize(), info.GetChecksum())
+			}
+			if info.GetDeletedAt() != "" || info.GetExif() != nil {
+				t.Fatalf("deleted at %q, EXIF %v", info.GetDeletedAt(), info.GetExif())
+			}
+			if len(info.GetVariants()) != tt.variants || len(info.GetMoves()) != tt.moves {
+				t.Fatalf("variants %v, moves %v", info.GetVariants(), info.GetMoves())
+			}
+			if tt.variants > 0 {
+				if variant := info.GetVariants()[0]; variant.GetName() != "thumb_64" || variant.GetWidth() != 64 || variant.GetHeight() != 32 {
+					t.Fatalf("variant %v", variant)
+				}
+				if move := info.GetMoves()[0]; move.GetFromTier() != catalog.TierHot || move.GetToTier() != catalog.TierCold || info.GetTier() != catalog.TierCold {
+					t.Fatalf("move %v, tier %s", move, info.GetTier())
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadCompressed(t *testing.T) {
+	text := bytes.Repeat([]byte("a line of text that compresses well\n"), 200)
+	// Цепочка sha256 не сжимается ни gzip, ни zstd
+	var noise []byte
+	sum := sha256.Sum256(nil)
+	for len(noise) < 4096 {
+		sum = sha256.Sum256(sum[:])
+		noise = append(noise, sum[:]...)
+	}
+	policy := compress.Policy{"text/*": compress.Gzip, "*": compress.Zstd}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		accept   []string
+		offset   int64
+		length   int64
+		// raw - ответ приходит сжатым, клиент распаковывает его сам
+		raw bool
+	}{
+		{name: "decompressed by the server", data: text, encoding: compress.Gzip},
+		{name: "sent compressed", data: text, encoding: compress.Gzip, accept: []string{compress.Zstd, compress.Gzip}, raw: true},
+		{name: "encoding not accepted", data: text, encoding: compress.Gzip, accept: []string{compress.Zstd}},
+		{name: "range of compressed file", data: text, encoding: compress.Gzip, accept: []string{compress.Gzip}, offset: 100, length: 50},
+		{name: "incompressible", data: noise, encoding: compress.None, accept: []string{compress.Zstd}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServerWith(t, Limits{}, Policy{}, policy, func(saveDir string) blob.Store { return blob.NewFileStore(saveDir) })
+			if _, err := upload(server, &pb.FileUploadInfo{FileName: "file"}, nil, tt.data); err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+
+			info, err := server.GetFileInfo(context.Background(), &pb.GetFileInfoRequest{FileName: "file"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.GetEncoding() != tt.encoding {
+				t.Fatalf("stored with %q, want %q", info.GetEncoding(), tt.encoding)
+			}
+			if compressed := info.GetStoredSize() < info.GetSize(); compressed != (tt.encoding != compress.None) {
+				t.Fatalf("stored %d bytes of %d", info.GetStoredSize(), info.GetSize())
+			}
+
+			stream, err := download(server, &pb.DownloadRequest{FileName: "file", Offset: tt.offset, Length: tt.length, AcceptEncoding: tt.accept})
+			if err != nil {
+				t.Fatalf("download: %v", err)
+			}
+
+			got := stream.content.Bytes()
+			encodings := stream.header.Get(ContentEncodingHeader)
+			if tt.raw {
+				if len(encodings) != 1 || encodings[0] != tt.encoding {
+					t.Fatalf("content encoding header %v, want %s", encodings, tt.encoding)
+				}
+				reader, err := compress.NewReader(tt.encoding, bytes.NewReader(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err = io.ReadAll(reader); err != nil {
+					t.Fatal(err)
+				}
+			} else if len(encodings) != 0 {
+				t.Fatalf("content encoding header %v for decompressed content", encodings)
+			}
+
+			want := tt.data[tt.offset:]
+			if tt.length > 0 {
+				want = want[:tt.length]
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got %d bytes, want %d", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Форматы изображений, которые сервер умеет читать и писать
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	GIF  = "gif"
+)
+
+// MaxPixels - изображения больше не декодируются: в памяти они занимают по 4 байта на пиксель
+const MaxPixels = 50_000_000
+
+var (
+	ErrUnsupported = errors.New("unsupported image format")
+	ErrTooLarge    = errors.New("image is too large")
+)
+
+// FormatOf - формат изображения с MIME-типом mimeType, пустой для остальных типов
+func FormatOf(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return JPEG
+	case "image/png":
+		return PNG
+	case "image/gif":
+		return GIF
+	}
+	return ""
+}
+
+// MimeType - MIME-тип формата
+func MimeType(format string) string {
+	return "image/" + format
+}
+
+// Decode читает JPEG, PNG или GIF (у анимированного - первый кадр) и возвращает изображение и его формат.
+// Размер проверяется по заголовку до декодирования
+func Decode(data []byte) (image.Image, string, error) {
+	return DecodeReader(bytes.NewReader(data))
+}
+
+// DecodeReader - Decode из потока. Формат и размер проверяются по заголовку, поэтому r, который
+// не изображение или слишком велик, дальше заголовка не читается
+func DecodeReader(r io.Reader) (image.Image, string, error) {
+	// Прочитанное DecodeConfig копится в header и отдается декодеру перед остатком r
+	var header bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, "", ErrUnsupported
+		}
+		return nil, "", err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Encode пишет img в формате format. quality используется только для JPEG
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case PNG:
+		return png.Encode(w, img)
+	case GIF:
+		return gif.Encode(w, img, &gif.Options{NumColors: 256})
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupported, format)
+}
+
+// Fit уменьшает img так, чтобы обе стороны были не больше size, сохраняя пропорции.
+// Изображение, которое уже помещается, возвращается как есть
+func Fit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+	return Resize(img, width, height)
+}
+
+// Resize масштабирует img до width x height. Каждый пиксель результата - среднее пикселей
+// исходника, которые он накрывает, поэтому при уменьшении не появляется лесенки
+func Resize(img image.Image, width int, height int) *image.RGBA {
+	// Усредняется premultiplied RGBA, иначе у полупрозрачных краев появляется ореол
+	src := toRGBA(img)
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, srcHeight)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, srcWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// span - строки или столбцы исходника размера srcSize, которые накрывает i-й из size пикселей результата
+func span(i int, size int, srcSize int) (int, int) {
+	from := i * srcSize / size
+	to := (i + 1) * srcSize / size
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// DetectFormat читает из r заголовок изображения и возвращает его формат
+func DetectFormat(r io.Reader) (string, error) {
+	_, format, err := image.DecodeConfig(r)
+	if errors.Is(err, image.ErrFormat) {
+		return "", ErrUnsupported
+	}
+	return format, err
+}
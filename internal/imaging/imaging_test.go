@@ -0,0 +1,38 @@
+package imaging
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{name: "landscape", width: 400, height: 200, size: 100, wantW: 100, wantH: 50},
+		{name: "portrait", width: 200, height: 400, size: 100, wantW: 50, wantH: 100},
+		{name: "square", width: 300, height: 300, size: 64, wantW: 64, wantH: 64},
+		{name: "rounded down", width: 300, height: 200, size: 64, wantW: 64, wantH: 42},
+		{name: "thin strip keeps one pixel", width: 1000, height: 2, size: 100, wantW: 100, wantH: 1},
+		{name: "one side too large", width: 50, height: 150, size: 100, wantW: 33, wantH: 100},
+		{name: "already fits", width: 80, height: 60, size: 100, wantW: 80, wantH: 60},
+		{name: "exactly fits", width: 100, height: 40, size: 100, wantW: 100, wantH: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := Fit(img, tt.size)
+			if got.Bounds().Dx() != tt.wantW || got.Bounds().Dy() != tt.wantH {
+				t.Fatalf("got %v, want %dx%d", got.Bounds(), tt.wantW, tt.wantH)
+			}
+			// Изображение, которое помещается, не копируется
+			if tt.width <= tt.size && tt.height <= tt.size && got != image.Image(img) {
+				t.Fatalf("image that fits was resized")
+			}
+		})
+	}
+}
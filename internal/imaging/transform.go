@@ -0,0 +1,228 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Виды операций Transform
+const (
+	OpResize = "resize"
+	OpCrop   = "crop"
+	OpRotate = "rotate"
+	OpFlip   = "flip"
+	OpFormat = "format"
+)
+
+// DefaultQuality - качество JPEG, если в операции format оно не задано
+const DefaultQuality = 85
+
+var ErrInvalidOperation = errors.New("invalid image operation")
+
+// Operation - шаг преобразования изображения. Какие поля значимы, зависит от Kind:
+//   - resize: Width x Height, одна из сторон 0 - считается по пропорциям
+//   - crop: прямоугольник Width x Height с левым верхним углом X, Y
+//   - rotate: Degrees по часовой стрелке, кратно 90
+//   - flip: Horizontal - зеркально слева направо, иначе сверху вниз
+//   - format: Format (jpeg, png, gif) и Quality (только для JPEG, 0 - DefaultQuality)
+type Operation struct {
+	Kind       string
+	Width      int
+	Height     int
+	X          int
+	Y          int
+	Degrees    int
+	Horizontal bool
+	Format     string
+	Quality    int
+}
+
+// String - каноническая запись операции: у одинаковых по смыслу операций она совпадает
+func (o Operation) String() string {
+	switch o.Kind {
+	case OpResize:
+		return fmt.Sprintf("resize:%dx%d", o.Width, o.Height)
+	case OpCrop:
+		return fmt.Sprintf("crop:%d,%d,%dx%d", o.X, o.Y, o.Width, o.Height)
+	case OpRotate:
+		return fmt.Sprintf("rotate:%d", normalizeDegrees(o.Degrees))
+	case OpFlip:
+		if o.Horizontal {
+			return "flip:h"
+		}
+		return "flip:v"
+	case OpFormat:
+		if o.Format == JPEG {
+			return fmt.Sprintf("format:%s:%d", o.Format, o.quality())
+		}
+		return "format:" + o.Format
+	}
+	return o.Kind
+}
+
+// Validate проверяет параметры операции, не глядя на изображение
+func (o Operation) Validate() error {
+	switch o.Kind {
+	case OpResize:
+		if o.Width < 0 || o.Height < 0 || o.Width == 0 && o.Height == 0 {
+			return fmt.Errorf("%w: resize to %dx%d", ErrInvalidOperation, o.Width, o.Height)
+		}
+		if int64(o.Width)*int64(o.Height) > MaxPixels {
+			return fmt.Errorf("%w: resize to %dx%d", ErrTooLarge, o.Width, o.Height)
+		}
+	case OpCrop:
+		if o.X < 0 || o.Y < 0 || o.Width <= 0 || o.Height <= 0 {
+			return fmt.Errorf("%w: crop %d,%d %dx%d", ErrInvalidOperation, o.X, o.Y, o.Width, o.Height)
+		}
+	case OpRotate:
+		if o.Degrees%90 != 0 {
+			return fmt.Errorf("%w: rotate by %d degrees, only multiples of 90 are supported", ErrInvalidOperation, o.Degrees)
+		}
+	case OpFlip:
+	case OpFormat:
+		if o.Format != JPEG && o.Format != PNG && o.Format != GIF {
+			return fmt.Errorf("%w: format %q", ErrInvalidOperation, o.Format)
+		}
+		if o.Quality < 0 || o.Quality > 100 {
+			return fmt.Errorf("%w: quality %d", ErrInvalidOperation, o.Quality)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidOperation, o.Kind)
+	}
+	return nil
+}
+
+func (o Operation) quality() int {
+	if o.Quality == 0 {
+		return DefaultQuality
+	}
+	return o.Quality
+}
+
+// Describe - каноническая запись списка операций
+func Describe(ops []Operation) string {
+	parts := make([]string, len(ops))
+	for i, op := range ops {
+		parts[i] = op.String()
+	}
+	return strings.Join(parts, ";")
+}
+
+// Output - формат и качество результата: их задает последняя операция format, без нее - формат исходника
+func Output(ops []Operation, sourceFormat string) (string, int) {
+	format, quality := sourceFormat, DefaultQuality
+	for _, op := range ops {
+		if op.Kind == OpFormat {
+			format, quality = op.Format, op.quality()
+		}
+	}
+	return format, quality
+}
+
+// Apply выполняет над img геометрические операции по порядку, операции format пропускаются
+func Apply(img image.Image, ops []Operation) (image.Image, error) {
+	for _, op := range ops {
+		if err := op.Validate(); err != nil {
+			return nil, err
+		}
+
+		bounds := img.Bounds()
+		switch op.Kind {
+		case OpResize:
+			width, height := op.Width, op.Height
+			if width == 0 {
+				width = max(1, bounds.Dx()*height/bounds.Dy())
+			}
+			if height == 0 {
+				height = max(1, bounds.Dy()*width/bounds.Dx())
+			}
+			if int64(width)*int64(height) > MaxPixels {
+				return nil, fmt.Errorf("%w: resize to %dx%d", ErrTooLarge, width, height)
+			}
+			img = Resize(img, width, height)
+		case OpCrop:
+			rect := image.Rect(op.X, op.Y, op.X+op.Width, op.Y+op.Height).Add(bounds.Min)
+			if !rect.In(bounds) {
+				return nil, fmt.Errorf("%w: crop %d,%d %dx%d is outside of %dx%d image",
+					ErrInvalidOperation, op.X, op.Y, op.Width, op.Height, bounds.Dx(), bounds.Dy())
+			}
+			img = Crop(img, rect)
+		case OpRotate:
+			img = Rotate(img, op.Degrees)
+		case OpFlip:
+			img = Flip(img, op.Horizontal)
+		}
+	}
+	return img, nil
+}
+
+// Crop вырезает из img прямоугольник rect (в координатах img)
+func Crop(img image.Image, rect image.Rectangle) *image.RGBA {
+	src := toRGBA(img)
+	rect = rect.Sub(img.Bounds().Min)
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	for y := 0; y < rect.Dy(); y++ {
+		from := src.PixOffset(rect.Min.X, rect.Min.Y+y)
+		copy(dst.Pix[y*dst.Stride:(y+1)*dst.Stride], src.Pix[from:from+rect.Dx()*4])
+	}
+	return dst
+}
+
+// Rotate поворачивает img на degrees по часовой стрелке, degrees кратно 90
+func Rotate(img image.Image, degrees int) *image.RGBA {
+	src := toRGBA(img)
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+
+	degrees = normalizeDegrees(degrees)
+	if degrees == 0 {
+		return src
+	}
+
+	dstWidth, dstHeight := width, height
+	if degrees != 180 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch degrees {
+			case 90:
+				dx, dy = height-1-y, x
+			case 180:
+				dx, dy = width-1-x, height-1-y
+			case 270:
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// Flip отражает img слева направо (horizontal) или сверху вниз
+func Flip(img image.Image, horizontal bool) *image.RGBA {
+	src := toRGBA(img)
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		if !horizontal {
+			copy(dst.Pix[y*dst.Stride:(y+1)*dst.Stride], src.Pix[(height-1-y)*src.Stride:(height-y)*src.Stride])
+			continue
+		}
+		for x := 0; x < width; x++ {
+			from := src.PixOffset(width-1-x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[from:from+4])
+		}
+	}
+	return dst
+}
+
+// normalizeDegrees приводит угол к 0, 90, 180 или 270
+func normalizeDegrees(degrees int) int {
+	return (degrees%360 + 360) % 360
+}
@@ -0,0 +1,242 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// gradient - w x h изображение, у которого в пикселе (x, y) записаны его координаты
+func gradient(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	return img
+}
+
+// sourceOf - координаты пикселя исходника, оказавшегося в (x, y) результата
+func sourceOf(img image.Image, x, y int) (int, int) {
+	c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	return int(c.R), int(c.G)
+}
+
+func TestApplyPixelMapping(t *testing.T) {
+	const w, h = 3, 2
+
+	tests := []struct {
+		name    string
+		ops     []Operation
+		width   int
+		height  int
+		src     func(x, y int) (int, int)
+		wantErr error
+	}{
+		{name: "rotate 0", ops: []Operation{{Kind: OpRotate}}, width: w, height: h, src: func(x, y int) (int, int) { return x, y }},
+		{name: "rotate 90", ops: []Operation{{Kind: OpRotate, Degrees: 90}}, width: h, height: w, src: func(x, y int) (int, int) { return y, h - 1 - x }},
+		{name: "rotate 180", ops: []Operation{{Kind: OpRotate, Degrees: 180}}, width: w, height: h, src: func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }},
+		{name: "rotate 270", ops: []Operation{{Kind: OpRotate, Degrees: 270}}, width: h, height: w, src: func(x, y int) (int, int) { return w - 1 - y, x }},
+		{name: "rotate -90", ops: []Operation{{Kind: OpRotate, Degrees: -90}}, width: h, height: w, src: func(x, y int) (int, int) { return w - 1 - y, x }},
+		{name: "rotate 450", ops: []Operation{{Kind: OpRotate, Degrees: 450}}, width: h, height: w, src: func(x, y int) (int, int) { return y, h - 1 - x }},
+		{name: "flip horizontal", ops: []Operation{{Kind: OpFlip, Horizontal: true}}, width: w, height: h, src: func(x, y int) (int, int) { return w - 1 - x, y }},
+		{name: "flip vertical", ops: []Operation{{Kind: OpFlip}}, width: w, height: h, src: func(x, y int) (int, int) { return x, h - 1 - y }},
+		{name: "crop", ops: []Operation{{Kind: OpCrop, X: 1, Y: 1, Width: 2, Height: 1}}, width: 2, height: 1, src: func(x, y int) (int, int) { return x + 1, y + 1 }},
+		{name: "crop whole image", ops: []Operation{{Kind: OpCrop, Width: w, Height: h}}, width: w, height: h, src: func(x, y int) (int, int) { return x, y }},
+		{name: "crop past the right edge", ops: []Operation{{Kind: OpCrop, X: 2, Width: 2, Height: 1}}, wantErr: ErrInvalidOperation},
+		{name: "crop past the bottom edge", ops: []Operation{{Kind: OpCrop, Y: 1, Width: 1, Height: 2}}, wantErr: ErrInvalidOperation},
+		{name: "crop then rotate", ops: []Operation{{Kind: OpCrop, X: 1, Width: 2, Height: 2}, {Kind: OpRotate, Degrees: 90}}, width: 2, height: 2, src: func(x, y int) (int, int) { return 1 + y, 1 - x }},
+		{name: "format is skipped", ops: []Operation{{Kind: OpFormat, Format: PNG}}, width: w, height: h, src: func(x, y int) (int, int) { return x, y }},
+		{name: "invalid operation", ops: []Operation{{Kind: OpRotate, Degrees: 45}}, wantErr: ErrInvalidOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(gradient(w, h), tt.ops)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.Bounds().Dx() != tt.width || got.Bounds().Dy() != tt.height {
+				t.Fatalf("got %v, want %dx%d", got.Bounds(), tt.width, tt.height)
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					wantX, wantY := tt.src(x, y)
+					if gotX, gotY := sourceOf(got, x, y); gotX != wantX || gotY != wantY {
+						t.Fatalf("pixel %d,%d comes from %d,%d, want %d,%d", x, y, gotX, gotY, wantX, wantY)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Crop считает координаты от угла изображения, даже если его Bounds начинаются не с 0,0
+func TestCropSubImage(t *testing.T) {
+	sub := gradient(4, 4).SubImage(image.Rect(1, 1, 4, 4))
+
+	got, err := Apply(sub, []Operation{{Kind: OpCrop, X: 1, Y: 0, Width: 2, Height: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y := sourceOf(got, 0, 0); x != 2 || y != 1 {
+		t.Fatalf("corner comes from %d,%d, want 2,1", x, y)
+	}
+
+	if _, err := Apply(sub, []Operation{{Kind: OpCrop, X: 2, Y: 2, Width: 2, Height: 1}}); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("crop outside of the sub-image: %v", err)
+	}
+}
+
+func TestApplyResize(t *testing.T) {
+	tests := []struct {
+		name          string
+		op            Operation
+		width, height int
+	}{
+		{name: "both sides", op: Operation{Kind: OpResize, Width: 5, Height: 7}, width: 5, height: 7},
+		{name: "width only", op: Operation{Kind: OpResize, Width: 50}, width: 50, height: 25},
+		{name: "height only", op: Operation{Kind: OpResize, Height: 10}, width: 20, height: 10},
+		{name: "rounded down to one pixel", op: Operation{Kind: OpResize, Width: 1}, width: 1, height: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(gradient(100, 50), []Operation{tt.op})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Bounds().Dx() != tt.width || got.Bounds().Dy() != tt.height {
+				t.Fatalf("got %v, want %dx%d", got.Bounds(), tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		op   Operation
+		want error
+	}{
+		{name: "resize", op: Operation{Kind: OpResize, Width: 10}},
+		{name: "resize to nothing", op: Operation{Kind: OpResize}, want: ErrInvalidOperation},
+		{name: "resize negative", op: Operation{Kind: OpResize, Width: -1, Height: 10}, want: ErrInvalidOperation},
+		{name: "resize too large", op: Operation{Kind: OpResize, Width: 100_000, Height: 100_000}, want: ErrTooLarge},
+		{name: "crop", op: Operation{Kind: OpCrop, Width: 1, Height: 1}},
+		{name: "crop empty", op: Operation{Kind: OpCrop, Width: 0, Height: 1}, want: ErrInvalidOperation},
+		{name: "crop negative corner", op: Operation{Kind: OpCrop, X: -1, Width: 1, Height: 1}, want: ErrInvalidOperation},
+		{name: "rotate", op: Operation{Kind: OpRotate, Degrees: -270}},
+		{name: "rotate not by right angle", op: Operation{Kind: OpRotate, Degrees: 30}, want: ErrInvalidOperation},
+		{name: "flip", op: Operation{Kind: OpFlip}},
+		{name: "format", op: Operation{Kind: OpFormat, Format: JPEG, Quality: 100}},
+		{name: "unknown format", op: Operation{Kind: OpFormat, Format: "webp"}, want: ErrInvalidOperation},
+		{name: "quality out of range", op: Operation{Kind: OpFormat, Format: JPEG, Quality: 101}, want: ErrInvalidOperation},
+		{name: "unknown operation", op: Operation{Kind: "blur"}, want: ErrInvalidOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op.Validate(); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Одинаковые по смыслу списки операций дают один ключ кэша, разные - разные
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []Operation
+		same bool
+	}{
+		{name: "rotate by full turns", a: []Operation{{Kind: OpRotate, Degrees: -90}}, b: []Operation{{Kind: OpRotate, Degrees: 270}}, same: true},
+		{name: "rotate by 360", a: []Operation{{Kind: OpRotate, Degrees: 360}}, b: []Operation{{Kind: OpRotate}}, same: true},
+		{name: "default jpeg quality", a: []Operation{{Kind: OpFormat, Format: JPEG}}, b: []Operation{{Kind: OpFormat, Format: JPEG, Quality: DefaultQuality}}, same: true},
+		{name: "png ignores quality", a: []Operation{{Kind: OpFormat, Format: PNG, Quality: 10}}, b: []Operation{{Kind: OpFormat, Format: PNG}}, same: true},
+		{name: "fields of other operations are ignored", a: []Operation{{Kind: OpFlip, Width: 5}}, b: []Operation{{Kind: OpFlip}}, same: true},
+		{name: "jpeg quality", a: []Operation{{Kind: OpFormat, Format: JPEG, Quality: 50}}, b: []Operation{{Kind: OpFormat, Format: JPEG}}},
+		{name: "flip direction", a: []Operation{{Kind: OpFlip, Horizontal: true}}, b: []Operation{{Kind: OpFlip}}},
+		{name: "order", a: []Operation{{Kind: OpFlip}, {Kind: OpRotate, Degrees: 90}}, b: []Operation{{Kind: OpRotate, Degrees: 90}, {Kind: OpFlip}}},
+		{name: "crop corner", a: []Operation{{Kind: OpCrop, X: 1, Width: 2, Height: 2}}, b: []Operation{{Kind: OpCrop, Y: 1, Width: 2, Height: 2}}},
+		{name: "resize sides", a: []Operation{{Kind: OpResize, Width: 10}}, b: []Operation{{Kind: OpResize, Height: 10}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Describe(tt.a), Describe(tt.b)
+			if (a == b) != tt.same {
+				t.Fatalf("%q and %q: same %v, want %v", a, b, a == b, tt.same)
+			}
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []Operation
+		format  string
+		quality int
+	}{
+		{name: "source format", ops: []Operation{{Kind: OpFlip}}, format: PNG, quality: DefaultQuality},
+		{name: "converted", ops: []Operation{{Kind: OpFormat, Format: JPEG, Quality: 40}, {Kind: OpFlip}}, format: JPEG, quality: 40},
+		{name: "last format wins", ops: []Operation{{Kind: OpFormat, Format: JPEG, Quality: 40}, {Kind: OpFormat, Format: GIF}}, format: GIF, quality: DefaultQuality},
+		{name: "no operations", format: PNG, quality: DefaultQuality},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, quality := Output(tt.ops, PNG)
+			if format != tt.format || quality != tt.quality {
+				t.Fatalf("got %s %d, want %s %d", format, quality, tt.format, tt.quality)
+			}
+		})
+	}
+}
+
+func TestDecodeReader(t *testing.T) {
+	var small bytes.Buffer
+	if err := gif.Encode(&small, gradient(2, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Заголовок GIF обещает 65535x65535: отклоняется до декодирования
+	huge := bytes.Clone(small.Bytes())
+	copy(huge[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		want   error
+	}{
+		{name: "gif", data: small.Bytes(), format: GIF},
+		{name: "not an image", data: bytes.Repeat([]byte("text "), 1000), want: ErrUnsupported},
+		{name: "empty", data: nil, want: ErrUnsupported},
+		{name: "too large", data: huge, want: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := DecodeReader(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			if format != tt.format || img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+				t.Fatalf("got %s %v", format, img.Bounds())
+			}
+		})
+	}
+}
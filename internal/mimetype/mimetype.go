@@ -0,0 +1,161 @@
+package mimetype
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Что делать с загрузкой, у которой содержимое не совпадает с расширением
+const (
+	// MismatchFlag - сохранить с MIME-типом по содержимому и запомнить тип по расширению
+	MismatchFlag = "flag"
+	// MismatchReject - отклонить загрузку
+	MismatchReject = "reject"
+)
+
+// SniffLen - сколько первых байт содержимого нужно Detect
+const SniffLen = 512
+
+// Unknown - тип, если ни расширение, ни содержимое его не говорят
+const Unknown = "application/octet-stream"
+
+var ErrPolicy = errors.New("invalid content type mismatch policy")
+
+// ParsePolicy проверяет политику расхождения, пустая строка - MismatchFlag
+func ParsePolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return MismatchFlag, nil
+	case MismatchFlag, MismatchReject:
+		return policy, nil
+	}
+	return "", fmt.Errorf("%w: %q, expected %s or %s", ErrPolicy, policy, MismatchFlag, MismatchReject)
+}
+
+// ByExtension - MIME-тип по расширению имени файла без параметров, пусто - расширение неизвестно
+func ByExtension(fileName string) string {
+	return base(mime.TypeByExtension(filepath.Ext(fileName)))
+}
+
+// Detect - MIME-тип по первым байтам содержимого без параметров (charset и т.п.).
+// Если сигнатура не найдена или содержимое пустое, возвращает Unknown
+func Detect(head []byte) string {
+	if len(head) == 0 {
+		return Unknown
+	}
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	return base(http.DetectContentType(head))
+}
+
+// Resolve выбирает, какой тип хранить в каталоге, по типу из расширения declared и по содержимому sniffed.
+// Если содержимое распознано лишь в общих чертах (octet-stream, text/plain, zip), а расширение ему
+// не противоречит, остается более точный declared. Иначе в каталог идет sniffed, а false означает,
+// что расширение противоречит содержимому. Расширение без типа или с типом Unknown ничему не противоречит
+func Resolve(declared string, sniffed string) (string, bool) {
+	if declared == "" || declared == Unknown {
+		if sniffed == "" {
+			return Unknown, true
+		}
+		return sniffed, true
+	}
+	if sniffed == "" || sniffed == Unknown || canonical(declared) == canonical(sniffed) {
+		return declared, true
+	}
+
+	switch sniffed {
+	case "text/plain":
+		if textual(declared) {
+			return declared, true
+		}
+	case "text/xml", "text/html":
+		// Сниффер считает HTML-ом и XML с комментарием в начале
+		if xml(declared) {
+			return declared, true
+		}
+	case "application/zip":
+		if zipContainer(declared) {
+			return declared, true
+		}
+	case "application/ogg":
+		if declared == "audio/ogg" || declared == "video/ogg" {
+			return declared, true
+		}
+	case "video/mp4":
+		if declared == "audio/mp4" || declared == "audio/x-m4a" {
+			return declared, true
+		}
+	}
+	return sniffed, false
+}
+
+// aliases - разные записи одного типа
+var aliases = map[string]string{
+	"application/x-gzip":           "application/gzip",
+	"application/javascript":       "text/javascript",
+	"application/x-javascript":     "text/javascript",
+	"application/xml":              "text/xml",
+	"audio/x-wav":                  "audio/wav",
+	"audio/wave":                   "audio/wav",
+	"audio/mp3":                    "audio/mpeg",
+	"image/x-icon":                 "image/vnd.microsoft.icon",
+	"image/x-ms-bmp":               "image/bmp",
+	"application/x-zip-compressed": "application/zip",
+}
+
+func canonical(mimeType string) string {
+	if alias, ok := aliases[mimeType]; ok {
+		return alias
+	}
+	return mimeType
+}
+
+func textual(mimeType string) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		strings.HasSuffix(mimeType, "+json"),
+		mimeType == "application/json",
+		mimeType == "application/javascript",
+		mimeType == "application/x-javascript",
+		mimeType == "application/x-sh",
+		mimeType == "application/toml",
+		mimeType == "application/yaml":
+		return true
+	}
+	return xml(mimeType)
+}
+
+func xml(mimeType string) bool {
+	return mimeType == "text/xml" || mimeType == "application/xml" || strings.HasSuffix(mimeType, "+xml")
+}
+
+// zipContainer - форматы, которые внутри являются zip-архивом
+func zipContainer(mimeType string) bool {
+	switch {
+	case strings.HasSuffix(mimeType, "+zip"),
+		strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument."),
+		strings.HasPrefix(mimeType, "application/vnd.oasis.opendocument."),
+		mimeType == "application/java-archive",
+		mimeType == "application/x-java-archive",
+		mimeType == "application/vnd.android.package-archive",
+		mimeType == "application/x-zip-compressed":
+		return true
+	}
+	return false
+}
+
+func base(mimeType string) string {
+	if mimeType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	}
+	return mediaType
+}
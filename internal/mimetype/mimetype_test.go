@@ -0,0 +1,129 @@
+package mimetype
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{name: "empty", head: nil, want: Unknown},
+		{name: "png", head: png, want: "image/png"},
+		{name: "jpeg", head: []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), want: "image/jpeg"},
+		{name: "gif", head: []byte("GIF89a\x01\x00\x01\x00"), want: "image/gif"},
+		{name: "webp", head: []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), want: "image/webp"},
+		{name: "pdf", head: []byte("%PDF-1.7\n"), want: "application/pdf"},
+		{name: "zip", head: []byte("PK\x03\x04\x14\x00\x00\x00"), want: "application/zip"},
+		// charset отбрасывается
+		{name: "text", head: []byte("just some text\n"), want: "text/plain"},
+		{name: "html", head: []byte("<!DOCTYPE html><html></html>"), want: "text/html"},
+		{name: "binary", head: []byte{0x00, 0x01, 0x02, 0x03, 0xfe}, want: Unknown},
+		// Сигнатура ищется только в первых SniffLen байтах
+		{name: "signature past sniff length", head: append(bytes.Repeat([]byte{0}, SniffLen), png...), want: Unknown},
+		{name: "long png", head: append(png, bytes.Repeat([]byte{0}, 2*SniffLen)...), want: "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.head); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{fileName: "photo.png", want: "image/png"},
+		{fileName: "photo.JPG", want: "image/jpeg"},
+		{fileName: "dir.v2/icon.svg", want: "image/svg+xml"},
+		// charset отбрасывается
+		{fileName: "page.html", want: "text/html"},
+		{fileName: "no_extension", want: ""},
+		{fileName: "file.unknownext", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if got := ByExtension(tt.fileName); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		declared  string
+		sniffed   string
+		want      string
+		wantMatch bool
+	}{
+		{name: "same", declared: "image/png", sniffed: "image/png", want: "image/png", wantMatch: true},
+		{name: "mismatch", declared: "image/png", sniffed: "image/jpeg", want: "image/jpeg"},
+		{name: "executable as image", declared: "image/jpeg", sniffed: "application/x-msdownload", want: "application/x-msdownload"},
+		{name: "no extension", declared: "", sniffed: "image/gif", want: "image/gif", wantMatch: true},
+		{name: "nothing known", declared: "", sniffed: "", want: Unknown, wantMatch: true},
+		{name: "unknown extension type", declared: Unknown, sniffed: "image/png", want: "image/png", wantMatch: true},
+		{name: "content not recognized", declared: "image/heic", sniffed: Unknown, want: "image/heic", wantMatch: true},
+		{name: "nothing sniffed", declared: "image/heic", sniffed: "", want: "image/heic", wantMatch: true},
+		{name: "alias", declared: "application/x-gzip", sniffed: "application/gzip", want: "application/x-gzip", wantMatch: true},
+		{name: "bmp alias", declared: "image/x-ms-bmp", sniffed: "image/bmp", want: "image/x-ms-bmp", wantMatch: true},
+		{name: "json is text", declared: "application/json", sniffed: "text/plain", want: "application/json", wantMatch: true},
+		{name: "csv is text", declared: "text/csv", sniffed: "text/plain", want: "text/csv", wantMatch: true},
+		{name: "svg sniffed as xml", declared: "image/svg+xml", sniffed: "text/xml", want: "image/svg+xml", wantMatch: true},
+		{name: "xml with comment sniffed as html", declared: "application/xml", sniffed: "text/html", want: "application/xml", wantMatch: true},
+		{name: "html declared as text", declared: "text/plain", sniffed: "text/html", want: "text/html"},
+		{name: "image is not text", declared: "image/png", sniffed: "text/plain", want: "text/plain"},
+		{name: "docx", declared: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", sniffed: "application/zip",
+			want: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", wantMatch: true},
+		{name: "epub", declared: "application/epub+zip", sniffed: "application/zip", want: "application/epub+zip", wantMatch: true},
+		{name: "zip as image", declared: "image/png", sniffed: "application/zip", want: "application/zip"},
+		{name: "ogg audio", declared: "audio/ogg", sniffed: "application/ogg", want: "audio/ogg", wantMatch: true},
+		{name: "m4a", declared: "audio/mp4", sniffed: "video/mp4", want: "audio/mp4", wantMatch: true},
+		{name: "mp4 as mov", declared: "video/quicktime", sniffed: "video/mp4", want: "video/mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, match := Resolve(tt.declared, tt.sniffed)
+			if got != tt.want || match != tt.wantMatch {
+				t.Fatalf("got %q, %v; want %q, %v", got, match, tt.want, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    string
+		wantErr error
+	}{
+		{policy: "", want: MismatchFlag},
+		{policy: "flag", want: MismatchFlag},
+		{policy: "reject", want: MismatchReject},
+		{policy: "Reject", wantErr: ErrPolicy},
+		{policy: "ignore", wantErr: ErrPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := ParsePolicy(tt.policy)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
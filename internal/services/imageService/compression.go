@@ -0,0 +1,86 @@
+package imageService
+
+import (
+	"io"
+	"os"
+
+	"imagestorage/internal/compress"
+)
+
+// compressStaged сжимает staging-файл в соседний файл и возвращает его путь и размер
+func compressStaged(filePath string, encoding string) (string, int64, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+
+	compressedPath := filePath + compress.Extension(encoding)
+	dst, err := os.Create(compressedPath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = func() error {
+		writer, err := compress.NewWriter(encoding, dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(writer, src); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		return dst.Sync()
+	}()
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(compressedPath)
+		return "", 0, err
+	}
+
+	info, err := os.Stat(compressedPath)
+	if err != nil {
+		os.Remove(compressedPath)
+		return "", 0, err
+	}
+
+	return compressedPath, info.Size(), nil
+}
+
+// decode распаковывает src и отдает length байт содержимого начиная с offset.
+// Сжатый поток нельзя читать с середины, поэтому первые offset байт пропускаются
+func decode(src io.ReadCloser, encoding string, offset int64, length int64) (io.ReadCloser, error) {
+	decoder, err := compress.NewReader(encoding, src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	reader := &decodedReader{Reader: decoder, decoder: decoder, stored: src}
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, decoder, offset); err != nil {
+			reader.Close()
+			return nil, err
+		}
+	}
+	if length > 0 {
+		reader.Reader = io.LimitReader(decoder, length)
+	}
+
+	return reader, nil
+}
+
+type decodedReader struct {
+	io.Reader
+	decoder io.Closer
+	stored  io.Closer
+}
+
+func (r *decodedReader) Close() error {
+	r.decoder.Close()
+	return r.stored.Close()
+}
@@ -0,0 +1,125 @@
+package imageService
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/storage/catalog"
+)
+
+var errNoKeys = errors.New("file is encrypted, but no master key is configured")
+
+// encryptStaged шифрует staging-файл новым ключом данных в соседний файл.
+// Закрытый текущим мастер-ключом ключ данных и размер шифротекста записываются в stored
+func (s *ImageService) encryptStaged(filePath string, stored *catalog.StoredBlob) (string, error) {
+	master := s.keys.Current()
+
+	dataKey, err := encryption.NewDataKey()
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := master.Wrap(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	encryptedPath := filePath + ".enc"
+	dst, err := os.Create(encryptedPath)
+	if err != nil {
+		return "", err
+	}
+
+	err = func() error {
+		writer, err := encryption.NewWriter(dataKey, dst)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(writer, src); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		return dst.Sync()
+	}()
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(encryptedPath)
+		return "", err
+	}
+
+	stored.StoredSize = encryption.EncryptedSize(stored.StoredSize)
+	stored.KeyID = master.ID()
+	stored.WrappedKey = wrapped
+
+	return encryptedPath, nil
+}
+
+// readDecrypted читает из хранилища только кадры, покрывающие диапазон, и расшифровывает их
+func (s *ImageService) readDecrypted(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	if s.keys == nil {
+		return nil, errNoKeys
+	}
+
+	dataKey, err := s.keys.Unwrap(stored.KeyID, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decryptRange(ctx, key, stored, dataKey, offset, length)
+}
+
+// decryptRange читает диапазон содержимого, зашифрованного ключом dataKey
+func (s *ImageService) decryptRange(ctx context.Context, key string, stored catalog.StoredBlob, dataKey []byte, offset int64, length int64) (io.ReadCloser, error) {
+	info, err := s.statBlob(ctx, key, stored.Tier)
+	if err != nil {
+		return nil, err
+	}
+
+	plainSize := encryption.PlainSize(info.Size)
+	if offset >= plainSize && plainSize > 0 {
+		return io.NopCloser(io.LimitReader(nil, 0)), nil
+	}
+
+	firstFrame, storedOffset, storedLength, skip := encryption.FrameRange(offset, length, plainSize)
+	src, err := s.openBlob(ctx, key, stored, storedOffset, storedLength)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := encryption.NewReader(dataKey, src, firstFrame, info.Size)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	if skip > 0 {
+		if _, err := io.CopyN(io.Discard, decrypted, skip); err != nil {
+			src.Close()
+			return nil, err
+		}
+	}
+
+	var reader io.Reader = decrypted
+	if length > 0 {
+		reader = io.LimitReader(decrypted, length)
+	}
+
+	return limitedReadCloser{Reader: reader, Closer: src}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
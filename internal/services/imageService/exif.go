@@ -0,0 +1,90 @@
+package imageService
+
+import (
+	"fmt"
+	"os"
+
+	"imagestorage/internal/exif"
+	"imagestorage/internal/storage/catalog"
+)
+
+// ReadExif читает EXIF из staging-файла JPEG. Возвращает false, если EXIF нет
+func (s *ImageService) ReadExif(stageName string) (catalog.Exif, bool, error) {
+	op := "internal.service.ImageService.ReadExif"
+
+	file, err := os.Open(s.stagePath(stageName))
+	if err != nil {
+		return catalog.Exif{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	metadata, found, err := exif.Read(file)
+	if err != nil {
+		return catalog.Exif{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !found {
+		return catalog.Exif{}, false, nil
+	}
+
+	return catalog.Exif{
+		CameraMake:  metadata.CameraMake,
+		CameraModel: metadata.CameraModel,
+		LensMake:    metadata.LensMake,
+		LensModel:   metadata.LensModel,
+		CapturedAt:  metadata.CapturedAt,
+		Orientation: metadata.Orientation,
+		HasGPS:      metadata.HasGPS,
+		Latitude:    metadata.Latitude,
+		Longitude:   metadata.Longitude,
+		Altitude:    metadata.Altitude,
+	}, true, nil
+}
+
+// StripExif вырезает из staging-файла JPEG метаданные по режиму mode (exif.StripGPS, exif.StripAll).
+// Если вырезать нечего, файл не меняется и возвращается false, иначе - sha256 и размер того, что осталось
+func (s *ImageService) StripExif(stageName string, mode string) (bool, string, int64, error) {
+	op := "internal.service.ImageService.StripExif"
+
+	unlock := s.lockFile(stageName)
+	defer unlock()
+
+	filePath := s.stagePath(stageName)
+	src, err := os.Open(filePath)
+	if err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(s.stagePath(""), stageName+".exif-*")
+	if err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	stripped, err := exif.Strip(src, tmp, mode)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if !stripped {
+		return false, "", 0, nil
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	checksum, err := fileChecksum(filePath)
+	if err != nil {
+		return false, "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Infof("Stripped EXIF (%s) from %s, %d bytes left", mode, stageName, info.Size())
+	return true, checksum, info.Size(), nil
+}
@@ -2,44 +2,123 @@ package imageService
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"imagestorage/internal/compress"
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// В blobDir лежит содержимое файлов, адресуемое по sha256
+	blobDir = "blobs"
+	// В stagingDir копятся незавершенные загрузки, по файлу на сессию
+	stagingDir = ".staging"
+	// В variantDir лежат миниатюры и другие варианты blob-ов
+	variantDir = "variants"
+)
+
+// ImageService принимает загрузки в локальный staging-каталог saveDir и
+// перекладывает готовые файлы в хранилище blobs
 type ImageService struct {
-	log      *logrus.Logger
-	saveDir  string
-	fileLock sync.Map
+	log         *logrus.Logger
+	saveDir     string
+	blobs       blob.Store
+	compression compress.Policy
+	// keys == nil - шифрование выключено
+	keys *encryption.Keyring
+	// shards == nil - состояние фрагментов не записывается
+	shards ShardRecorder
+	// cold == nil - холодного хранилища нет, все blob-ы в blobs
+	cold blob.Store
+	// locks == nil - blob-ы защищены только локом в процессе
+	locks BlobLocker
+	// fileLocks - локи staging-файлов и blob-ов в процессе, по имени
+	fileLocks keyLocks
+	// verified - копии blob-ов, уже сверенные со StoredChecksum, по каталогу данных и ключу
+	verified   map[string]verifiedReplica
+	verifiedMu sync.Mutex
+}
+
+// ShardRecorder отмечает в каталоге, где и в каком состоянии нашелся фрагмент blob-а
+type ShardRecorder interface {
+	UpdateShardHealth(checksum string, index int, location string, health string) error
+}
+
+// BlobLocker берет лок blob-а, общий для всех экземпляров сервера с одним каталогом.
+// Запись и удаление blob-а под ним не расходятся, даже если идут с разных экземпляров
+type BlobLocker interface {
+	LockBlob(ctx context.Context, key string) (func() error, error)
 }
 
 type ImageSaver interface {
 	DiskSave(ctx context.Context, imageName string, imageData []byte) error
 }
 
-func NewImageService(log *logrus.Logger, path string) *ImageService {
+func NewImageService(log *logrus.Logger, path string, blobs blob.Store, compression compress.Policy, keys *encryption.Keyring, shards ShardRecorder, cold blob.Store, locks BlobLocker) *ImageService {
 	return &ImageService{
-		log:      log,
-		saveDir:  path,
-		fileLock: sync.Map{},
+		log:         log,
+		saveDir:     path,
+		blobs:       blobs,
+		compression: compression,
+		keys:        keys,
+		shards:      shards,
+		cold:        cold,
+		locks:       locks,
 	}
 }
-func (s *ImageService) getFileLock(fileName string) *sync.Mutex {
-	lock, _ := s.fileLock.LoadOrStore(fileName, &sync.Mutex{})
-	return lock.(*sync.Mutex)
+
+// lockFile берет лок staging-файла или blob-а по имени в процессе. Возвращает функцию, которая его отпускает
+func (s *ImageService) lockFile(name string) func() {
+	return s.fileLocks.lock(name)
 }
-func (s *ImageService) DiskSave(ctx context.Context, imageName string, imageData []byte) error {
+
+// lockBlob берет лок blob-а key в процессе и общий лок в каталоге. Возвращает функцию, которая отпускает оба
+func (s *ImageService) lockBlob(ctx context.Context, key string) (func(), error) {
+	unlockFile := s.lockFile(key)
+	if s.locks == nil {
+		return unlockFile, nil
+	}
+
+	unlock, err := s.locks.LockBlob(ctx, key)
+	if err != nil {
+		unlockFile()
+		return nil, err
+	}
+	return func() {
+		if err := unlock(); err != nil {
+			s.log.Errorf("Failed to release lock of blob %s: %v", key, err)
+		}
+		unlockFile()
+	}, nil
+}
+
+func (s *ImageService) stagePath(stageName string) string {
+	return filepath.Join(s.saveDir, stagingDir, stageName)
+}
+
+// DiskSave дописывает чанк в staging-файл загрузки. До StoreBlob файл не виден под настоящим именем
+func (s *ImageService) DiskSave(ctx context.Context, stageName string, imageData []byte) error {
 	op := "internal.service.ImageService.DiskSave"
-	fileLock := s.getFileLock(imageName)
-	fileLock.Lock()
-	defer fileLock.Unlock()
+	unlock := s.lockFile(stageName)
+	defer unlock()
+
+	filePath := s.stagePath(stageName)
+	s.log.Info("Saving file on disk.... full path: ", filePath)
 
-	s.log.Info("Saving file on disk.... full path: ", filepath.Join(s.saveDir, imageName))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		s.log.Errorf("Failed to create staging dir: %v %s", err, op)
+		return err
+	}
 
-	file, err := os.OpenFile(filepath.Join(s.saveDir, imageName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	//s.log.Info(filepath.Join(s.saveDir, imageName))
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		s.log.Errorf("Failed to open file: %v %s", err, op)
 		return err
@@ -58,19 +137,335 @@ func (s *ImageService) DiskSave(ctx context.Context, imageName string, imageData
 	return nil
 }
 
-func (s *ImageService) DeleteFile(log *logrus.Logger, imageName string, success bool) {
-	fileLock := s.getFileLock(imageName)
-	fileLock.Lock()
-	defer fileLock.Unlock()
+// Truncate обрезает частично загруженный файл до подтвержденного смещения сессии
+func (s *ImageService) Truncate(stageName string, size int64) error {
+	op := "internal.service.ImageService.Truncate"
+	unlock := s.lockFile(stageName)
+	defer unlock()
+
+	filePath := s.stagePath(stageName)
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) && size == 0 {
+			return nil
+		}
+		s.log.Errorf("Failed to stat file: %v %s", err, op)
+		return err
+	}
+
+	if info.Size() < size {
+		return fmt.Errorf("%s: file %s has %d bytes, session offset is %d", op, stageName, info.Size(), size)
+	}
+
+	if err := os.Truncate(filePath, size); err != nil {
+		s.log.Errorf("Failed to truncate file: %v %s", err, op)
+		return err
+	}
+
+	return nil
+}
+
+// BlobKey - ключ содержимого, сохраненного под своим sha256
+func (s *ImageService) BlobKey(checksum string) string {
+	return BlobKey(checksum)
+}
+
+// StatBlob возвращает размер blob-а по ключу
+func (s *ImageService) StatBlob(ctx context.Context, key string) (int64, error) {
+	info, err := s.statBlob(ctx, key, catalog.TierHot)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// ReadBlob открывает length байт содержимого начиная с offset, length == 0 - до конца.
+// Зашифрованное содержимое расшифровывается, сжатое stored.Encoding распаковывается.
+// С пустым stored.Encoding сжатый blob отдается как есть
+func (s *ImageService) ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	if stored.KeyID == "" {
+		if stored.Encoding == compress.None {
+			return s.openBlob(ctx, key, stored, offset, length)
+		}
+		src, err := s.openBlob(ctx, key, stored, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return decode(src, stored.Encoding, offset, length)
+	}
+
+	if stored.Encoding == compress.None {
+		return s.readDecrypted(ctx, key, stored, offset, length)
+	}
+	src, err := s.readDecrypted(ctx, key, stored, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decode(src, stored.Encoding, offset, length)
+}
+
+// StoreBlob фиксирует загрузку: staging-файл сбрасывается на диск, при необходимости сжимается
+// по политике для mimeType и шифруется, затем commit записывает в базу, в каком виде и в каких каталогах
+// данных лежат его копии или фрагменты, и сообщает, заведен ли blob этой записью. Только после коммита
+// содержимое переносится в хранилище blob-ов. Если blob в каталоге уже был, загруженная копия удаляется:
+// каталог ссылается на его содержимое, а не на нее. Если перенос не удался, discard убирает записанное
+// commit, чтобы следующая загрузка того же содержимого не сослалась на blob без данных.
+// Все происходит под локом blob-а, чтобы не разойтись с параллельным RemoveBlob, в том числе
+// на другом экземпляре сервера
+func (s *ImageService) StoreBlob(stageName string, checksum string, mimeType string, commit func(stored catalog.StoredBlob) (bool, error), discard func() error) error {
+	op := "internal.service.ImageService.StoreBlob"
+
+	key := s.BlobKey(checksum)
+	unlock, err := s.lockBlob(context.Background(), key)
+	if err != nil {
+		s.log.Errorf("Failed to lock blob: %v %s", err, op)
+		return err
+	}
+	defer unlock()
+
+	unlockStage := s.lockFile(stageName)
+	defer unlockStage()
+
+	filePath := s.stagePath(stageName)
+
+	if err := syncFile(filePath); err != nil {
+		s.log.Errorf("Failed to sync staged file: %v %s", err, op)
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		s.log.Errorf("Failed to stat staged file: %v %s", err, op)
+		return err
+	}
+
+	storedPath, storedSize := filePath, info.Size()
+	encoding := s.compression.Encoding(mimeType)
+	if encoding != compress.None {
+		compressedPath, compressedSize, err := compressStaged(filePath, encoding)
+		if err != nil {
+			s.log.Errorf("Failed to compress staged file: %v %s", err, op)
+			return err
+		}
+		defer os.Remove(compressedPath)
 
-	filePath := filepath.Join(s.saveDir, imageName)
+		// Несжимаемое содержимое хранится как есть
+		if compressedSize < storedSize {
+			storedPath, storedSize = compressedPath, compressedSize
+		} else {
+			encoding = compress.None
+		}
+	}
+
+	stored := catalog.StoredBlob{Encoding: encoding, StoredSize: storedSize}
+	if s.keys != nil {
+		encryptedPath, err := s.encryptStaged(storedPath, &stored)
+		if err != nil {
+			s.log.Errorf("Failed to encrypt staged file: %v %s", err, op)
+			return err
+		}
+		defer os.Remove(encryptedPath)
+		storedPath = encryptedPath
+	}
+
+	// По sha256 сохраненного вида проверяются копии при чтении
+	if stored.StoredChecksum, err = fileChecksum(storedPath); err != nil {
+		s.log.Errorf("Failed to hash stored file: %v %s", err, op)
+		return err
+	}
+	if replicated, ok := s.blobs.(blob.Replicated); ok {
+		stored.Replicas = replicated.Placement(key)
+	}
+	if sharded, ok := s.blobs.(blob.Sharded); ok {
+		for index, location := range sharded.ShardPlacement(key) {
+			stored.Shards = append(stored.Shards, catalog.BlobShard{Index: index, Location: location, Health: catalog.ShardOK})
+		}
+	}
+
+	// Дубликат определяется по каталогу, а не по хранилищу: в хранилище может лежать orphan
+	// с другим ключом шифрования или сжатием, а у записи - не оказаться содержимого
+	created, err := commit(stored)
+	if err != nil {
+		return err
+	}
+	if !created {
+		s.log.Infof("Blob %s already stored, dropping duplicate %s", checksum, stageName)
+		if err := os.Remove(filePath); err != nil {
+			s.log.Errorf("Failed to remove duplicate file: %v %s", err, op)
+		}
+		return nil
+	}
+
+	// Отмена запроса клиентом не должна прерывать перенос между хранилищем и базой
+	ctx := context.Background()
+
+	// Если содержимое не легло в хранилище, запись уже ссылается на blob без содержимого: она откатывается,
+	// пока лок blob-а еще взят, а после падения между коммитом и переносом ее найдет reconciler
+	if err := s.putCommitted(ctx, key, storedPath); err != nil {
+		s.log.Errorf("Failed to move staged file %s to blob %s: %v %s", stageName, checksum, err, op)
+		if discardErr := discard(); discardErr != nil {
+			s.log.Errorf("Failed to discard catalog record of blob %s without content: %v %s", checksum, discardErr, op)
+		}
+		return err
+	}
+
+	if storedPath != filePath {
+		if err := os.Remove(filePath); err != nil {
+			s.log.Errorf("Failed to remove staged file: %v %s", err, op)
+		}
+	}
+
+	return nil
+}
+
+// putCommitted кладет содержимое только что записанного в каталог blob-а. Записи о blob-е не было, поэтому
+// все, что лежит под его ключом, - orphan-ы. В основном хранилище их перезапишет загруженная копия,
+// а из холодного их нужно убрать, чтобы перенос туда их не подхватил
+func (s *ImageService) putCommitted(ctx context.Context, key string, filePath string) error {
+	if s.cold != nil {
+		if err := s.cold.Delete(ctx, key); err != nil {
+			return fmt.Errorf("remove stale cold copy: %w", err)
+		}
+	}
+	return s.putStaged(ctx, key, filePath)
+}
+
+func (s *ImageService) putStaged(ctx context.Context, key string, filePath string) error {
+	return putFile(ctx, s.blobs, key, filePath)
+}
+
+// putFile кладет файл в store и удаляет его
+func putFile(ctx context.Context, store blob.Store, key string, filePath string) error {
+	if putter, ok := store.(blob.FilePutter); ok {
+		return putter.PutFile(ctx, key, filePath)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := store.Put(ctx, key, file, info.Size()); err != nil {
+		return err
+	}
+
+	return os.Remove(filePath)
+}
+
+// SweepStaging удаляет staging-файлы, для которых isActive не находит сессию загрузки.
+// Вызывается при старте, когда загрузок еще нет
+func (s *ImageService) SweepStaging(isActive func(stageName string) (bool, error)) error {
+	op := "internal.service.ImageService.SweepStaging"
+
+	dir := filepath.Join(s.saveDir, stagingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, entry := range entries {
+		active, err := isActive(entry.Name())
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if active {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			s.log.Errorf("Failed to remove stale staging file %s: %v %s", entry.Name(), err, op)
+			continue
+		}
+		s.log.Infof("Removed stale staging file %s", entry.Name())
+	}
+
+	return nil
+}
+
+func syncFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+// RemoveBlob отпускает ссылку на blob через release и удаляет данные, если ссылка была последней.
+// release возвращает и ключи вариантов blob-а: записи о них уходят из каталога вместе с blob-ом
+func (s *ImageService) RemoveBlob(checksum string, release func() (bool, []string, error)) error {
+	op := "internal.service.ImageService.RemoveBlob"
+
+	key := s.BlobKey(checksum)
+	unlock, err := s.lockBlob(context.Background(), key)
+	if err != nil {
+		s.log.Errorf("Failed to lock blob: %v %s", err, op)
+		return err
+	}
+	defer unlock()
+
+	last, variants, err := release()
+	if err != nil {
+		return err
+	}
+	if !last {
+		return nil
+	}
+
+	for _, variantKey := range variants {
+		if err := s.blobs.Delete(context.Background(), variantKey); err != nil {
+			s.log.Errorf("Failed to remove variant %s: %v %s", variantKey, err, op)
+			return err
+		}
+	}
+
+	// До перехода на шардированную раскладку blob мог остаться в плоском каталоге
+	for _, blobKey := range []string{key, FlatBlobKey(checksum)} {
+		for _, store := range s.tiers() {
+			if err := store.Delete(context.Background(), blobKey); err != nil {
+				s.log.Errorf("Failed to remove blob: %v %s", err, op)
+				return err
+			}
+		}
+	}
+
+	s.log.Infof("Blob %s removed, no references left", checksum)
+	return nil
+}
+
+// DeleteFile удаляет staging-файл неудачной загрузки
+func (s *ImageService) DeleteFile(log *logrus.Logger, stageName string, success bool) {
+	unlock := s.lockFile(stageName)
+	defer unlock()
+
+	filePath := s.stagePath(stageName)
 
 	if !success {
 		// Если операция не завершилась успешно, удаляем файл
-		if err := os.Remove(filePath); err != nil {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
 			log.Errorf("failed to delete file %s: %v", filePath, err)
-		} else {
+		} else if err == nil {
 			log.Infof("File %s deleted due to upload failure", filePath)
 		}
 	}
 }
+
+// RemoveLegacyFile удаляет файл, загруженный до появления blob-ов, по его path_to_file
+func (s *ImageService) RemoveLegacyFile(path string) error {
+	unlock := s.lockFile(path)
+	defer unlock()
+
+	return s.blobs.Delete(context.Background(), path)
+}
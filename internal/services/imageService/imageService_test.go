@@ -0,0 +1,147 @@
+package imageService
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+)
+
+func readStored(t *testing.T, store blob.Store, key string) string {
+	t.Helper()
+
+	rc, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStoreBlob(t *testing.T) {
+	data := []byte("uploaded content")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	errCommit := errors.New("catalog is down")
+
+	tests := []struct {
+		name string
+		// hotOrphan, coldOrphan - содержимое под ключом blob-а, на которое нет записи
+		hotOrphan, coldOrphan string
+		created               bool
+		commitErr             error
+		// want - что должно лежать в основном хранилище, пусто - ничего
+		want string
+	}{
+		{name: "new blob", created: true, want: string(data)},
+		{name: "duplicate", created: false},
+		// Сирота могла быть зашифрована другим ключом: новая запись ссылается на загруженную копию
+		{name: "orphan overwritten", hotOrphan: "stale bytes", coldOrphan: "stale bytes", created: true, want: string(data)},
+		{name: "duplicate keeps stored content", hotOrphan: "stored bytes", created: false, want: "stored bytes"},
+		{name: "commit failed", hotOrphan: "stale bytes", commitErr: errCommit, want: "stale bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hot, cold := newTierTestService(t)
+			key := s.BlobKey(checksum)
+			if tt.hotOrphan != "" {
+				putBlob(t, hot, key, []byte(tt.hotOrphan))
+			}
+			if tt.coldOrphan != "" {
+				putBlob(t, cold, key, []byte(tt.coldOrphan))
+			}
+			if err := s.DiskSave(context.Background(), "upload-1", data); err != nil {
+				t.Fatal(err)
+			}
+
+			var committed catalog.StoredBlob
+			err := s.StoreBlob("upload-1", checksum, "image/png", func(stored catalog.StoredBlob) (bool, error) {
+				// Содержимое кладется в хранилище только после записи в каталог
+				if tt.hotOrphan == "" && hasBlob(t, hot, key) {
+					t.Fatalf("blob stored before commit")
+				}
+				committed = stored
+				return tt.created, tt.commitErr
+			}, func() error {
+				t.Fatalf("discarded after a successful put")
+				return nil
+			})
+			if !errors.Is(err, tt.commitErr) {
+				t.Fatalf("got %v, want %v", err, tt.commitErr)
+			}
+			if committed.StoredSize != int64(len(data)) || committed.StoredChecksum != checksum {
+				t.Fatalf("committed %+v", committed)
+			}
+
+			if tt.want == "" {
+				if hasBlob(t, hot, key) {
+					t.Fatalf("duplicate stored")
+				}
+			} else if got := readStored(t, hot, key); got != tt.want {
+				t.Fatalf("stored %q, want %q", got, tt.want)
+			}
+			if tt.created && hasBlob(t, cold, key) {
+				t.Fatalf("stale cold copy kept")
+			}
+
+			// Staging-файл неудачной загрузки удаляет DeleteFile, в остальных случаях его уже нет
+			_, err = os.Stat(s.stagePath("upload-1"))
+			if staged := err == nil; staged != (tt.commitErr != nil) {
+				t.Fatalf("staging file kept: %v", staged)
+			}
+		})
+	}
+}
+
+// failingDeletes отказывает в Delete
+type failingDeletes struct {
+	blob.Store
+}
+
+var errDelete = errors.New("cold storage is down")
+
+func (failingDeletes) Delete(ctx context.Context, key string) error {
+	return errDelete
+}
+
+// TestStoreBlobColdCleanupFailed: запись уже в каталоге, а содержимое не перенесено - она откатывается
+func TestStoreBlobColdCleanupFailed(t *testing.T) {
+	data := []byte("uploaded content")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	s, hot, cold := newTierTestService(t)
+	s.cold = failingDeletes{Store: cold}
+	if err := s.DiskSave(context.Background(), "upload-1", data); err != nil {
+		t.Fatal(err)
+	}
+
+	discarded := false
+	err := s.StoreBlob("upload-1", checksum, "image/png", func(stored catalog.StoredBlob) (bool, error) {
+		return true, nil
+	}, func() error {
+		discarded = true
+		return nil
+	})
+	if !errors.Is(err, errDelete) {
+		t.Fatalf("got %v, want %v", err, errDelete)
+	}
+	if !discarded {
+		t.Fatalf("catalog record of a blob without content kept")
+	}
+	if hasBlob(t, hot, s.BlobKey(checksum)) {
+		t.Fatalf("blob stored after a failed cleanup")
+	}
+}
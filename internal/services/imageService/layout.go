@@ -0,0 +1,39 @@
+package imageService
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// В legacyDir переезжают файлы, загруженные до появления blob-ов
+const legacyDir = "files"
+
+// ShardedKey раскладывает name по двум уровням каталогов из первых символов hash: dir/ab/cd/name.
+// Так в одном каталоге оказывается не больше 256 записей на уровень
+func ShardedKey(dir string, hash string, name string) string {
+	if len(hash) < 4 {
+		return dir + "/" + name
+	}
+	return dir + "/" + hash[:2] + "/" + hash[2:4] + "/" + name
+}
+
+// BlobKey - ключ содержимого с данным sha256
+func BlobKey(checksum string) string {
+	return ShardedKey(blobDir, checksum, checksum)
+}
+
+// VariantKey - ключ варианта name blob-а с данным sha256. Варианты одного blob-а лежат в одном каталоге
+func VariantKey(checksum string, name string) string {
+	return ShardedKey(variantDir, checksum, checksum+"/"+name)
+}
+
+// FlatBlobKey - ключ blob-а в плоской раскладке, которая была до шардирования
+func FlatBlobKey(checksum string) string {
+	return blobDir + "/" + checksum
+}
+
+// LegacyKey - ключ файла без blob-а, шардированный по sha256 имени
+func LegacyKey(fileName string) string {
+	sum := sha256.Sum256([]byte(fileName))
+	return ShardedKey(legacyDir, hex.EncodeToString(sum[:]), fileName)
+}
@@ -0,0 +1,50 @@
+package imageService
+
+import "sync"
+
+// keyLocks - мьютексы по имени. Запись живет, пока лок кто-то держит или ждет, поэтому случайные
+// id сессий и ключи blob-ов не копятся в памяти. Нулевое значение готово к работе
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	// refs - сколько вызовов lock держат или ждут этот лок
+	refs int
+}
+
+// lock берет лок key и возвращает функцию, которая его отпускает
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*keyLock)
+	}
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// len - сколько имен сейчас занято
+func (l *keyLocks) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
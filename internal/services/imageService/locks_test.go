@@ -0,0 +1,41 @@
+package imageService
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyLocks(t *testing.T) {
+	var locks keyLocks
+
+	// Один ключ держит один вызов за раз
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock("blob")
+			counter++
+			unlock()
+		}()
+	}
+	wg.Wait()
+	if counter != 50 {
+		t.Fatalf("counter %d, want 50", counter)
+	}
+
+	// Разные ключи не мешают друг другу
+	unlockA := locks.lock("a")
+	unlockB := locks.lock("b")
+	if locks.len() != 2 {
+		t.Fatalf("%d keys locked, want 2", locks.len())
+	}
+	unlockA()
+	unlockB()
+
+	// Отпущенные ключи не остаются в памяти
+	if locks.len() != 0 {
+		t.Fatalf("%d keys left after unlock", locks.len())
+	}
+}
@@ -0,0 +1,37 @@
+package imageService
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"imagestorage/internal/mimetype"
+)
+
+// DetectType определяет MIME-тип staging-файла по первым байтам содержимого
+func (s *ImageService) DetectType(stageName string) (string, error) {
+	op := "internal.service.ImageService.DetectType"
+
+	unlock := s.lockFile(stageName)
+	defer unlock()
+
+	file, err := os.Open(s.stagePath(stageName))
+	if errors.Is(err, fs.ErrNotExist) {
+		// Пустая загрузка не создает staging-файл
+		return mimetype.Unknown, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	head := make([]byte, mimetype.SniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mimetype.Detect(head[:n]), nil
+}
@@ -0,0 +1,53 @@
+package imageService
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"imagestorage/internal/mimetype"
+	"imagestorage/internal/storage/blob"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDetectType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		want   string
+	}{
+		{name: "no staging file", want: mimetype.Unknown},
+		{name: "png in one chunk", chunks: [][]byte{png}, want: "image/png"},
+		// Сигнатура собирается из нескольких чанков
+		{name: "png split across chunks", chunks: [][]byte{png[:3], png[3:5], png[5:]}, want: "image/png"},
+		{name: "long text", chunks: [][]byte{bytes.Repeat([]byte("text "), 1000)}, want: "text/plain"},
+		{name: "unrecognized", chunks: [][]byte{{0x00, 0x01, 0x02}}, want: mimetype.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			log := logrus.New()
+			log.SetOutput(io.Discard)
+			s := NewImageService(log, dir, blob.NewFileStore(dir), nil, nil, nil, nil, nil)
+
+			for _, chunk := range tt.chunks {
+				if err := s.DiskSave(context.Background(), "upload-1", chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.DetectType("upload-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
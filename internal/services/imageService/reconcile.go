@@ -0,0 +1,106 @@
+package imageService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+)
+
+var errNotListable = errors.New("blob storage cannot list its contents")
+
+// ListBlobs перечисляет содержимое основного и холодного хранилищ. Staging-каталог не перечисляется
+func (s *ImageService) ListBlobs(ctx context.Context, fn func(tier string, key string, info blob.Info) error) error {
+	op := "internal.service.ImageService.ListBlobs"
+
+	tiers := []string{catalog.TierHot, catalog.TierCold}
+	for i, store := range s.tiers() {
+		lister, ok := store.(blob.Lister)
+		if !ok {
+			return fmt.Errorf("%s: %s: %w", op, tiers[i], errNotListable)
+		}
+
+		err := lister.List(ctx, func(key string, info blob.Info) error {
+			return fn(tiers[i], key, info)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// StatStoredBlob ищет blob сначала в хранилище tier, потом в другом
+func (s *ImageService) StatStoredBlob(ctx context.Context, key string, tier string) (blob.Info, error) {
+	return s.statBlob(ctx, key, tier)
+}
+
+// ChecksumStoredBlob читает blob в том виде, в каком он лежит в хранилище, и возвращает его sha256.
+// Испорченные реплики и фрагменты пропускаются так же, как при скачивании
+func (s *ImageService) ChecksumStoredBlob(ctx context.Context, key string, stored catalog.StoredBlob) (string, error) {
+	rc, err := s.openBlob(ctx, key, stored, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	return sha256Of(rc)
+}
+
+// RemoveOrphan удаляет blob key из хранилища tier, если isOrphan подтверждает, что на него
+// никто не ссылается. Проверка и удаление идут под локом blob-а, чтобы не разойтись с StoreBlob
+func (s *ImageService) RemoveOrphan(ctx context.Context, tier string, key string, isOrphan func() (bool, error)) (bool, error) {
+	op := "internal.service.ImageService.RemoveOrphan"
+
+	store := s.blobs
+	if tier == catalog.TierCold {
+		if s.cold == nil {
+			return false, fmt.Errorf("%s: %w", op, errNoColdStorage)
+		}
+		store = s.cold
+	}
+
+	unlock, err := s.lockBlob(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer unlock()
+
+	orphan, err := isOrphan()
+	if err != nil || !orphan {
+		return false, err
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Infof("Orphan %s removed from %s storage", key, tier)
+	return true, nil
+}
+
+// ForgetMissing вызывает forget для blob-а key, которого нет ни в одном хранилище. Отсутствие перепроверяется
+// под локом blob-а: StoreBlob кладет содержимое после записи в каталог, и reconciler мог застать запись без него
+func (s *ImageService) ForgetMissing(ctx context.Context, key string, tier string, forget func() error) (bool, error) {
+	op := "internal.service.ImageService.ForgetMissing"
+
+	unlock, err := s.lockBlob(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer unlock()
+
+	if _, err := s.statBlob(ctx, key, tier); err == nil {
+		return false, nil
+	} else if !errors.Is(err, blob.ErrNotFound) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := forget(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,242 @@
+package imageService
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+)
+
+var errChecksumMismatch = errors.New("stored checksum mismatch")
+
+// openHot открывает диапазон blob-а в основном хранилище. С репликацией копии перебираются начиная с записанных
+// в каталоге файлов, затем по остальным каталогам данных. Если известен stored.StoredChecksum,
+// копия сверяется с ним (см. openReplica), испорченные копии пропускаются.
+// С фрагментами blob читается хранилищем, а изменившееся состояние прочитанных фрагментов записывается
+// в каталог при закрытии: порча содержимого находится только по ходу чтения
+func (s *ImageService) openHot(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	op := "internal.service.ImageService.openHot"
+
+	if sharded, ok := s.blobs.(blob.Sharded); ok {
+		shards := &shardReport{}
+		rc, err := sharded.ReadShards(ctx, key, offset, length, shards.add)
+		if err != nil {
+			s.recordShards(key, stored.Shards, shards.states)
+			return nil, err
+		}
+		return &shardReadCloser{ReadCloser: rc, done: func() {
+			s.recordShards(key, stored.Shards, shards.states)
+		}}, nil
+	}
+
+	replicated, ok := s.blobs.(blob.Replicated)
+	if !ok {
+		return s.blobs.GetRange(ctx, key, offset, length)
+	}
+
+	locations := slices.Clone(stored.Replicas)
+	for _, location := range replicated.Locations(key) {
+		if !slices.Contains(locations, location) {
+			locations = append(locations, location)
+		}
+	}
+
+	var lastErr error = blob.ErrNotFound
+	for _, location := range locations {
+		replica, ok := replicated.Replica(location)
+		if !ok {
+			s.log.Warnf("Replica of %s is recorded in %s, which is not a data dir anymore %s", key, location, op)
+			continue
+		}
+
+		rc, err := s.openReplica(ctx, location, replica, key, stored.StoredChecksum, offset, length)
+		if err == nil {
+			return rc, nil
+		}
+
+		if errors.Is(err, blob.ErrNotFound) {
+			if slices.Contains(stored.Replicas, location) {
+				s.log.Warnf("Replica of %s is missing in %s, trying next %s", key, location, op)
+			}
+			continue
+		}
+		s.log.Errorf("Replica of %s in %s is unreadable, trying next: %v %s", key, location, err, op)
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%s: no intact replica of %s: %w", op, key, lastErr)
+}
+
+// shardReport собирает состояния фрагментов, о которых сообщает ReadShards. Фрагмент, испорченный
+// по ходу чтения, сообщается повторно, и остается последнее состояние
+type shardReport struct {
+	states []blob.ShardState
+}
+
+func (r *shardReport) add(state blob.ShardState) {
+	for i := range r.states {
+		if r.states[i].Index == state.Index {
+			r.states[i] = state
+			return
+		}
+	}
+	r.states = append(r.states, state)
+}
+
+// shardReadCloser вызывает done один раз при закрытии
+type shardReadCloser struct {
+	io.ReadCloser
+	done func()
+}
+
+func (r *shardReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if r.done != nil {
+		r.done()
+		r.done = nil
+	}
+	return err
+}
+
+func (s *ImageService) recordShards(key string, known []catalog.BlobShard, states []blob.ShardState) {
+	op := "internal.service.ImageService.recordShards"
+
+	if s.shards == nil || len(known) == 0 {
+		return
+	}
+
+	recorded := make(map[int]catalog.BlobShard, len(known))
+	for _, shard := range known {
+		recorded[shard.Index] = shard
+	}
+
+	// Фрагменты есть только у blob-ов, а ключ blob-а заканчивается его checksum
+	checksum := path.Base(key)
+	for _, state := range states {
+		health := catalog.ShardOK
+		if errors.Is(state.Err, blob.ErrNotFound) {
+			health = catalog.ShardMissing
+		} else if state.Err != nil {
+			health = catalog.ShardCorrupt
+		}
+
+		if health != catalog.ShardOK {
+			s.log.Warnf("Shard %d of %s in %s is %s: %v %s", state.Index, key, state.Location, health, state.Err, op)
+		}
+
+		if shard, ok := recorded[state.Index]; ok && shard.Health == health && shard.Location == state.Location {
+			continue
+		}
+		if err := s.shards.UpdateShardHealth(checksum, state.Index, state.Location, health); err != nil {
+			s.log.Errorf("Failed to record shard %d of %s: %v %s", state.Index, key, err, op)
+		}
+	}
+}
+
+// maxVerifiedReplicas ограничивает память под проверенные копии: при переполнении список начинается заново
+const maxVerifiedReplicas = 100000
+
+// replicaVerifyTTL - сколько чтения диапазонов доверяют проверке копии. Порча на диске не меняет
+// ни размер, ни время изменения файла, поэтому проверка должна устаревать
+const replicaVerifyTTL = time.Hour
+
+// verifiedReplica - копия, чье содержимое совпало с checksum в момент at, пока ее размер и время изменения те же
+type verifiedReplica struct {
+	checksum string
+	info     blob.Info
+	at       time.Time
+}
+
+// openReplica открывает диапазон копии key в каталоге location. Копия сверяется с checksum целиком
+// при каждом чтении всего blob-а. Короткие чтения диапазонов не читают весь blob: им хватает проверки
+// не старше replicaVerifyTTL, если копия с тех пор не изменилась
+func (s *ImageService) openReplica(ctx context.Context, location string, replica blob.Store, key string, checksum string, offset int64, length int64) (io.ReadCloser, error) {
+	if checksum != "" {
+		info, err := replica.Stat(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		whole := offset == 0 && length == 0
+		current := verifiedReplica{checksum: checksum, info: info, at: time.Now()}
+		if whole || !s.replicaVerified(location, key, current) {
+			if err := verifyReplica(ctx, replica, key, checksum); err != nil {
+				s.forgetReplica(location, key)
+				return nil, err
+			}
+			s.rememberReplica(location, key, current)
+		}
+	}
+
+	return replica.GetRange(ctx, key, offset, length)
+}
+
+func verifyReplica(ctx context.Context, replica blob.Store, key string, checksum string) error {
+	src, err := replica.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	sum, err := sha256Of(src)
+	if err != nil {
+		return err
+	}
+	if sum != checksum {
+		return fmt.Errorf("%w: got %s, want %s", errChecksumMismatch, sum, checksum)
+	}
+	return nil
+}
+
+func (s *ImageService) replicaVerified(location string, key string, replica verifiedReplica) bool {
+	s.verifiedMu.Lock()
+	defer s.verifiedMu.Unlock()
+
+	verified, ok := s.verified[path.Join(location, key)]
+	return ok && verified.checksum == replica.checksum && verified.info.Size == replica.info.Size &&
+		verified.info.ModTime.Equal(replica.info.ModTime) && replica.at.Sub(verified.at) < replicaVerifyTTL
+}
+
+func (s *ImageService) rememberReplica(location string, key string, replica verifiedReplica) {
+	s.verifiedMu.Lock()
+	defer s.verifiedMu.Unlock()
+
+	if s.verified == nil || len(s.verified) >= maxVerifiedReplicas {
+		s.verified = make(map[string]verifiedReplica)
+	}
+	s.verified[path.Join(location, key)] = replica
+}
+
+func (s *ImageService) forgetReplica(location string, key string) {
+	s.verifiedMu.Lock()
+	defer s.verifiedMu.Unlock()
+
+	delete(s.verified, path.Join(location, key))
+}
+
+// fileChecksum - sha256 файла в hex
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return sha256Of(file)
+}
+
+func sha256Of(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,175 @@
+package imageService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newReplicatedTestService(t *testing.T) (*ImageService, *blob.ReplicatedStore) {
+	t.Helper()
+
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+	store, err := blob.NewReplicatedStore(dirs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewImageService(log, t.TempDir(), store, nil, nil, nil, nil, nil), store
+}
+
+func replicaPath(location string, key string) string {
+	return filepath.Join(location, filepath.FromSlash(key))
+}
+
+// corruptReplica портит байт at копии, не меняя ни ее размер, ни время изменения
+func corruptReplica(t *testing.T, location string, key string, at int) {
+	t.Helper()
+
+	file := replicaPath(location, key)
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[at] ^= 0xff
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readHot(s *ImageService, key string, stored catalog.StoredBlob, offset int64, length int64) ([]byte, error) {
+	rc, err := s.openHot(context.Background(), key, stored, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func TestOpenHotReplicas(t *testing.T) {
+	data := []byte("replicated blob content")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	remove := func(t *testing.T, location string, key string) {
+		if err := os.Remove(replicaPath(location, key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupt := func(t *testing.T, location string, key string) {
+		corruptReplica(t, location, key, 3)
+	}
+
+	tests := []struct {
+		name string
+		// damage - что сделать с копиями в Placement, по порядку
+		damage []func(t *testing.T, location string, key string)
+		// leftover - копия лежит и в каталоге вне Placement
+		leftover bool
+		wantErr  error
+	}{
+		{name: "intact"},
+		{name: "first missing", damage: []func(*testing.T, string, string){remove}},
+		{name: "first corrupted", damage: []func(*testing.T, string, string){corrupt}},
+		{name: "second corrupted", damage: []func(*testing.T, string, string){nil, corrupt}},
+		{name: "copy outside placement", damage: []func(*testing.T, string, string){remove, corrupt}, leftover: true},
+		{name: "all corrupted", damage: []func(*testing.T, string, string){corrupt, corrupt}, wantErr: errChecksumMismatch},
+		{name: "all missing", damage: []func(*testing.T, string, string){remove, remove}, wantErr: blob.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newReplicatedTestService(t)
+			key := s.BlobKey(checksum)
+			putBlob(t, store, key, data)
+			placement := store.Placement(key)
+			if tt.leftover {
+				other := store.Locations(key)[2]
+				replica, _ := store.Replica(other)
+				putBlob(t, replica, key, data)
+			}
+			for i, damage := range tt.damage {
+				if damage != nil {
+					damage(t, placement[i], key)
+				}
+			}
+
+			stored := catalog.StoredBlob{StoredChecksum: checksum, Replicas: placement}
+			for _, r := range []struct{ offset, length int64 }{{0, 0}, {2, 5}} {
+				got, err := readHot(s, key, stored, r.offset, r.length)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("read %d+%d: got %v, want %v", r.offset, r.length, err, tt.wantErr)
+				}
+				if tt.wantErr != nil {
+					continue
+				}
+				want := data[r.offset:]
+				if r.length > 0 {
+					want = want[:r.length]
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("read %d+%d: got %q, want %q", r.offset, r.length, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestReplicaVerifyExpires замечает порчу, которая не меняет ни размер, ни время изменения копии
+func TestReplicaVerifyExpires(t *testing.T) {
+	data := []byte("replicated blob content")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	s, store := newReplicatedTestService(t)
+	key := s.BlobKey(checksum)
+	putBlob(t, store, key, data)
+	placement := store.Placement(key)
+	stored := catalog.StoredBlob{StoredChecksum: checksum, Replicas: placement}
+
+	if _, err := readHot(s, key, stored, 2, 5); err != nil {
+		t.Fatal(err)
+	}
+	corruptReplica(t, placement[0], key, 3)
+
+	// Чтение всего blob-а сверяет копию заново и читает вторую
+	got, err := readHot(s, key, stored, 0, 0)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, ok := s.verified[path.Join(placement[0], key)]; ok {
+		t.Fatalf("corrupted replica is still trusted")
+	}
+
+	// Проверка второй копии устаревает, и ее порчу замечает и чтение диапазона
+	corruptReplica(t, placement[1], key, 3)
+	s.verifiedMu.Lock()
+	for k, v := range s.verified {
+		v.at = v.at.Add(-2 * replicaVerifyTTL)
+		s.verified[k] = v
+	}
+	s.verifiedMu.Unlock()
+	if got, err := readHot(s, key, stored, 2, 5); !errors.Is(err, errChecksumMismatch) {
+		t.Fatalf("got %q, %v, want %v", got, err, errChecksumMismatch)
+	}
+}
@@ -0,0 +1,156 @@
+package imageService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+)
+
+var errNoColdStorage = errors.New("cold storage is not configured")
+
+// tiers - хранилища, в которых может лежать blob
+func (s *ImageService) tiers() []blob.Store {
+	if s.cold == nil {
+		return []blob.Store{s.blobs}
+	}
+	return []blob.Store{s.blobs, s.cold}
+}
+
+// statBlob ищет blob сначала в хранилище tier, потом в другом: каталог и данные расходятся,
+// пока идет перенос, и после переноса, прерванного до записи в каталог
+func (s *ImageService) statBlob(ctx context.Context, key string, tier string) (blob.Info, error) {
+	stores := s.tiers()
+	if tier == catalog.TierCold && s.cold != nil {
+		stores = []blob.Store{s.cold, s.blobs}
+	}
+
+	var info blob.Info
+	var err error
+	for _, store := range stores {
+		info, err = store.Stat(ctx, key)
+		if !errors.Is(err, blob.ErrNotFound) {
+			return info, err
+		}
+	}
+	return info, err
+}
+
+// openBlob открывает диапазон blob-а в хранилище stored.Tier, а если его там нет - в другом
+func (s *ImageService) openBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	if s.cold == nil {
+		return s.openHot(ctx, key, stored, offset, length)
+	}
+
+	if stored.Tier == catalog.TierCold {
+		rc, err := s.cold.GetRange(ctx, key, offset, length)
+		if !errors.Is(err, blob.ErrNotFound) {
+			return rc, err
+		}
+		return s.openHot(ctx, key, stored, offset, length)
+	}
+
+	rc, err := s.openHot(ctx, key, stored, offset, length)
+	if !errors.Is(err, blob.ErrNotFound) {
+		return rc, err
+	}
+	return s.cold.GetRange(ctx, key, offset, length)
+}
+
+// MoveBlob переносит blob между хранилищами from и to: копирует его, сверяя со storedChecksum,
+// вызывает commit (запись о переносе в каталоге) и только потом удаляет исходную копию.
+// Если commit вернул false, blob уже перенесли или удалили, и новая копия удаляется.
+// Все происходит под локом blob-а, чтобы не разойтись с StoreBlob и RemoveBlob
+func (s *ImageService) MoveBlob(ctx context.Context, checksum string, storedChecksum string, from string, to string, commit func() (bool, error)) error {
+	op := "internal.service.ImageService.MoveBlob"
+
+	if s.cold == nil {
+		return fmt.Errorf("%s: %w", op, errNoColdStorage)
+	}
+	src, dst := s.blobs, s.cold
+	if from == catalog.TierCold {
+		src, dst = s.cold, s.blobs
+	}
+
+	key := s.BlobKey(checksum)
+	unlock, err := s.lockBlob(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer unlock()
+
+	// Копия в to могла остаться от прерванного переноса. Put атомарный, поэтому она целая
+	_, err = dst.Stat(ctx, key)
+	existed := err == nil
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !existed {
+		if err := s.copyBlob(ctx, src, dst, key, storedChecksum); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	moved, err := commit()
+	if err != nil || !moved {
+		if !existed {
+			if err := dst.Delete(ctx, key); err != nil {
+				s.log.Errorf("Failed to remove copy of %s in %s: %v %s", checksum, to, err, op)
+			}
+		}
+		return err
+	}
+
+	if err := src.Delete(ctx, key); err != nil {
+		// Каталог уже указывает на to, лишняя копия в from только занимает место
+		s.log.Errorf("Failed to remove %s from %s after move: %v %s", checksum, from, err, op)
+	}
+
+	s.log.Infof("Blob %s moved from %s to %s", checksum, from, to)
+	return nil
+}
+
+// copyBlob копирует blob из src в dst через временный файл в staging-каталоге
+func (s *ImageService) copyBlob(ctx context.Context, src blob.Store, dst blob.Store, key string, storedChecksum string) error {
+	var rc io.ReadCloser
+	var err error
+	if src == s.blobs {
+		// openHot пропускает испорченные реплики, если storedChecksum известен
+		rc, err = s.openHot(ctx, key, catalog.StoredBlob{StoredChecksum: storedChecksum}, 0, 0)
+	} else {
+		rc, err = src.Get(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dir := filepath.Join(s.saveDir, stagingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "tier-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	sum, err := sha256Of(io.TeeReader(rc, tmp))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if storedChecksum != "" && sum != storedChecksum {
+		return fmt.Errorf("%w: got %s, want %s", errChecksumMismatch, sum, storedChecksum)
+	}
+
+	return putFile(ctx, dst, key, tmp.Name())
+}
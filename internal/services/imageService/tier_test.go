@@ -0,0 +1,183 @@
+package imageService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTierTestService(t *testing.T) (*ImageService, blob.Store, blob.Store) {
+	t.Helper()
+
+	dir := t.TempDir()
+	hot := blob.NewFileStore(filepath.Join(dir, "hot"))
+	cold := blob.NewFileStore(filepath.Join(dir, "cold"))
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewImageService(log, dir, hot, nil, nil, nil, cold, nil), hot, cold
+}
+
+func putBlob(t *testing.T, store blob.Store, key string, data []byte) {
+	t.Helper()
+
+	if err := store.Put(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasBlob(t *testing.T, store blob.Store, key string) bool {
+	t.Helper()
+
+	_, err := store.Stat(context.Background(), key)
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestMoveBlob(t *testing.T) {
+	data := []byte("blob moved between tiers")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	errCommit := errors.New("catalog is down")
+
+	tests := []struct {
+		name           string
+		from, to       string
+		storedChecksum string
+		// leftover - копия в to от прерванного переноса
+		leftover bool
+		commit   func() (bool, error)
+		wantErr  error
+		// inFrom, inTo - где blob должен остаться
+		inFrom, inTo bool
+	}{
+		{name: "to cold", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum,
+			commit: func() (bool, error) { return true, nil }, inTo: true},
+		{name: "back to hot", from: catalog.TierCold, to: catalog.TierHot, storedChecksum: checksum,
+			commit: func() (bool, error) { return true, nil }, inTo: true},
+		{name: "unknown stored checksum", from: catalog.TierHot, to: catalog.TierCold,
+			commit: func() (bool, error) { return true, nil }, inTo: true},
+		{name: "already moved", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum,
+			commit: func() (bool, error) { return false, nil }, inFrom: true},
+		{name: "commit failed", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum,
+			commit: func() (bool, error) { return false, errCommit }, wantErr: errCommit, inFrom: true},
+		{name: "checksum mismatch", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum[1:] + "0",
+			commit: func() (bool, error) {
+				t.Fatalf("commit after a checksum mismatch")
+				return false, nil
+			}, wantErr: errChecksumMismatch, inFrom: true},
+		{name: "leftover copy reused", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum[1:] + "0", leftover: true,
+			commit: func() (bool, error) { return true, nil }, inTo: true},
+		// Копия, оставшаяся от прерванного переноса, не удаляется, даже если перенос не записан
+		{name: "leftover copy kept", from: catalog.TierHot, to: catalog.TierCold, storedChecksum: checksum, leftover: true,
+			commit: func() (bool, error) { return false, nil }, inFrom: true, inTo: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hot, cold := newTierTestService(t)
+			from, to := hot, cold
+			if tt.from == catalog.TierCold {
+				from, to = cold, hot
+			}
+			key := s.BlobKey(checksum)
+			putBlob(t, from, key, data)
+			if tt.leftover {
+				putBlob(t, to, key, data)
+			}
+
+			err := s.MoveBlob(context.Background(), checksum, tt.storedChecksum, tt.from, tt.to, tt.commit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if got := hasBlob(t, from, key); got != tt.inFrom {
+				t.Fatalf("blob in %s: %v, want %v", tt.from, got, tt.inFrom)
+			}
+			if got := hasBlob(t, to, key); got != tt.inTo {
+				t.Fatalf("blob in %s: %v, want %v", tt.to, got, tt.inTo)
+			}
+			if !tt.inTo {
+				return
+			}
+
+			// Перенесенный blob читается через openBlob по новому tier
+			rc, err := s.openBlob(context.Background(), key, catalog.StoredBlob{Tier: tt.to}, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("read %q after move", got)
+			}
+		})
+	}
+}
+
+func TestMoveBlobWithoutColdStorage(t *testing.T) {
+	dir := t.TempDir()
+	s := NewImageService(logrus.New(), dir, blob.NewFileStore(dir), nil, nil, nil, nil, nil)
+
+	err := s.MoveBlob(context.Background(), "checksum", "", catalog.TierHot, catalog.TierCold, func() (bool, error) {
+		t.Fatalf("commit without cold storage")
+		return false, nil
+	})
+	if !errors.Is(err, errNoColdStorage) {
+		t.Fatalf("got %v, want %v", err, errNoColdStorage)
+	}
+}
+
+// TestOpenBlobFallback читает blob из другого хранилища, если каталог и данные разошлись
+func TestOpenBlobFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		tier   string
+		inCold bool
+	}{
+		{name: "catalog says hot, blob is cold", tier: catalog.TierHot, inCold: true},
+		{name: "catalog says cold, blob is hot", tier: catalog.TierCold, inCold: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hot, cold := newTierTestService(t)
+			store := hot
+			if tt.inCold {
+				store = cold
+			}
+			putBlob(t, store, "key", []byte("data"))
+
+			rc, err := s.openBlob(context.Background(), "key", catalog.StoredBlob{Tier: tt.tier}, 1, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "at" {
+				t.Fatalf("got %q, want %q", got, "at")
+			}
+
+			info, err := s.statBlob(context.Background(), "key", tt.tier)
+			if err != nil || info.Size != 4 {
+				t.Fatalf("stat: %+v %v", info, err)
+			}
+		})
+	}
+}
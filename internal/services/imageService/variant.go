@@ -0,0 +1,146 @@
+package imageService
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/storage/catalog"
+)
+
+// StoreVariant кладет в хранилище data - вариант variant.Name blob-а variant.Checksum - и вызывает commit
+// (запись в каталоге) с заполненными ключом, размерами и sha256. Вариант зашифрованного blob-а (stored - его
+// сохраненный вид) шифруется ключом, выведенным из ключа данных blob-а и новой случайной соли variant.KeySalt,
+// так что ротация мастер-ключа его не касается, а перегенерированный вариант не шифруется прежним ключом.
+// Если commit вернул false, blob-а уже нет, и вариант удаляется. Все происходит под локом blob-а,
+// чтобы не разойтись с RemoveBlob
+func (s *ImageService) StoreVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, data []byte, commit func(variant catalog.Variant) (bool, error)) error {
+	op := "internal.service.ImageService.StoreVariant"
+
+	variant.Path = VariantKey(variant.Checksum, variant.Name)
+	variant.Size = int64(len(data))
+	variant.KeySalt = nil
+	if stored.KeyID != "" {
+		variant.KeySalt = make([]byte, variantSaltSize)
+		if _, err := rand.Read(variant.KeySalt); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	dir := filepath.Join(s.saveDir, stagingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	tmp, err := os.CreateTemp(dir, "variant-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = s.writeVariant(tmp, stored, variant, data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	variant.StoredSize = info.Size()
+	if variant.StoredChecksum, err = fileChecksum(tmp.Name()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	unlock, err := s.lockBlob(ctx, s.BlobKey(variant.Checksum))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer unlock()
+
+	// RemoveOrphan берет лок по ключу самого варианта: пока вариант не записан в каталог,
+	// его содержимое нельзя принять за orphan
+	unlockVariant, err := s.lockBlob(ctx, variant.Path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer unlockVariant()
+
+	if err := putFile(ctx, s.blobs, variant.Path, tmp.Name()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := commit(variant)
+	if err != nil || !saved {
+		if err := s.blobs.Delete(ctx, variant.Path); err != nil {
+			s.log.Errorf("Failed to remove variant %s: %v %s", variant.Path, err, op)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *ImageService) writeVariant(w io.Writer, stored catalog.StoredBlob, variant catalog.Variant, data []byte) error {
+	if stored.KeyID == "" {
+		_, err := w.Write(data)
+		return err
+	}
+
+	dataKey, err := s.variantKey(stored, variant)
+	if err != nil {
+		return err
+	}
+	writer, err := encryption.NewWriter(dataKey, w)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// ReadVariant открывает length байт варианта начиная с offset, length == 0 - до конца.
+// stored - сохраненный вид blob-а, из которого построен вариант
+func (s *ImageService) ReadVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, offset int64, length int64) (io.ReadCloser, error) {
+	// Варианты всегда в основном хранилище
+	variantStored := catalog.StoredBlob{StoredSize: variant.StoredSize, StoredChecksum: variant.StoredChecksum, Tier: catalog.TierHot}
+	if stored.KeyID == "" {
+		return s.openBlob(ctx, variant.Path, variantStored, offset, length)
+	}
+
+	dataKey, err := s.variantKey(stored, variant)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptRange(ctx, variant.Path, variantStored, dataKey, offset, length)
+}
+
+// variantSaltSize - размер случайной соли ключа варианта
+const variantSaltSize = 16
+
+// variantKey - ключ, которым шифруется вариант зашифрованного blob-а. Номера кадров служат nonce,
+// поэтому у каждой версии варианта своя соль. Варианты без соли созданы до ее появления
+func (s *ImageService) variantKey(stored catalog.StoredBlob, variant catalog.Variant) ([]byte, error) {
+	if s.keys == nil {
+		return nil, errNoKeys
+	}
+
+	dataKey, err := s.keys.Unwrap(stored.KeyID, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	label := "variant/" + variant.Name
+	if len(variant.KeySalt) > 0 {
+		label += "/" + hex.EncodeToString(variant.KeySalt)
+	}
+	return encryption.DeriveKey(dataKey, label), nil
+}
@@ -0,0 +1,179 @@
+package imageService
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"imagestorage/internal/encryption"
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newVariantTestService - сервис с мастер-ключом и зашифрованный им blob
+func newVariantTestService(t *testing.T) (*ImageService, blob.Store, catalog.StoredBlob) {
+	t.Helper()
+
+	raw := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	master, err := encryption.ParseMasterKey(base64.StdEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := encryption.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := master.Wrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	hot := blob.NewFileStore(filepath.Join(dir, "hot"))
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	s := NewImageService(log, dir, hot, nil, encryption.NewKeyring(master), nil, nil, nil)
+	return s, hot, catalog.StoredBlob{KeyID: master.ID(), WrappedKey: wrapped}
+}
+
+func readVariant(t *testing.T, s *ImageService, stored catalog.StoredBlob, variant catalog.Variant, offset, length int64) ([]byte, error) {
+	t.Helper()
+
+	rc, err := s.ReadVariant(context.Background(), stored, variant, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func TestStoreVariant(t *testing.T) {
+	data := bytes.Repeat([]byte("thumbnail bytes "), 5000)
+	errCommit := errors.New("catalog is down")
+
+	tests := []struct {
+		name      string
+		encrypted bool
+		saved     bool
+		commitErr error
+	}{
+		{name: "plain", saved: true},
+		{name: "encrypted", encrypted: true, saved: true},
+		// Blob успели удалить: вариант без записи не должен остаться в хранилище
+		{name: "blob gone", saved: false},
+		{name: "encrypted blob gone", encrypted: true, saved: false},
+		{name: "commit failed", commitErr: errCommit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hot, stored := newVariantTestService(t)
+			if !tt.encrypted {
+				stored = catalog.StoredBlob{}
+			}
+
+			var committed catalog.Variant
+			variant := catalog.Variant{Checksum: "0123abcd", Name: "thumb_64"}
+			err := s.StoreVariant(context.Background(), stored, variant, data, func(v catalog.Variant) (bool, error) {
+				committed = v
+				// Вариант уже лежит в хранилище, когда пишется в каталог
+				if !hasBlob(t, hot, v.Path) {
+					t.Fatalf("variant committed before it is stored")
+				}
+				return tt.saved, tt.commitErr
+			})
+			if !errors.Is(err, tt.commitErr) {
+				t.Fatalf("got %v, want %v", err, tt.commitErr)
+			}
+
+			if committed.Path != VariantKey(variant.Checksum, variant.Name) || committed.Size != int64(len(data)) {
+				t.Fatalf("committed %+v", committed)
+			}
+			if got := len(committed.KeySalt) > 0; got != tt.encrypted {
+				t.Fatalf("key salt %x for encrypted %v", committed.KeySalt, tt.encrypted)
+			}
+			if !tt.saved || tt.commitErr != nil {
+				if hasBlob(t, hot, committed.Path) {
+					t.Fatalf("variant without a catalog record is kept")
+				}
+				return
+			}
+
+			info, err := hot.Stat(context.Background(), committed.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size != committed.StoredSize || committed.StoredChecksum == "" {
+				t.Fatalf("stored %d bytes, committed %+v", info.Size, committed)
+			}
+			if raw := readStored(t, hot, committed.Path); (raw == string(data)) == tt.encrypted {
+				t.Fatalf("stored content is plain text: %v", raw == string(data))
+			}
+
+			got, err := readVariant(t, s, stored, committed, 0, 0)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("read back %d bytes, %v", len(got), err)
+			}
+			got, err = readVariant(t, s, stored, committed, 70000, 100)
+			if err != nil || !bytes.Equal(got, data[70000:70100]) {
+				t.Fatalf("read back range: %d bytes, %v", len(got), err)
+			}
+		})
+	}
+}
+
+// Перегенерированный вариант шифруется новым ключом: его нельзя расшифровать солью прежней версии,
+// поэтому номера кадров не повторяются под одним ключом
+func TestStoreVariantNewSalt(t *testing.T) {
+	s, _, stored := newVariantTestService(t)
+	variant := catalog.Variant{Checksum: "0123abcd", Name: "thumb_64"}
+
+	store := func(data []byte) catalog.Variant {
+		var committed catalog.Variant
+		err := s.StoreVariant(context.Background(), stored, variant, data, func(v catalog.Variant) (bool, error) {
+			committed = v
+			return true, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return committed
+	}
+
+	first := store([]byte("first thumbnail"))
+	second := store([]byte("second thumbnail"))
+	if bytes.Equal(first.KeySalt, second.KeySalt) {
+		t.Fatalf("regenerated variant reuses salt %x", first.KeySalt)
+	}
+	if first.Path != second.Path {
+		t.Fatalf("variant moved from %s to %s", first.Path, second.Path)
+	}
+
+	got, err := readVariant(t, s, stored, second, 0, 0)
+	if err != nil || string(got) != "second thumbnail" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	// Запись прежней версии указывает на тот же путь, но ее ключ к новому содержимому не подходит
+	stale := first
+	stale.StoredSize, stale.StoredChecksum = second.StoredSize, second.StoredChecksum
+	if _, err := readVariant(t, s, stored, stale, 0, 0); !errors.Is(err, encryption.ErrCorrupted) {
+		t.Fatalf("read with the previous salt: %v, want %v", err, encryption.ErrCorrupted)
+	}
+
+	// Без мастер-ключа вариант зашифрованного blob-а не читается
+	s.keys = nil
+	if _, err := readVariant(t, s, stored, second, 0, 0); !errors.Is(err, errNoKeys) {
+		t.Fatalf("read without keys: %v, want %v", err, errNoKeys)
+	}
+}
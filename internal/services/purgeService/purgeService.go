@@ -0,0 +1,146 @@
+package purgeService
+
+import (
+	"context"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Storage interface {
+	ListDeletedFiles(before time.Time, fileName string) ([]catalog.DeletedFile, error)
+	PurgeFile(id int64, checksum string, before time.Time) (bool, bool, error)
+	ListBlobVariants(checksum string) ([]catalog.Variant, error)
+	DeleteExpiredUploadSessions(before time.Time) ([]string, error)
+}
+
+type BlobRemover interface {
+	RemoveBlob(checksum string, release func() (bool, []string, error)) error
+	RemoveLegacyFile(path string) error
+	DeleteFile(log *logrus.Logger, stageName string, success bool)
+}
+
+// PurgeService окончательно удаляет помеченные файлы после срока хранения
+// и закрывает брошенные сессии загрузки
+type PurgeService struct {
+	log         *logrus.Logger
+	storage     Storage
+	blobs       BlobRemover
+	gracePeriod time.Duration
+	sessionTTL  time.Duration
+}
+
+func NewPurgeService(log *logrus.Logger, storage Storage, blobs BlobRemover, gracePeriod time.Duration, sessionTTL time.Duration) *PurgeService {
+	return &PurgeService{
+		log:         log,
+		storage:     storage,
+		blobs:       blobs,
+		gracePeriod: gracePeriod,
+		sessionTTL:  sessionTTL,
+	}
+}
+
+// ExpireUploadSessions удаляет сессии загрузки, которые не обновлялись дольше sessionTTL, вместе с их
+// staging-файлами. Пока сессия есть, новая загрузка файла с тем же именем отклоняется
+func (s *PurgeService) ExpireUploadSessions() (int, error) {
+	op := "internal.service.PurgeService.ExpireUploadSessions"
+
+	expired, err := s.storage.DeleteExpiredUploadSessions(time.Now().Add(-s.sessionTTL))
+	if err != nil {
+		s.log.Errorf("Failed to expire upload sessions: %v %s", err, op)
+		return 0, err
+	}
+
+	for _, sessionID := range expired {
+		s.blobs.DeleteFile(s.log, sessionID, false)
+		s.log.Infof("Expired upload session %s", sessionID)
+	}
+
+	return len(expired), nil
+}
+
+// Purge удаляет записи и данные файлов, удаленных раньше срока хранения.
+// Пустой fileName - все файлы, force - не дожидаться срока хранения
+func (s *PurgeService) Purge(fileName string, force bool) (int64, error) {
+	op := "internal.service.PurgeService.Purge"
+
+	before := time.Now().Add(-s.gracePeriod)
+	if force {
+		before = time.Now()
+	}
+
+	files, err := s.storage.ListDeletedFiles(before, fileName)
+	if err != nil {
+		s.log.Errorf("Failed to list deleted files: %v %s", err, op)
+		return 0, err
+	}
+
+	var purged int64
+	for _, file := range files {
+		removed := false
+		release := func() (bool, error) {
+			var lastRef bool
+			var err error
+			removed, lastRef, err = s.storage.PurgeFile(file.ID, file.Checksum, before)
+			return lastRef, err
+		}
+
+		if file.Checksum != "" {
+			err = s.blobs.RemoveBlob(file.Checksum, func() (bool, []string, error) {
+				// Варианты читаются до PurgeFile: с последней ссылкой их записи удаляются вместе с blob-ом
+				variants, err := s.storage.ListBlobVariants(file.Checksum)
+				if err != nil {
+					return false, nil, err
+				}
+				var keys []string
+				for _, variant := range variants {
+					keys = append(keys, variant.Path)
+				}
+
+				lastRef, err := release()
+				return lastRef, keys, err
+			})
+		} else {
+			// файл загружен до появления blob-ов и лежит отдельно по path_to_file
+			_, err = release()
+			if err == nil && removed {
+				if err := s.blobs.RemoveLegacyFile(file.Path); err != nil {
+					s.log.Errorf("Failed to remove file %s: %v %s", file.FileName, err, op)
+				}
+			}
+		}
+		if err != nil {
+			s.log.Errorf("Failed to purge %s version %d: %v %s", file.FileName, file.Version, err, op)
+			return purged, err
+		}
+
+		if removed {
+			purged++
+			s.log.Infof("Purged %s version %d", file.FileName, file.Version)
+		}
+	}
+
+	return purged, nil
+}
+
+// Run вызывает Purge и ExpireUploadSessions раз в interval, пока не отменен ctx
+func (s *PurgeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Purge("", false); err != nil {
+				s.log.Errorf("background purge failed: %v", err)
+			}
+			if _, err := s.ExpireUploadSessions(); err != nil {
+				s.log.Errorf("background upload session expiry failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package purgeService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/bolt"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestService(t *testing.T, gracePeriod time.Duration) (*PurgeService, *bolt.Storage, blob.Store) {
+	t.Helper()
+
+	dir := t.TempDir()
+	st, err := bolt.New(filepath.Join(dir, "catalog.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	store := blob.NewFileStore(filepath.Join(dir, "images"))
+	images := imageService.NewImageService(log, filepath.Join(dir, "images"), store, nil, nil, st, nil, st)
+	return NewPurgeService(log, st, images, gracePeriod, time.Hour), st, store
+}
+
+// TestPurgeReleasesBlob удаляет содержимое из хранилища только с последней ссылкой на blob
+func TestPurgeReleasesBlob(t *testing.T) {
+	data := []byte("content shared by two files")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	key := imageService.BlobKey(checksum)
+
+	service, st, store := newTestService(t, time.Hour)
+	if err := store.Put(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.png", "b.png"} {
+		stored := catalog.StoredBlob{StoredSize: int64(len(data))}
+		if _, _, err := st.SaveImage(name, "", key, len(data), "image/png", "", checksum, stored, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name     string
+		delete   string
+		force    bool
+		purged   int64
+		wantBlob bool
+	}{
+		{name: "within grace period", delete: "a.png", purged: 0, wantBlob: true},
+		{name: "one of two references", force: true, purged: 1, wantBlob: true},
+		{name: "last reference", delete: "b.png", force: true, purged: 1, wantBlob: false},
+		{name: "nothing left", force: true, purged: 0, wantBlob: false},
+	}
+	for _, step := range steps {
+		if step.delete != "" {
+			if _, err := st.SoftDeleteFile(step.delete, 0); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+
+		purged, err := service.Purge("", step.force)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if purged != step.purged {
+			t.Fatalf("%s: purged %d, want %d", step.name, purged, step.purged)
+		}
+
+		_, err = store.Stat(context.Background(), key)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			t.Fatal(err)
+		}
+		if (err == nil) != step.wantBlob {
+			t.Fatalf("%s: blob stored %v, want %v", step.name, err == nil, step.wantBlob)
+		}
+	}
+
+	// Blob отпущен в каталоге: то же содержимое снова заводит его запись
+	_, created, err := st.SaveImage("c.png", "", key, len(data), "image/png", "", checksum, catalog.StoredBlob{StoredSize: int64(len(data))}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatalf("blob row is left after the last reference was purged")
+	}
+}
@@ -0,0 +1,382 @@
+package reconcileService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Виды расхождений каталога и хранилища
+const (
+	// ProblemOrphan - blob в хранилище, на который не ссылается ни одна запись files или вариант
+	ProblemOrphan = "orphan"
+	// ProblemMissing - запись files или вариант, содержимого которых нет ни в одном хранилище
+	ProblemMissing = "missing"
+	// ProblemSizeMismatch - размер blob-а не совпадает с files.stored_size
+	ProblemSizeMismatch = "size_mismatch"
+	// ProblemChecksumMismatch - sha256 blob-а не совпадает с blobs.stored_checksum
+	ProblemChecksumMismatch = "checksum_mismatch"
+	// ProblemUnreadable - blob есть, но прочитать его не удалось
+	ProblemUnreadable = "unreadable"
+)
+
+type Storage interface {
+	ListFileLocations() ([]catalog.FileLocation, error)
+	HasFilePath(path string) (bool, error)
+	SoftDeleteFile(fileName string, version int64) (int64, error)
+	ListBlobVariants(checksum string) ([]catalog.Variant, error)
+	DeleteBlobVariant(checksum string, name string) error
+}
+
+type Blobs interface {
+	ListBlobs(ctx context.Context, fn func(tier string, key string, info blob.Info) error) error
+	StatStoredBlob(ctx context.Context, key string, tier string) (blob.Info, error)
+	ChecksumStoredBlob(ctx context.Context, key string, stored catalog.StoredBlob) (string, error)
+	RemoveOrphan(ctx context.Context, tier string, key string, isOrphan func() (bool, error)) (bool, error)
+	ForgetMissing(ctx context.Context, key string, tier string, forget func() error) (bool, error)
+}
+
+// Problem - расхождение каталога и хранилища. Files - записи files или вариант, которых оно касается, пусто у orphan
+type Problem struct {
+	Kind   string
+	Key    string
+	Tier   string
+	Files  []string
+	Detail string
+	// Fixed - исправлено в этом проходе
+	Fixed bool
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s %s (%s)", p.Kind, p.Key, p.Tier)
+	if len(p.Files) > 0 {
+		s += fmt.Sprintf(" files %v", p.Files)
+	}
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	if p.Fixed {
+		s += " [fixed]"
+	}
+	return s
+}
+
+type Report struct {
+	// Blobs - сколько blob-ов нашлось в хранилищах, Paths - на сколько разных ключей ссылается каталог
+	Blobs    int
+	Paths    int
+	Problems []Problem
+}
+
+// Unfixed - сколько расхождений осталось
+func (r Report) Unfixed() int {
+	n := 0
+	for _, problem := range r.Problems {
+		if !problem.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+type Options struct {
+	// Fix удаляет старые orphan-ы, помечает удаленными записи без содержимого и удаляет записи о вариантах без него.
+	// Расхождения размера и checksum только попадают в отчет
+	Fix bool
+	// Verify читает каждый blob и сверяет его sha256 с каталогом
+	Verify bool
+}
+
+// ReconcileService сверяет каталог файлов с содержимым хранилищ blob-ов
+type ReconcileService struct {
+	log     *logrus.Logger
+	storage Storage
+	blobs   Blobs
+	// orphanAge - blob без записи моложе этого не считается orphan-ом: его загрузка могла еще не завершиться
+	orphanAge time.Duration
+}
+
+func NewReconcileService(log *logrus.Logger, storage Storage, blobs Blobs, orphanAge time.Duration) *ReconcileService {
+	return &ReconcileService{
+		log:       log,
+		storage:   storage,
+		blobs:     blobs,
+		orphanAge: orphanAge,
+	}
+}
+
+type storedBlob struct {
+	tier string
+	info blob.Info
+}
+
+// Reconcile сверяет каталог с хранилищами и возвращает найденные расхождения
+func (s *ReconcileService) Reconcile(ctx context.Context, opts Options) (Report, error) {
+	op := "internal.service.ReconcileService.Reconcile"
+
+	// Хранилище перечисляется раньше каталога: blob кладется в хранилище после записи в каталоге,
+	// поэтому у всего, что попало в перечисление, запись уже будет видна. Обратное неверно: запись
+	// может быть видна раньше содержимого, поэтому отсутствующее перепроверяется перед исправлением
+	stored := make(map[string][]storedBlob)
+	var report Report
+	err := s.blobs.ListBlobs(ctx, func(tier string, key string, info blob.Info) error {
+		stored[key] = append(stored[key], storedBlob{tier: tier, info: info})
+		report.Blobs++
+		return nil
+	})
+	if err != nil {
+		s.log.Errorf("Failed to list blobs: %v %s", err, op)
+		return Report{}, err
+	}
+
+	locations, err := s.storage.ListFileLocations()
+	if err != nil {
+		s.log.Errorf("Failed to list file locations: %v %s", err, op)
+		return Report{}, err
+	}
+
+	byPath := make(map[string][]catalog.FileLocation)
+	for _, location := range locations {
+		byPath[location.Path] = append(byPath[location.Path], location)
+	}
+
+	variants, err := s.storage.ListBlobVariants("")
+	if err != nil {
+		s.log.Errorf("Failed to list variants: %v %s", err, op)
+		return Report{}, err
+	}
+	variantPaths := make(map[string]catalog.Variant, len(variants))
+	for _, variant := range variants {
+		variantPaths[variant.Path] = variant
+	}
+	report.Paths = len(byPath) + len(variantPaths)
+
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		problem, err := s.checkPath(ctx, byPath[path], stored[path], opts)
+		if err != nil {
+			s.log.Errorf("Failed to check %s: %v %s", path, err, op)
+			return report, err
+		}
+		if problem != nil {
+			report.Problems = append(report.Problems, *problem)
+		}
+	}
+
+	for _, variant := range variants {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		problem, err := s.checkVariant(ctx, variant, stored[variant.Path], opts)
+		if err != nil {
+			s.log.Errorf("Failed to check %s: %v %s", variant.Path, err, op)
+			return report, err
+		}
+		if problem != nil {
+			report.Problems = append(report.Problems, *problem)
+		}
+	}
+
+	orphans, err := s.orphans(ctx, stored, func(key string) bool {
+		_, file := byPath[key]
+		_, variant := variantPaths[key]
+		return file || variant
+	}, opts)
+	report.Problems = append(report.Problems, orphans...)
+	if err != nil {
+		s.log.Errorf("Failed to remove orphans: %v %s", err, op)
+		return report, err
+	}
+
+	for _, problem := range report.Problems {
+		s.log.Warnf("Reconcile: %s", problem)
+	}
+	s.log.Infof("Reconciled %d blobs and %d catalog paths: %d problems, %d left", report.Blobs, report.Paths,
+		len(report.Problems), report.Unfixed())
+
+	return report, nil
+}
+
+// checkPath проверяет содержимое, на которое ссылаются записи locations с одним path_to_file
+func (s *ReconcileService) checkPath(ctx context.Context, locations []catalog.FileLocation, copies []storedBlob, opts Options) (*Problem, error) {
+	location := locations[0]
+	problem := &Problem{Key: location.Path, Tier: location.Tier}
+	for _, l := range locations {
+		problem.Files = append(problem.Files, fmt.Sprintf("%s v%d", l.FileName, l.Version))
+	}
+
+	err := s.inspect(ctx, problem, location.StoredBlob, copies, opts)
+	if err != nil || problem.Kind == "" {
+		return nil, err
+	}
+	if problem.Kind == ProblemMissing && opts.Fix {
+		return s.fixMissing(ctx, problem, location.Tier, locations)
+	}
+	return problem, nil
+}
+
+// checkVariant проверяет содержимое варианта blob-а. Вариант без содержимого при Fix забывается:
+// его построят заново при следующей загрузке того же содержимого
+func (s *ReconcileService) checkVariant(ctx context.Context, variant catalog.Variant, copies []storedBlob, opts Options) (*Problem, error) {
+	problem := &Problem{Key: variant.Path, Tier: catalog.TierHot,
+		Files: []string{fmt.Sprintf("variant %s of %s", variant.Name, variant.Checksum)}}
+
+	stored := catalog.StoredBlob{StoredSize: variant.StoredSize, StoredChecksum: variant.StoredChecksum, Tier: catalog.TierHot}
+	err := s.inspect(ctx, problem, stored, copies, opts)
+	if err != nil || problem.Kind == "" {
+		return nil, err
+	}
+	if problem.Kind == ProblemMissing && opts.Fix {
+		if err := s.storage.DeleteBlobVariant(variant.Checksum, variant.Name); err != nil {
+			return problem, err
+		}
+		problem.Fixed = true
+	}
+	return problem, nil
+}
+
+// inspect сверяет найденные в хранилище копии copies по ключу problem.Key с каталогом и заполняет
+// problem.Kind и problem.Detail. Пустой Kind - расхождений нет
+func (s *ReconcileService) inspect(ctx context.Context, problem *Problem, stored catalog.StoredBlob, copies []storedBlob, opts Options) error {
+	var info blob.Info
+	var found bool
+	for _, c := range copies {
+		if c.tier == stored.Tier {
+			info, found = c.info, true
+		}
+	}
+	if !found && len(copies) > 0 {
+		info, found = copies[0].info, true
+	}
+	if !found {
+		// Blob мог появиться после перечисления хранилища
+		var err error
+		info, err = s.blobs.StatStoredBlob(ctx, problem.Key, stored.Tier)
+		if errors.Is(err, blob.ErrNotFound) {
+			problem.Kind = ProblemMissing
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if info.Size != stored.StoredSize {
+		problem.Kind = ProblemSizeMismatch
+		problem.Detail = fmt.Sprintf("stored %d bytes, catalog says %d", info.Size, stored.StoredSize)
+		return nil
+	}
+
+	if !opts.Verify || stored.StoredChecksum == "" {
+		return nil
+	}
+
+	sum, err := s.blobs.ChecksumStoredBlob(ctx, problem.Key, stored)
+	if err != nil {
+		problem.Kind = ProblemUnreadable
+		problem.Detail = err.Error()
+		return nil
+	}
+	if sum != stored.StoredChecksum {
+		problem.Kind = ProblemChecksumMismatch
+		problem.Detail = fmt.Sprintf("got %s, catalog says %s", sum, stored.StoredChecksum)
+	}
+
+	return nil
+}
+
+// fixMissing помечает удаленными записи без содержимого, дальше их убирает purge. Если содержимое
+// появилось, пока шла сверка (загрузка записала каталог, но еще не положила blob), это не расхождение
+func (s *ReconcileService) fixMissing(ctx context.Context, problem *Problem, tier string, locations []catalog.FileLocation) (*Problem, error) {
+	missing, err := s.blobs.ForgetMissing(ctx, problem.Key, tier, func() error {
+		for _, location := range locations {
+			if _, err := s.storage.SoftDeleteFile(location.FileName, location.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return problem, err
+	}
+	if !missing {
+		return nil, nil
+	}
+	problem.Fixed = true
+	return problem, nil
+}
+
+func (s *ReconcileService) orphans(ctx context.Context, stored map[string][]storedBlob, referenced func(key string) bool, opts Options) ([]Problem, error) {
+	keys := make([]string, 0, len(stored))
+	for key := range stored {
+		if !referenced(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	cutoff := time.Now().Add(-s.orphanAge)
+
+	var problems []Problem
+	for _, key := range keys {
+		for _, c := range stored[key] {
+			if c.info.ModTime.After(cutoff) {
+				continue
+			}
+
+			problem := Problem{Kind: ProblemOrphan, Key: key, Tier: c.tier,
+				Detail: fmt.Sprintf("%d bytes, modified %s", c.info.Size, c.info.ModTime.UTC().Format(time.DateTime))}
+			if opts.Fix {
+				removed, err := s.blobs.RemoveOrphan(ctx, c.tier, key, func() (bool, error) {
+					referenced, err := s.storage.HasFilePath(key)
+					return !referenced, err
+				})
+				if err != nil {
+					return problems, err
+				}
+				// Запись появилась после сверки: это не orphan
+				if !removed {
+					continue
+				}
+				problem.Fixed = true
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems, nil
+}
+
+// Run вызывает Reconcile раз в interval, пока не отменен ctx
+func (s *ReconcileService) Run(ctx context.Context, interval time.Duration, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reconcile(ctx, opts); err != nil {
+				s.log.Errorf("background reconcile failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package reconcileService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"imagestorage/internal/services/imageService"
+	"imagestorage/internal/storage/blob"
+	"imagestorage/internal/storage/bolt"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testEnv - каталог bolt и два файловых хранилища, сверяемые через ImageService
+type testEnv struct {
+	st        *bolt.Storage
+	hot, cold blob.Store
+	dirs      map[string]string
+	log       *logrus.Logger
+	images    *imageService.ImageService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	dir := t.TempDir()
+	st, err := bolt.New(filepath.Join(dir, "catalog.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	env := &testEnv{
+		st:   st,
+		dirs: map[string]string{catalog.TierHot: filepath.Join(dir, "hot"), catalog.TierCold: filepath.Join(dir, "cold")},
+		log:  log,
+	}
+	env.hot = blob.NewFileStore(env.dirs[catalog.TierHot])
+	env.cold = blob.NewFileStore(env.dirs[catalog.TierCold])
+	env.images = imageService.NewImageService(log, dir, env.hot, nil, nil, st, env.cold, st)
+	return env
+}
+
+func (e *testEnv) store(tier string) blob.Store {
+	if tier == catalog.TierCold {
+		return e.cold
+	}
+	return e.hot
+}
+
+// put кладет blob в хранилище tier и состаривает его, чтобы он не казался незавершенной загрузкой
+func (e *testEnv) put(t *testing.T, tier string, key string, data []byte) {
+	t.Helper()
+
+	if err := e.store(tier).Put(context.Background(), key, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(e.dirs[tier], filepath.FromSlash(key)), old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addFile записывает в каталог файл с содержимым data, не кладя его в хранилище
+func (e *testEnv) addFile(t *testing.T, fileName string, data []byte) string {
+	t.Helper()
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	key := imageService.BlobKey(checksum)
+	stored := catalog.StoredBlob{StoredSize: int64(len(data)), StoredChecksum: checksum}
+	if _, _, err := e.st.SaveImage(fileName, "", key, len(data), "image/png", "", checksum, stored, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (e *testEnv) has(t *testing.T, tier string, key string) bool {
+	t.Helper()
+
+	_, err := e.store(tier).Stat(context.Background(), key)
+	if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestReconcile(t *testing.T) {
+	content := []byte("image content")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		opts      Options
+		orphanAge time.Duration
+		// setup возвращает ключ, который проверяет check
+		setup func(t *testing.T, e *testEnv) string
+		want  []Problem
+		check func(t *testing.T, e *testEnv, key string)
+	}{
+		{
+			name: "consistent",
+			opts: Options{Fix: true, Verify: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierHot, key, content)
+				return key
+			},
+		},
+		{
+			name: "orphan reported",
+			setup: func(t *testing.T, e *testEnv) string {
+				e.put(t, catalog.TierHot, "blobs/orphan", content)
+				return "blobs/orphan"
+			},
+			want: []Problem{{Kind: ProblemOrphan, Key: "blobs/orphan", Tier: catalog.TierHot}},
+			check: func(t *testing.T, e *testEnv, key string) {
+				if !e.has(t, catalog.TierHot, key) {
+					t.Fatalf("orphan removed without Fix")
+				}
+			},
+		},
+		{
+			name: "orphan removed",
+			opts: Options{Fix: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				e.put(t, catalog.TierCold, "blobs/orphan", content)
+				return "blobs/orphan"
+			},
+			want: []Problem{{Kind: ProblemOrphan, Key: "blobs/orphan", Tier: catalog.TierCold, Fixed: true}},
+			check: func(t *testing.T, e *testEnv, key string) {
+				if e.has(t, catalog.TierCold, key) {
+					t.Fatalf("orphan is still in cold storage")
+				}
+			},
+		},
+		{
+			name:      "young blob is not an orphan",
+			opts:      Options{Fix: true},
+			orphanAge: time.Hour,
+			setup: func(t *testing.T, e *testEnv) string {
+				if err := e.hot.Put(context.Background(), "blobs/uploading", bytes.NewReader(content), int64(len(content))); err != nil {
+					t.Fatal(err)
+				}
+				return "blobs/uploading"
+			},
+			check: func(t *testing.T, e *testEnv, key string) {
+				if !e.has(t, catalog.TierHot, key) {
+					t.Fatalf("blob of an unfinished upload removed")
+				}
+			},
+		},
+		{
+			name: "missing reported",
+			setup: func(t *testing.T, e *testEnv) string {
+				return e.addFile(t, "a.png", content)
+			},
+			want: []Problem{{Kind: ProblemMissing, Key: imageService.BlobKey(checksum), Tier: catalog.TierHot}},
+			check: func(t *testing.T, e *testEnv, key string) {
+				if _, err := e.st.GetFileInfo("a.png", 0); err != nil {
+					t.Fatalf("file deleted without Fix: %v", err)
+				}
+			},
+		},
+		{
+			name: "missing soft-deleted",
+			opts: Options{Fix: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				e.addFile(t, "a.png", content)
+				return e.addFile(t, "b.png", content)
+			},
+			want: []Problem{{Kind: ProblemMissing, Key: imageService.BlobKey(checksum), Tier: catalog.TierHot, Fixed: true,
+				Files: []string{"a.png v1", "b.png v1"}}},
+			check: func(t *testing.T, e *testEnv, key string) {
+				for _, name := range []string{"a.png", "b.png"} {
+					if _, err := e.st.GetFileInfo(name, 0); !errors.Is(err, catalog.ErrFileNotFound) {
+						t.Fatalf("%s: got %v, want %v", name, err, catalog.ErrFileNotFound)
+					}
+				}
+			},
+		},
+		{
+			name: "size mismatch",
+			opts: Options{Fix: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierHot, key, content[1:])
+				return key
+			},
+			want: []Problem{{Kind: ProblemSizeMismatch, Key: imageService.BlobKey(checksum), Tier: catalog.TierHot}},
+		},
+		{
+			name: "checksum mismatch",
+			opts: Options{Verify: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierHot, key, bytes.ToUpper(content))
+				return key
+			},
+			want: []Problem{{Kind: ProblemChecksumMismatch, Key: imageService.BlobKey(checksum), Tier: catalog.TierHot}},
+		},
+		{
+			name: "checksum not read without Verify",
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierHot, key, bytes.ToUpper(content))
+				return key
+			},
+		},
+		{
+			name: "cold blob",
+			opts: Options{Fix: true, Verify: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				if _, err := e.st.MoveBlobTier(checksum, catalog.TierHot, catalog.TierCold, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+				e.put(t, catalog.TierCold, key, content)
+				return key
+			},
+		},
+		// Перенос, прерванный до записи в каталог: blob уже в холодном хранилище, каталог говорит hot
+		{
+			name: "blob in the other tier",
+			opts: Options{Fix: true, Verify: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierCold, key, content)
+				return key
+			},
+		},
+		{
+			name: "missing variant forgotten",
+			opts: Options{Fix: true},
+			setup: func(t *testing.T, e *testEnv) string {
+				key := e.addFile(t, "a.png", content)
+				e.put(t, catalog.TierHot, key, content)
+				variant := catalog.Variant{Checksum: checksum, Name: "thumb_64", Path: "variants/thumb_64", MimeType: "image/png", StoredSize: 5}
+				if _, err := e.st.SaveBlobVariant(variant); err != nil {
+					t.Fatal(err)
+				}
+				return variant.Path
+			},
+			want: []Problem{{Kind: ProblemMissing, Key: "variants/thumb_64", Tier: catalog.TierHot, Fixed: true,
+				Files: []string{"variant thumb_64 of " + checksum}}},
+			check: func(t *testing.T, e *testEnv, key string) {
+				variants, err := e.st.ListBlobVariants(checksum)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(variants) != 0 {
+					t.Fatalf("variant without content kept: %+v", variants)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			key := tt.setup(t, e)
+
+			s := NewReconcileService(e.log, e.st, e.images, tt.orphanAge)
+			report, err := s.Reconcile(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+
+			if len(report.Problems) != len(tt.want) {
+				t.Fatalf("got problems %v, want %d", report.Problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := report.Problems[i]
+				if got.Kind != want.Kind || got.Key != want.Key || got.Tier != want.Tier || got.Fixed != want.Fixed {
+					t.Fatalf("got %s, want %s", got, want)
+				}
+				if want.Files != nil && !slices.Equal(got.Files, want.Files) {
+					t.Fatalf("got files %v, want %v", got.Files, want.Files)
+				}
+			}
+
+			unfixed := 0
+			for _, want := range tt.want {
+				if !want.Fixed {
+					unfixed++
+				}
+			}
+			if report.Unfixed() != unfixed {
+				t.Fatalf("%d problems left, want %d", report.Unfixed(), unfixed)
+			}
+
+			if tt.check != nil {
+				tt.check(t, e, key)
+			}
+		})
+	}
+}
+
+// TestRemoveOrphanRecheck не удаляет blob, на который успела появиться запись
+func TestRemoveOrphanRecheck(t *testing.T) {
+	e := newTestEnv(t)
+	e.put(t, catalog.TierHot, "blobs/late", []byte("late"))
+
+	removed, err := e.images.RemoveOrphan(context.Background(), catalog.TierHot, "blobs/late", func() (bool, error) {
+		return false, nil
+	})
+	if err != nil || removed {
+		t.Fatalf("got %v, %v", removed, err)
+	}
+	if !e.has(t, catalog.TierHot, "blobs/late") {
+		t.Fatalf("referenced blob removed")
+	}
+}
+
+// lockedBlobs сообщает в entered, что reconciler идет исправлять расхождение под локом blob-а
+type lockedBlobs struct {
+	*imageService.ImageService
+	entered chan struct{}
+}
+
+func (b lockedBlobs) enter() {
+	select {
+	case b.entered <- struct{}{}:
+	default:
+	}
+}
+
+func (b lockedBlobs) RemoveOrphan(ctx context.Context, tier string, key string, isOrphan func() (bool, error)) (bool, error) {
+	b.enter()
+	return b.ImageService.RemoveOrphan(ctx, tier, key, isOrphan)
+}
+
+func (b lockedBlobs) ForgetMissing(ctx context.Context, key string, tier string, forget func() error) (bool, error) {
+	b.enter()
+	return b.ImageService.ForgetMissing(ctx, key, tier, forget)
+}
+
+// TestReconcileDuringUpload сверяет каталог, пока загрузка держит лок blob-а: reconciler ждет ее
+// и не трогает ни ее запись, ни ее содержимое
+func TestReconcileDuringUpload(t *testing.T) {
+	content := []byte("image content")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	key := imageService.BlobKey(checksum)
+
+	tests := []struct {
+		name string
+		// orphan - под ключом лежит содержимое без записи, оставшееся от прошлой загрузки
+		orphan bool
+		// committed - reconciler запускается после записи в каталог, еще до того, как blob положен
+		committed bool
+	}{
+		{name: "committed, not stored yet", committed: true},
+		{name: "orphan being replaced", orphan: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			if tt.orphan {
+				e.put(t, catalog.TierHot, key, []byte("stale"))
+			}
+			if err := e.images.DiskSave(context.Background(), "upload-1", content); err != nil {
+				t.Fatal(err)
+			}
+
+			blobs := lockedBlobs{ImageService: e.images, entered: make(chan struct{}, 1)}
+			s := NewReconcileService(e.log, e.st, blobs, 0)
+
+			locked, proceed := make(chan struct{}), make(chan struct{})
+			uploaded := make(chan error, 1)
+			go func() {
+				uploaded <- e.images.StoreBlob("upload-1", checksum, "image/png", func(stored catalog.StoredBlob) (bool, error) {
+					var created bool
+					var err error
+					if tt.committed {
+						_, created, err = e.st.SaveImage("a.png", "", key, len(content), "image/png", "", checksum, stored, time.Now())
+					}
+					close(locked)
+					<-proceed
+					if !tt.committed {
+						_, created, err = e.st.SaveImage("a.png", "", key, len(content), "image/png", "", checksum, stored, time.Now())
+					}
+					return created, err
+				}, func() error { return nil })
+			}()
+
+			<-locked
+			reconciled := make(chan Report, 1)
+			go func() {
+				report, err := s.Reconcile(context.Background(), Options{Fix: true})
+				if err != nil {
+					t.Error(err)
+				}
+				reconciled <- report
+			}()
+
+			select {
+			case <-blobs.entered:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("reconciler found nothing to fix")
+			}
+			close(proceed)
+			if err := <-uploaded; err != nil {
+				t.Fatal(err)
+			}
+
+			report := <-reconciled
+			if len(report.Problems) != 0 {
+				t.Fatalf("got problems %v", report.Problems)
+			}
+			if _, err := e.st.GetFileInfo("a.png", 0); err != nil {
+				t.Fatalf("uploaded file deleted: %v", err)
+			}
+			rc, err := e.images.ReadBlob(context.Background(), key, catalog.StoredBlob{Tier: catalog.TierHot}, 0, 0)
+			if err != nil {
+				t.Fatalf("uploaded blob removed: %v", err)
+			}
+			defer rc.Close()
+			if got, _ := io.ReadAll(rc); !bytes.Equal(got, content) {
+				t.Fatalf("stored %q", got)
+			}
+		})
+	}
+}
+
+// TestReconcileUncommittedVariant не удаляет вариант, который уже положен в хранилище, но еще не записан в каталог
+func TestReconcileUncommittedVariant(t *testing.T) {
+	e := newTestEnv(t)
+	blobs := lockedBlobs{ImageService: e.images, entered: make(chan struct{}, 1)}
+	s := NewReconcileService(e.log, e.st, blobs, 0)
+
+	content := []byte("image content")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	e.put(t, catalog.TierHot, e.addFile(t, "a.png", content), content)
+
+	variant := catalog.Variant{Checksum: checksum, Name: "thumb_64", MimeType: "image/png"}
+	stored, proceed := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- e.images.StoreVariant(context.Background(), catalog.StoredBlob{}, variant, []byte("thumbnail"), func(v catalog.Variant) (bool, error) {
+			variant = v
+			close(stored)
+			<-proceed
+			return e.st.SaveBlobVariant(v)
+		})
+	}()
+
+	<-stored
+	reconciled := make(chan Report, 1)
+	go func() {
+		report, err := s.Reconcile(context.Background(), Options{Fix: true})
+		if err != nil {
+			t.Error(err)
+		}
+		reconciled <- report
+	}()
+
+	select {
+	case <-blobs.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reconciler did not take the variant for an orphan")
+	}
+	close(proceed)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if report := <-reconciled; len(report.Problems) != 0 {
+		t.Fatalf("got problems %v", report.Problems)
+	}
+	if !e.has(t, catalog.TierHot, variant.Path) {
+		t.Fatalf("variant removed before it was committed")
+	}
+}
@@ -0,0 +1,143 @@
+package thumbnailService
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"time"
+
+	"imagestorage/internal/imaging"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Storage interface {
+	FindFileLocation(fileName string, version int64) (catalog.FileLocation, error)
+	ListBlobVariants(checksum string) ([]catalog.Variant, error)
+	SaveBlobVariant(variant catalog.Variant) (bool, error)
+}
+
+type Blobs interface {
+	ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error)
+	StoreVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, data []byte, commit func(variant catalog.Variant) (bool, error)) error
+}
+
+// Name - имя миниатюры размера size, под ним она запрашивается в DownloadRequest.Variant
+func Name(size int) string {
+	return fmt.Sprintf("thumb_%d", size)
+}
+
+// ThumbnailService строит миниатюры загруженных изображений. Миниатюры - варианты blob-а,
+// поэтому у одинакового содержимого они общие
+type ThumbnailService struct {
+	log     *logrus.Logger
+	storage Storage
+	blobs   Blobs
+	// sizes - наибольшая сторона миниатюр, пусто - миниатюры не строятся
+	sizes   []int
+	quality int
+}
+
+func NewThumbnailService(log *logrus.Logger, storage Storage, blobs Blobs, sizes []int, quality int) *ThumbnailService {
+	return &ThumbnailService{
+		log:     log,
+		storage: storage,
+		blobs:   blobs,
+		sizes:   slices.DeleteFunc(slices.Clone(sizes), func(size int) bool { return size <= 0 }),
+		quality: quality,
+	}
+}
+
+// Generate строит миниатюры версии файла, которых еще нет у его blob-а. Файлы не JPEG, PNG или GIF
+// пропускаются. Возвращает число построенных миниатюр
+func (s *ThumbnailService) Generate(ctx context.Context, fileName string, version int64, mimeType string) (int, error) {
+	op := "internal.service.ThumbnailService.Generate"
+
+	if len(s.sizes) == 0 || imaging.FormatOf(mimeType) == "" {
+		return 0, nil
+	}
+
+	location, err := s.storage.FindFileLocation(fileName, version)
+	if err != nil {
+		s.log.Errorf("Failed to find %s: %v %s", fileName, err, op)
+		return 0, err
+	}
+	// У файлов, загруженных до появления blob-ов, вариантов нет
+	if location.Checksum == "" {
+		return 0, nil
+	}
+
+	existing, err := s.storage.ListBlobVariants(location.Checksum)
+	if err != nil {
+		s.log.Errorf("Failed to list variants of %s: %v %s", location.Checksum, err, op)
+		return 0, err
+	}
+
+	var missing []int
+	for _, size := range s.sizes {
+		if !slices.ContainsFunc(existing, func(v catalog.Variant) bool { return v.Name == Name(size) }) {
+			missing = append(missing, size)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	img, format, err := s.decode(ctx, location)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		s.log.Warnf("%s is not a JPEG, PNG or GIF image, no thumbnails %s", fileName, op)
+		return 0, nil
+	}
+	if err != nil {
+		s.log.Errorf("Failed to decode %s: %v %s", fileName, err, op)
+		return 0, err
+	}
+
+	var generated int
+	for _, size := range missing {
+		thumbnail := imaging.Fit(img, size)
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, thumbnail, format, s.quality); err != nil {
+			s.log.Errorf("Failed to encode thumbnail of %s: %v %s", fileName, err, op)
+			return generated, err
+		}
+
+		variant := catalog.Variant{
+			Checksum:  location.Checksum,
+			Name:      Name(size),
+			MimeType:  imaging.MimeType(format),
+			Width:     thumbnail.Bounds().Dx(),
+			Height:    thumbnail.Bounds().Dy(),
+			CreatedAt: time.Now(),
+		}
+		err := s.blobs.StoreVariant(ctx, location.StoredBlob, variant, buf.Bytes(), s.storage.SaveBlobVariant)
+		if err != nil {
+			s.log.Errorf("Failed to store %s of %s: %v %s", variant.Name, fileName, err, op)
+			return generated, err
+		}
+		generated++
+	}
+
+	s.log.Infof("Generated %d thumbnails of %s", generated, fileName)
+	return generated, nil
+}
+
+func (s *ThumbnailService) decode(ctx context.Context, location catalog.FileLocation) (image.Image, string, error) {
+	rc, err := s.blobs.ReadBlob(ctx, location.Path, location.StoredBlob, 0, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, "", err
+	}
+	return imaging.Decode(data)
+}
@@ -0,0 +1,158 @@
+package thumbnailService
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"slices"
+	"testing"
+
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeCatalog - каталог из одного файла и вариантов его blob-а
+type fakeCatalog struct {
+	location catalog.FileLocation
+	variants []catalog.Variant
+}
+
+func (c *fakeCatalog) FindFileLocation(fileName string, version int64) (catalog.FileLocation, error) {
+	if fileName != c.location.FileName {
+		return catalog.FileLocation{}, catalog.ErrFileNotFound
+	}
+	return c.location, nil
+}
+
+func (c *fakeCatalog) ListBlobVariants(checksum string) ([]catalog.Variant, error) {
+	return c.variants, nil
+}
+
+func (c *fakeCatalog) SaveBlobVariant(variant catalog.Variant) (bool, error) {
+	c.variants = append(c.variants, variant)
+	return true, nil
+}
+
+// blobs отдает content как содержимое любого blob-а и запоминает сохраненные варианты
+type blobs struct {
+	content []byte
+	reads   int
+	stored  map[string][]byte
+}
+
+func (b *blobs) ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	b.reads++
+	return io.NopCloser(bytes.NewReader(b.content)), nil
+}
+
+func (b *blobs) StoreVariant(ctx context.Context, stored catalog.StoredBlob, variant catalog.Variant, data []byte, commit func(variant catalog.Variant) (bool, error)) error {
+	if b.stored == nil {
+		b.stored = make(map[string][]byte)
+	}
+	b.stored[variant.Name] = data
+	_, err := commit(variant)
+	return err
+}
+
+func jpegOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestService(t *testing.T, content []byte, sizes []int, existing ...string) (*ThumbnailService, *fakeCatalog, *blobs) {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	c := &fakeCatalog{location: catalog.FileLocation{FileName: "photo.jpg", Checksum: "0123", Path: "blobs/0123"}}
+	for _, name := range existing {
+		c.variants = append(c.variants, catalog.Variant{Checksum: "0123", Name: name})
+	}
+	b := &blobs{content: content}
+	return NewThumbnailService(log, c, b, sizes, 80), c, b
+}
+
+func TestGenerate(t *testing.T) {
+	photo := jpegOf(t, 400, 300)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		mimeType string
+		sizes    []int
+		existing []string
+		// want - имена построенных миниатюр
+		want []string
+		// decoded - читался ли blob
+		decoded bool
+		wantErr bool
+	}{
+		{name: "all sizes", content: photo, mimeType: "image/jpeg", sizes: []int{64, 256}, want: []string{"thumb_64", "thumb_256"}, decoded: true},
+		{name: "existing size skipped", content: photo, mimeType: "image/jpeg", sizes: []int{64, 256}, existing: []string{"thumb_64"}, want: []string{"thumb_256"}, decoded: true},
+		{name: "all sizes exist", content: photo, mimeType: "image/jpeg", sizes: []int{64}, existing: []string{"thumb_64"}},
+		{name: "not an image type", content: photo, mimeType: "application/pdf", sizes: []int{64}},
+		{name: "no sizes", content: photo, mimeType: "image/jpeg", sizes: []int{0, -5}},
+		// Тип взят по первым байтам, но содержимое не декодируется
+		{name: "undecodable", content: []byte("\xff\xd8\xff not really a jpeg"), mimeType: "image/jpeg", sizes: []int{64}, decoded: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c, b := newTestService(t, tt.content, tt.sizes, tt.existing...)
+
+			generated, err := s.Generate(context.Background(), "photo.jpg", 0, tt.mimeType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+			if generated != len(tt.want) {
+				t.Fatalf("generated %d, want %d", generated, len(tt.want))
+			}
+			for _, name := range tt.want {
+				if _, ok := b.stored[name]; !ok {
+					t.Fatalf("%s not stored, got %v", name, b.stored)
+				}
+				if !slices.ContainsFunc(c.variants, func(v catalog.Variant) bool { return v.Name == name }) {
+					t.Fatalf("%s not saved in the catalog", name)
+				}
+			}
+			if len(b.stored) != len(tt.want) {
+				t.Fatalf("stored %d thumbnails, want %d", len(b.stored), len(tt.want))
+			}
+			if (b.reads > 0) != tt.decoded {
+				t.Fatalf("blob read %d times", b.reads)
+			}
+		})
+	}
+}
+
+func TestGenerateVariant(t *testing.T) {
+	s, c, b := newTestService(t, jpegOf(t, 400, 300), []int{100})
+
+	if _, err := s.Generate(context.Background(), "photo.jpg", 0, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	variant := c.variants[0]
+	if variant.Checksum != "0123" || variant.MimeType != "image/jpeg" || variant.Width != 100 || variant.Height != 75 {
+		t.Fatalf("got %+v", variant)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(b.stored["thumb_100"]))
+	if err != nil || format != "jpeg" || config.Width != 100 || config.Height != 75 {
+		t.Fatalf("stored %s %dx%d, %v", format, config.Width, config.Height, err)
+	}
+
+	// Файлы без blob-а пропускаются
+	c.location.Checksum = ""
+	if generated, err := s.Generate(context.Background(), "photo.jpg", 0, "image/jpeg"); err != nil || generated != 0 {
+		t.Fatalf("legacy file: generated %d, %v", generated, err)
+	}
+}
@@ -0,0 +1,153 @@
+package tierService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// demoteBatch - сколько blob-ов Demote берет из каталога за один запрос
+	demoteBatch = 100
+	// touchInterval - не чаще этого время скачивания blob-а пишется в каталог
+	touchInterval = time.Minute
+)
+
+type Storage interface {
+	TouchBlob(checksum string, at time.Time) error
+	ListIdleBlobs(tier string, before time.Time, limit int) ([]catalog.IdleBlob, error)
+	MoveBlobTier(checksum string, from string, to string, at time.Time) (bool, error)
+}
+
+type BlobMover interface {
+	MoveBlob(ctx context.Context, checksum string, storedChecksum string, from string, to string, commit func() (bool, error)) error
+}
+
+// TierService переносит в холодное хранилище blob-ы, которые не скачивали idlePeriod,
+// и возвращает их в основное при скачивании
+type TierService struct {
+	log        *logrus.Logger
+	storage    Storage
+	blobs      BlobMover
+	idlePeriod time.Duration
+	// promoting - blob-ы, которые уже возвращаются в основное хранилище
+	promoting sync.Map
+}
+
+func NewTierService(log *logrus.Logger, storage Storage, blobs BlobMover, idlePeriod time.Duration) *TierService {
+	return &TierService{
+		log:        log,
+		storage:    storage,
+		blobs:      blobs,
+		idlePeriod: idlePeriod,
+	}
+}
+
+// Demote переносит в холодное хранилище blob-ы, которые не скачивали дольше idlePeriod.
+// blob, который не удалось перенести, пропускается до следующего запуска, чтобы он не останавливал
+// перенос остальных. Возвращает число перенесенных и ошибки пропущенных
+func (s *TierService) Demote(ctx context.Context) (int64, error) {
+	op := "internal.service.TierService.Demote"
+
+	before := time.Now().Add(-s.idlePeriod)
+
+	var moved int64
+	var errs []error
+	// Каталог отдает самые давние blob-ы первыми, поэтому неперенесенные каждый раз оказываются
+	// в начале списка: запрос берет на столько больше, а уже обработанные пропускаются
+	attempted := make(map[string]bool)
+	for {
+		limit := demoteBatch + len(errs)
+		blobs, err := s.storage.ListIdleBlobs(catalog.TierHot, before, limit)
+		if err != nil {
+			s.log.Errorf("Failed to list idle blobs: %v %s", err, op)
+			return moved, errors.Join(append(errs, err)...)
+		}
+
+		fresh := 0
+		for _, idle := range blobs {
+			if attempted[idle.Checksum] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return moved, errors.Join(append(errs, err)...)
+			}
+			attempted[idle.Checksum] = true
+			fresh++
+
+			err := s.move(ctx, idle.Checksum, idle.StoredChecksum, catalog.TierHot, catalog.TierCold)
+			if err != nil {
+				s.log.Errorf("Failed to move blob %s to cold storage, skipping it: %v %s", idle.Checksum, err, op)
+				errs = append(errs, fmt.Errorf("blob %s: %w", idle.Checksum, err))
+				continue
+			}
+			moved++
+		}
+
+		if fresh == 0 || len(blobs) < limit {
+			return moved, errors.Join(errs...)
+		}
+	}
+}
+
+func (s *TierService) move(ctx context.Context, checksum string, storedChecksum string, from string, to string) error {
+	return s.blobs.MoveBlob(ctx, checksum, storedChecksum, from, to, func() (bool, error) {
+		return s.storage.MoveBlobTier(checksum, from, to, time.Now())
+	})
+}
+
+// Accessed отмечает скачивание файла. Время скачивания пишется в каталог не чаще touchInterval,
+// а blob из холодного хранилища возвращается в основное в фоне, ответ клиенту его не ждет
+func (s *TierService) Accessed(location catalog.FileLocation) {
+	op := "internal.service.TierService.Accessed"
+
+	if location.Checksum == "" {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(location.LastAccessedAt) >= touchInterval {
+		if err := s.storage.TouchBlob(location.Checksum, now); err != nil {
+			s.log.Errorf("Failed to record access to %s: %v %s", location.Checksum, err, op)
+		}
+	}
+
+	if location.Tier != catalog.TierCold {
+		return
+	}
+	if _, busy := s.promoting.LoadOrStore(location.Checksum, struct{}{}); busy {
+		return
+	}
+
+	go func() {
+		defer s.promoting.Delete(location.Checksum)
+
+		err := s.move(context.Background(), location.Checksum, location.StoredChecksum, catalog.TierCold, catalog.TierHot)
+		if err != nil {
+			s.log.Errorf("Failed to move blob %s back from cold storage: %v %s", location.Checksum, err, op)
+		}
+	}()
+}
+
+// Run вызывает Demote раз в interval, пока не отменен ctx
+func (s *TierService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Demote(ctx); err != nil {
+				s.log.Errorf("background tier demotion failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package tierService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeCatalog - каталог blob-ов в памяти: tier и время последнего скачивания
+type fakeCatalog struct {
+	mu       sync.Mutex
+	tiers    map[string]string
+	accessed map[string]time.Time
+	listErr  error
+}
+
+func newFakeCatalog(blobs int, lastAccess time.Time) *fakeCatalog {
+	c := &fakeCatalog{tiers: make(map[string]string), accessed: make(map[string]time.Time)}
+	for i := 0; i < blobs; i++ {
+		checksum := fmt.Sprintf("%064x", i)
+		c.tiers[checksum] = catalog.TierHot
+		c.accessed[checksum] = lastAccess.Add(time.Duration(i) * time.Second)
+	}
+	return c
+}
+
+func (c *fakeCatalog) TouchBlob(checksum string, at time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessed[checksum] = at
+	return nil
+}
+
+func (c *fakeCatalog) ListIdleBlobs(tier string, before time.Time, limit int) ([]catalog.IdleBlob, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+	var idle []catalog.IdleBlob
+	for checksum, t := range c.tiers {
+		if t == tier && c.accessed[checksum].Before(before) {
+			idle = append(idle, catalog.IdleBlob{Checksum: checksum, LastAccessedAt: c.accessed[checksum]})
+		}
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].LastAccessedAt.Before(idle[j].LastAccessedAt) })
+	if len(idle) > limit {
+		idle = idle[:limit]
+	}
+	return idle, nil
+}
+
+func (c *fakeCatalog) MoveBlobTier(checksum string, from string, to string, at time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tiers[checksum] != from {
+		return false, nil
+	}
+	c.tiers[checksum] = to
+	return true, nil
+}
+
+func (c *fakeCatalog) count(tier string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.tiers {
+		if t == tier {
+			n++
+		}
+	}
+	return n
+}
+
+// fakeMover переносит blob, только записывая перенос, и отказывает blob-ам из failing
+type fakeMover struct {
+	mu      sync.Mutex
+	failing map[string]bool
+	calls   map[string]int
+	moved   chan string
+}
+
+var errMove = errors.New("cold storage is unavailable")
+
+func (m *fakeMover) MoveBlob(ctx context.Context, checksum string, storedChecksum string, from string, to string, commit func() (bool, error)) error {
+	m.mu.Lock()
+	m.calls[checksum]++
+	failing := m.failing[checksum]
+	m.mu.Unlock()
+
+	if failing {
+		return errMove
+	}
+	_, err := commit()
+	if m.moved != nil {
+		m.moved <- checksum
+	}
+	return err
+}
+
+func newTestTierService(fake *fakeCatalog, failing ...int) (*TierService, *fakeMover) {
+	mover := &fakeMover{failing: make(map[string]bool), calls: make(map[string]int)}
+	for _, i := range failing {
+		mover.failing[fmt.Sprintf("%064x", i)] = true
+	}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewTierService(log, fake, mover, time.Hour), mover
+}
+
+func TestDemote(t *testing.T) {
+	old := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		blobs     int
+		fresh     int
+		failing   []int
+		wantMoved int64
+	}{
+		{name: "nothing idle", blobs: 0},
+		{name: "one batch", blobs: 10, wantMoved: 10},
+		{name: "several batches", blobs: 2*demoteBatch + 7, wantMoved: 2*demoteBatch + 7},
+		{name: "recently accessed stay", blobs: 10, fresh: 4, wantMoved: 6},
+		{name: "failures are skipped", blobs: 10, failing: []int{0, 3}, wantMoved: 8},
+		// Неперенесенные остаются в начале списка и не должны вытеснять остальные
+		{name: "failures fill a batch", blobs: 2*demoteBatch + 5, failing: []int{0, 1, 2, 3, 4, 150}, wantMoved: 2*demoteBatch - 1},
+		{name: "everything fails", blobs: demoteBatch + 1, failing: func() []int {
+			all := make([]int, demoteBatch+1)
+			for i := range all {
+				all[i] = i
+			}
+			return all
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeCatalog(tt.blobs, old)
+			for i := 0; i < tt.fresh; i++ {
+				fake.accessed[fmt.Sprintf("%064x", i)] = time.Now()
+			}
+			s, mover := newTestTierService(fake, tt.failing...)
+
+			moved, err := s.Demote(context.Background())
+			if moved != tt.wantMoved {
+				t.Fatalf("moved %d, want %d", moved, tt.wantMoved)
+			}
+			if fake.count(catalog.TierCold) != int(tt.wantMoved) {
+				t.Fatalf("%d blobs are cold in the catalog, want %d", fake.count(catalog.TierCold), tt.wantMoved)
+			}
+
+			if len(tt.failing) == 0 {
+				if err != nil {
+					t.Fatalf("got %v", err)
+				}
+			} else {
+				if !errors.Is(err, errMove) {
+					t.Fatalf("got %v, want %v", err, errMove)
+				}
+				// Каждый пропущенный blob есть в ошибке
+				for _, i := range tt.failing {
+					if checksum := fmt.Sprintf("%064x", i); !strings.Contains(err.Error(), checksum) {
+						t.Fatalf("error does not mention blob %s: %v", checksum, err)
+					}
+				}
+			}
+
+			// За один запуск blob пробуют перенести не больше одного раза
+			for checksum, calls := range mover.calls {
+				if calls != 1 {
+					t.Fatalf("blob %s tried %d times", checksum, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestDemoteListError(t *testing.T) {
+	fake := newFakeCatalog(3, time.Now().Add(-24*time.Hour))
+	fake.listErr = errors.New("catalog is down")
+	s, _ := newTestTierService(fake)
+
+	moved, err := s.Demote(context.Background())
+	if moved != 0 || !errors.Is(err, fake.listErr) {
+		t.Fatalf("got %d, %v", moved, err)
+	}
+}
+
+func TestDemoteCanceled(t *testing.T) {
+	fake := newFakeCatalog(5, time.Now().Add(-24*time.Hour))
+	s, mover := newTestTierService(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	moved, err := s.Demote(ctx)
+	if moved != 0 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %d, %v", moved, err)
+	}
+	if len(mover.calls) != 0 {
+		t.Fatalf("moved %d blobs after cancel", len(mover.calls))
+	}
+}
+
+func TestAccessed(t *testing.T) {
+	checksum := fmt.Sprintf("%064x", 0)
+
+	tests := []struct {
+		name        string
+		tier        string
+		lastAccess  time.Time
+		wantTouched bool
+		wantPromote bool
+	}{
+		{name: "hot, accessed long ago", tier: catalog.TierHot, lastAccess: time.Now().Add(-time.Hour), wantTouched: true},
+		{name: "hot, accessed just now", tier: catalog.TierHot, lastAccess: time.Now()},
+		{name: "cold", tier: catalog.TierCold, lastAccess: time.Now().Add(-time.Hour), wantTouched: true, wantPromote: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeCatalog(1, tt.lastAccess)
+			fake.tiers[checksum] = tt.tier
+			s, mover := newTestTierService(fake)
+			mover.moved = make(chan string, 1)
+
+			s.Accessed(catalog.FileLocation{Checksum: checksum, StoredBlob: catalog.StoredBlob{Tier: tt.tier}, LastAccessedAt: tt.lastAccess})
+
+			touched := !fake.accessed[checksum].Equal(tt.lastAccess)
+			if touched != tt.wantTouched {
+				t.Fatalf("touched %v, want %v", touched, tt.wantTouched)
+			}
+			if !tt.wantPromote {
+				return
+			}
+			select {
+			case <-mover.moved:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("cold blob was not moved back")
+			}
+			if fake.count(catalog.TierHot) != 1 {
+				t.Fatalf("blob is still cold in the catalog")
+			}
+		})
+	}
+}
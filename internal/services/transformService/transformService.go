@@ -0,0 +1,267 @@
+package transformService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"imagestorage/internal/imaging"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Storage interface {
+	FindFileLocation(fileName string, version int64) (catalog.FileLocation, error)
+}
+
+type Blobs interface {
+	ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error)
+}
+
+// TransformService преобразует изображения по списку операций. Результаты кэшируются на диске
+// по sha256 содержимого и операциям, поэтому у одинакового содержимого кэш общий
+type TransformService struct {
+	log     *logrus.Logger
+	storage Storage
+	blobs   Blobs
+	// cacheDir - каталог кэша, cacheSize - его наибольший размер в байтах, 0 - без кэша
+	cacheDir  string
+	cacheSize int64
+
+	mu sync.Mutex
+	// used - размер кэша, -1 - еще не посчитан
+	used int64
+}
+
+func NewTransformService(log *logrus.Logger, storage Storage, blobs Blobs, cacheDir string, cacheSize int64) *TransformService {
+	return &TransformService{
+		log:       log,
+		storage:   storage,
+		blobs:     blobs,
+		cacheDir:  cacheDir,
+		cacheSize: cacheSize,
+		used:      -1,
+	}
+}
+
+// Transform выполняет ops над версией файла и возвращает результат и его MIME-тип.
+// Результаты зашифрованных файлов не кэшируются, чтобы расшифрованное содержимое не оказалось на диске
+func (s *TransformService) Transform(ctx context.Context, fileName string, version int64, ops []imaging.Operation) (io.ReadCloser, string, error) {
+	op := "internal.service.TransformService.Transform"
+
+	for _, operation := range ops {
+		if err := operation.Validate(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Файл ищется и при попадании в кэш: удаленный файл не должен отдаваться из кэша
+	location, err := s.storage.FindFileLocation(fileName, version)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	cacheable := s.cacheSize > 0 && location.Checksum != "" && location.KeyID == ""
+	path := s.cachePath(location.Checksum, ops)
+	if cacheable {
+		reader, format, err := s.cached(path)
+		if err == nil {
+			return reader, imaging.MimeType(format), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.log.Warnf("Failed to read cached transform %s: %v %s", path, err, op)
+		}
+	}
+
+	data, format, err := s.transform(ctx, location, ops)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cacheable {
+		if err := s.store(path, data); err != nil {
+			s.log.Errorf("Failed to cache transform of %s: %v %s", fileName, err, op)
+		}
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), imaging.MimeType(format), nil
+}
+
+func (s *TransformService) transform(ctx context.Context, location catalog.FileLocation, ops []imaging.Operation) ([]byte, string, error) {
+	rc, err := s.blobs.ReadBlob(ctx, location.Path, location.StoredBlob, 0, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	// Файл, который не изображение, отклоняется по заголовку, не читая его целиком
+	img, sourceFormat, err := imaging.DecodeReader(rc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, err = imaging.Apply(img, ops)
+	if err != nil {
+		return nil, "", err
+	}
+
+	format, quality := imaging.Output(ops, sourceFormat)
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, quality); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), format, nil
+}
+
+// cachePath - файл кэша результата ops над содержимым checksum
+func (s *TransformService) cachePath(checksum string, ops []imaging.Operation) string {
+	sum := sha256.Sum256([]byte(checksum + "\n" + imaging.Describe(ops)))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(s.cacheDir, key[:2], key)
+}
+
+// cached открывает результат из кэша и отмечает его использование: вытесняются давно не читанные
+func (s *TransformService) cached(path string) (io.ReadCloser, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	format, err := imaging.DetectFormat(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		s.log.Warnf("Failed to touch cached transform %s: %v", path, err)
+	}
+
+	return file, format, nil
+}
+
+// store кладет результат в кэш и вытесняет старые, если кэш стал больше cacheSize
+func (s *TransformService) store(path string, data []byte) error {
+	if int64(len(data)) > s.cacheSize {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.used < 0 {
+		entries, err := s.entries()
+		if err != nil {
+			return err
+		}
+		s.used = 0
+		for _, entry := range entries {
+			s.used += entry.size
+		}
+	}
+
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	s.used += int64(len(data)) - replaced
+
+	if s.used > s.cacheSize {
+		return s.evict()
+	}
+	return nil
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *TransformService) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+	err := filepath.WalkDir(s.cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Base(path)[0] == '.' {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, cacheEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return entries, err
+}
+
+// evict удаляет давно не читанные результаты, пока кэш не уменьшится до 90% cacheSize,
+// чтобы не обходить каталог на каждой записи. Вызывается под mu
+func (s *TransformService) evict() error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(entries, func(a, b cacheEntry) int { return a.modTime.Compare(b.modTime) })
+
+	s.used = 0
+	for _, entry := range entries {
+		s.used += entry.size
+	}
+
+	target := s.cacheSize - s.cacheSize/10
+	var evicted int
+	for _, entry := range entries {
+		if s.used <= target {
+			break
+		}
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		s.used -= entry.size
+		evicted++
+	}
+
+	s.log.Infof("Evicted %d cached transforms, cache size %d", evicted, s.used)
+	return nil
+}
@@ -0,0 +1,253 @@
+package transformService
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+
+	"imagestorage/internal/imaging"
+	"imagestorage/internal/storage/catalog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeCatalog - каталог из одного файла
+type fakeCatalog struct {
+	location catalog.FileLocation
+}
+
+func (c fakeCatalog) FindFileLocation(fileName string, version int64) (catalog.FileLocation, error) {
+	if fileName != c.location.FileName {
+		return catalog.FileLocation{}, catalog.ErrFileNotFound
+	}
+	return c.location, nil
+}
+
+// blobs отдает content и считает, сколько байт из него прочитано
+type blobs struct {
+	content func() io.Reader
+	read    int64
+	opened  int
+}
+
+func (b *blobs) ReadBlob(ctx context.Context, key string, stored catalog.StoredBlob, offset int64, length int64) (io.ReadCloser, error) {
+	b.opened++
+	return io.NopCloser(&countingReader{r: b.content(), n: &b.read}), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+func newTestService(t *testing.T, content func() io.Reader, cacheSize int64) (*TransformService, *blobs) {
+	t.Helper()
+	return newTestServiceFor(t, catalog.FileLocation{FileName: "a", Checksum: "0123", Path: "blobs/0123"}, content, cacheSize)
+}
+
+func newTestServiceFor(t *testing.T, location catalog.FileLocation, content func() io.Reader, cacheSize int64) (*TransformService, *blobs) {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	store := &blobs{content: content}
+	return NewTransformService(log, fakeCatalog{location: location}, store, t.TempDir(), cacheSize), store
+}
+
+// zeros - бесконечный поток нулей
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestTransformRejectsNonImage(t *testing.T) {
+	const size = 64 << 20
+	s, store := newTestService(t, func() io.Reader { return io.LimitReader(zeros{}, size) }, 0)
+
+	_, _, err := s.Transform(context.Background(), "a", 0, []imaging.Operation{{Kind: imaging.OpRotate, Degrees: 90}})
+	if !errors.Is(err, imaging.ErrUnsupported) {
+		t.Fatalf("got %v, want %v", err, imaging.ErrUnsupported)
+	}
+	if store.read >= 1<<20 {
+		t.Fatalf("read %d bytes of a non-image to reject it", store.read)
+	}
+
+	// Пустой файл тоже не изображение
+	s, _ = newTestService(t, func() io.Reader { return bytes.NewReader(nil) }, 0)
+	if _, _, err := s.Transform(context.Background(), "a", 0, []imaging.Operation{{Kind: imaging.OpFlip}}); err == nil {
+		t.Fatalf("transformed an empty file")
+	}
+}
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, rc io.ReadCloser) []byte {
+	t.Helper()
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTransformCache(t *testing.T) {
+	source := pngOf(t, 8, 4)
+	content := func() io.Reader { return bytes.NewReader(source) }
+	ctx := context.Background()
+
+	t.Run("hit", func(t *testing.T) {
+		s, store := newTestService(t, content, 1<<20)
+
+		rc, mimeType, err := s.Transform(ctx, "a", 0, []imaging.Operation{{Kind: imaging.OpRotate, Degrees: -90}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := readAll(t, rc)
+		if mimeType != "image/png" {
+			t.Fatalf("content type %s", mimeType)
+		}
+
+		// Тот же поворот, записанный иначе, берется из кэша, не читая blob
+		rc, mimeType, err = s.Transform(ctx, "a", 0, []imaging.Operation{{Kind: imaging.OpRotate, Degrees: 270}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second := readAll(t, rc); !bytes.Equal(first, second) || mimeType != "image/png" {
+			t.Fatalf("cached result differs: %d bytes %s", len(second), mimeType)
+		}
+		if store.opened != 1 {
+			t.Fatalf("blob read %d times, want 1", store.opened)
+		}
+
+		// Другие операции - другой результат
+		rc, mimeType, err = s.Transform(ctx, "a", 0, []imaging.Operation{{Kind: imaging.OpFormat, Format: imaging.JPEG}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		readAll(t, rc)
+		if store.opened != 2 || mimeType != "image/jpeg" {
+			t.Fatalf("blob read %d times, content type %s", store.opened, mimeType)
+		}
+	})
+
+	t.Run("deleted file is not served from the cache", func(t *testing.T) {
+		s, _ := newTestService(t, content, 1<<20)
+		ops := []imaging.Operation{{Kind: imaging.OpFlip}}
+		rc, _, err := s.Transform(ctx, "a", 0, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readAll(t, rc)
+
+		s.storage = fakeCatalog{}
+		if _, _, err := s.Transform(ctx, "a", 0, ops); !errors.Is(err, catalog.ErrFileNotFound) {
+			t.Fatalf("got %v, want %v", err, catalog.ErrFileNotFound)
+		}
+	})
+
+	t.Run("encrypted file is not cached", func(t *testing.T) {
+		location := catalog.FileLocation{FileName: "a", Checksum: "0123", Path: "blobs/0123", StoredBlob: catalog.StoredBlob{KeyID: "k1"}}
+		s, store := newTestServiceFor(t, location, content, 1<<20)
+		for i := 0; i < 2; i++ {
+			rc, _, err := s.Transform(ctx, "a", 0, []imaging.Operation{{Kind: imaging.OpFlip}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			readAll(t, rc)
+		}
+		if store.opened != 2 {
+			t.Fatalf("blob read %d times, want 2", store.opened)
+		}
+		if entries, err := s.entries(); err != nil || len(entries) != 0 {
+			t.Fatalf("cache has %d entries, %v", len(entries), err)
+		}
+	})
+}
+
+// Переполненный кэш вытесняет давно не читанные результаты, пока не уменьшится до 90% размера
+func TestTransformCacheEviction(t *testing.T) {
+	// cached читает заголовок результата, поэтому в кэше - настоящие изображения
+	result := pngOf(t, 1, 1)
+	size := int64(len(result))
+	s, _ := newTestService(t, func() io.Reader { return bytes.NewReader(nil) }, 3*size+size/2)
+	ops := func(n int) []imaging.Operation { return []imaging.Operation{{Kind: imaging.OpRotate, Degrees: 90 * n}} }
+
+	paths := make([]string, 4)
+	for i := range paths {
+		paths[i] = s.cachePath("0123", ops(i))
+	}
+
+	// Результаты записаны по порядку, у каждого свое время последнего чтения
+	start := time.Now().Add(-time.Hour)
+	for i, path := range paths[:3] {
+		if err := s.store(path, result); err != nil {
+			t.Fatal(err)
+		}
+		at := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.used != 3*size {
+		t.Fatalf("used %d, want %d", s.used, 3*size)
+	}
+
+	// Чтение первого делает его самым свежим, поэтому вытесняется второй
+	rc, format, err := s.cached(paths[0])
+	if err != nil || format != imaging.PNG {
+		t.Fatalf("cached: %s, %v", format, err)
+	}
+	rc.Close()
+	if err := s.store(paths[3], result); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false, true, true} {
+		_, err := os.Stat(paths[i])
+		if exists := err == nil; exists != want {
+			t.Fatalf("result %d exists %v, want %v", i, exists, want)
+		}
+	}
+	if s.used != 3*size {
+		t.Fatalf("used %d after eviction, want %d", s.used, 3*size)
+	}
+
+	// Результат больше всего кэша не кладется
+	big := s.cachePath("0123", []imaging.Operation{{Kind: imaging.OpFlip}})
+	if err := s.store(big, bytes.Repeat(result, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(big); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("result larger than the cache is stored: %v", err)
+	}
+}
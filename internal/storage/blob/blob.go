@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"imagestorage/internal/config"
+)
+
+const (
+	DriverFS     = "fs"
+	DriverMemory = "memory"
+	DriverS3     = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Info - то, что хранилище знает о blob-е без чтения содержимого
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store - хранилище содержимого файлов по ключу. Ключи - пути через "/", например "blobs/<sha256>"
+type Store interface {
+	// Put записывает size байт из r под ключом key. Читатели не видят blob, пока Put не завершился
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange читает length байт начиная с offset, length == 0 - до конца
+	GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	// Delete удаляет blob, отсутствие blob-а ошибкой не считается
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (Info, error)
+}
+
+// New создает хранилище выбранного в конфиге драйвера
+func New(cfg config.BlobStorage, root string) (Store, error) {
+	const op = "storage.blob.New"
+
+	switch cfg.Driver {
+	case DriverFS, "":
+		if cfg.ErasureDataShards > 0 {
+			if cfg.ReplicationFactor > 1 {
+				return nil, fmt.Errorf("%s: erasure coding and replication are mutually exclusive", op)
+			}
+			return NewErasureStore(cfg.DataDirs, cfg.ErasureDataShards, cfg.ErasureParityShards)
+		}
+		if len(cfg.DataDirs) > 0 {
+			return NewReplicatedStore(cfg.DataDirs, cfg.ReplicationFactor)
+		}
+		return NewFileStore(root), nil
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	case DriverS3:
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("%s: unknown blob driver %q", op, cfg.Driver)
+	}
+}
+
+// NewCold создает холодное хранилище для файлов, которые давно не скачивали, или nil, если перенос выключен.
+// S3 у холодного хранилища общий с основным, поэтому оба сразу быть s3 не могут
+func NewCold(cfg config.Tiering, hot config.BlobStorage) (Store, error) {
+	const op = "storage.blob.NewCold"
+
+	switch cfg.ColdDriver {
+	case "":
+		return nil, nil
+	case DriverFS:
+		if cfg.ColdStoragePath == "" {
+			return nil, fmt.Errorf("%s: cold storage path is required", op)
+		}
+		return NewFileStore(cfg.ColdStoragePath), nil
+	case DriverS3:
+		if hot.Driver == DriverS3 {
+			return nil, fmt.Errorf("%s: hot and cold storage cannot both be s3", op)
+		}
+		return NewS3Store(hot.S3)
+	default:
+		return nil, fmt.Errorf("%s: unknown cold blob driver %q", op, cfg.ColdDriver)
+	}
+}
+
+// FilePutter реализуют хранилища, которые забирают готовый локальный файл без копирования.
+// После успешного PutFile файла по path больше нет
+type FilePutter interface {
+	PutFile(ctx context.Context, key string, path string) error
+}
+
+// Lister реализуют хранилища, которые умеют перечислять свои blob-ы
+type Lister interface {
+	// List вызывает fn для каждого blob-а. Порядок не определен, blob-ы, записанные во время обхода,
+	// могут не попасть в него
+	List(ctx context.Context, fn func(key string, info Info) error) error
+}
@@ -0,0 +1,357 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testData   = 4
+	testParity = 2
+)
+
+func newTestErasureStore(t *testing.T, dirs int) *ErasureStore {
+	t.Helper()
+
+	paths := make([]string, dirs)
+	for i := range paths {
+		paths[i] = t.TempDir()
+	}
+	s, err := NewErasureStore(paths, testData, testParity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func shardPath(s *ErasureStore, key string, index int) string {
+	return filepath.Join(s.ShardPlacement(key)[index], filepath.FromSlash(shardKey(key, index)))
+}
+
+func readBlob(t *testing.T, s *ErasureStore, key string, offset, length int64) ([]byte, []ShardState, error) {
+	t.Helper()
+
+	var states []ShardState
+	report := func(state ShardState) {
+		for i := range states {
+			if states[i].Index == state.Index {
+				states[i] = state
+				return
+			}
+		}
+		states = append(states, state)
+	}
+
+	rc, err := s.ReadShards(context.Background(), key, offset, length, report)
+	if err != nil {
+		return nil, states, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	return data, states, err
+}
+
+func TestErasureReconstruct(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	data = append(data, "tail"...)
+
+	remove := func(index int) func(t *testing.T, s *ErasureStore, key string) {
+		return func(t *testing.T, s *ErasureStore, key string) {
+			if err := os.Remove(shardPath(s, key, index)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	flip := func(index int, at int) func(t *testing.T, s *ErasureStore, key string) {
+		return func(t *testing.T, s *ErasureStore, key string) {
+			path := shardPath(s, key, index)
+			file, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file[at] ^= 0xff
+			if err := os.WriteFile(path, file, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// swap кладет фрагменты i и j на места друг друга: заголовок цел, но номер не тот
+	swap := func(i, j int) func(t *testing.T, s *ErasureStore, key string) {
+		return func(t *testing.T, s *ErasureStore, key string) {
+			first, second := shardPath(s, key, i), shardPath(s, key, j)
+			a, err := os.ReadFile(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(first, b, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(second, a, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Сообщаются только прочитанные фрагменты: parity открываются, лишь когда data-фрагментов не хватает
+	payload := shardHeaderSize + sha256.Size + 10
+	tests := []struct {
+		name    string
+		damage  []func(t *testing.T, s *ErasureStore, key string)
+		states  map[int]error
+		wantErr bool
+	}{
+		{name: "intact", states: map[int]error{0: nil, 1: nil, 2: nil, 3: nil}},
+		{name: "one data shard lost", damage: []func(*testing.T, *ErasureStore, string){remove(0)}, states: map[int]error{0: ErrNotFound, 1: nil, 2: nil, 3: nil, 4: nil}},
+		{name: "parity lost", damage: []func(*testing.T, *ErasureStore, string){remove(4), remove(5)}, states: map[int]error{0: nil, 1: nil, 2: nil, 3: nil}},
+		{name: "two data shards lost", damage: []func(*testing.T, *ErasureStore, string){remove(1), remove(3)}, states: map[int]error{0: nil, 1: ErrNotFound, 2: nil, 3: ErrNotFound, 4: nil, 5: nil}},
+		{name: "payload corrupted", damage: []func(*testing.T, *ErasureStore, string){flip(2, payload)}, states: map[int]error{0: nil, 1: nil, 2: ErrShardCorrupt, 3: nil, 4: nil}},
+		{name: "checksum corrupted", damage: []func(*testing.T, *ErasureStore, string){flip(1, shardHeaderSize+3)}, states: map[int]error{0: nil, 1: ErrShardCorrupt, 2: nil, 3: nil, 4: nil}},
+		{name: "magic corrupted", damage: []func(*testing.T, *ErasureStore, string){flip(0, 0), remove(5)}, states: map[int]error{0: ErrShardCorrupt, 1: nil, 2: nil, 3: nil, 4: nil}},
+		{name: "shards swapped", damage: []func(*testing.T, *ErasureStore, string){swap(0, 1)}, states: map[int]error{0: ErrShardCorrupt, 1: ErrShardCorrupt, 2: nil, 3: nil, 4: nil, 5: nil}},
+		{name: "too many lost", damage: []func(*testing.T, *ErasureStore, string){remove(0), remove(2), flip(4, payload)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestErasureStore(t, testData+testParity+1)
+			key := "ab/cd/blob"
+			if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			for _, damage := range tt.damage {
+				damage(t, s, key)
+			}
+
+			got, states, err := readBlob(t, s, key, 0, 0)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("read %d bytes from %d intact shards", len(got), testData-1)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("reconstructed %d bytes differ from the original %d", len(got), len(data))
+			}
+
+			if len(states) != len(tt.states) {
+				t.Fatalf("got %d shard states %v, want %d", len(states), states, len(tt.states))
+			}
+			for _, state := range states {
+				want, ok := tt.states[state.Index]
+				if !ok || !errors.Is(state.Err, want) || (want == nil && state.Err != nil) {
+					t.Fatalf("shard %d: got %v, want %v", state.Index, state.Err, want)
+				}
+			}
+		})
+	}
+}
+
+// Порча одного куска восстанавливается из его строки, а диапазон не трогает фрагменты, на которые не приходится
+func TestErasureChunks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestErasureStore(t, testData+testParity)
+	data := make([]byte, 3*shardChunkSize*testData+123)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	key := "chunks"
+	// Источник без ReadAt копируется во временный файл
+	if err := s.Put(ctx, key, io.MultiReader(bytes.NewReader(data)), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	perShard, chunks := s.shardLayout(int64(len(data)))
+	headerSize := int64(shardHeaderSize) + chunks*sha256.Size
+	path := shardPath(s, key, 1)
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file[headerSize+shardChunkSize+5] ^= 0xff
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("whole", func(t *testing.T) {
+		got, states, err := readBlob(t, s, key, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatal("reconstructed blob differs from the original")
+		}
+		for _, state := range states {
+			if state.Index == 1 && !errors.Is(state.Err, ErrShardCorrupt) {
+				t.Fatalf("shard 1: got %v, want %v", state.Err, ErrShardCorrupt)
+			}
+		}
+	})
+
+	t.Run("range within one shard", func(t *testing.T) {
+		offset := 2*perShard + 10
+		got, states, err := readBlob(t, s, key, offset, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data[offset:offset+100]) {
+			t.Fatal("range differs from the original")
+		}
+		// Фрагмент 0 открывается ради размера blob-а, 2 - ради данных
+		if len(states) != 2 || states[0].Index != 0 || states[1].Index != 2 {
+			t.Fatalf("read shards %v, want 0 and 2", states)
+		}
+	})
+
+	t.Run("range over the corrupt chunk", func(t *testing.T) {
+		offset := perShard + shardChunkSize - 50
+		got, _, err := readBlob(t, s, key, offset, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data[offset:offset+100]) {
+			t.Fatal("range differs from the original")
+		}
+	})
+}
+
+func TestErasureRanges(t *testing.T) {
+	ctx := context.Background()
+	s := newTestErasureStore(t, testData+testParity)
+	data := bytes.Repeat([]byte("range"), 777)
+	key := "ranges"
+	if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	// Диапазоны читаются и при восстановлении
+	if err := os.Remove(shardPath(s, key, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	size := int64(len(data))
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           []byte
+	}{
+		{name: "whole", offset: 0, length: 0, want: data},
+		{name: "head", offset: 0, length: 10, want: data[:10]},
+		{name: "middle", offset: 1000, length: 500, want: data[1000:1500]},
+		{name: "to the end", offset: size - 3, length: 0, want: data[size-3:]},
+		{name: "length past the end", offset: size - 3, length: 100, want: data[size-3:]},
+		{name: "offset past the end", offset: size + 10, length: 0, want: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := readBlob(t, s, key, tt.offset, tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("got %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != size {
+		t.Fatalf("Stat size %d, want %d", info.Size, size)
+	}
+}
+
+func TestErasureSpecialBlobs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty", func(t *testing.T) {
+		s := newTestErasureStore(t, testData+testParity)
+		if err := s.Put(ctx, "empty", bytes.NewReader(nil), 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(shardPath(s, "empty", 0)); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := readBlob(t, s, "empty", 0, 0)
+		if err != nil || len(got) != 0 {
+			t.Fatalf("got %d bytes, %v", len(got), err)
+		}
+	})
+
+	t.Run("whole file written before sharding", func(t *testing.T) {
+		s := newTestErasureStore(t, testData+testParity)
+		location := s.dirs.Locations("legacy")[0]
+		store, _ := s.dirs.Replica(location)
+		if err := store.Put(ctx, "legacy", bytes.NewReader([]byte("legacy blob")), 11); err != nil {
+			t.Fatal(err)
+		}
+
+		got, states, err := readBlob(t, s, "legacy", 7, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "blob" || states != nil {
+			t.Fatalf("got %q with states %v", got, states)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		s := newTestErasureStore(t, testData+testParity)
+		if _, _, err := readBlob(t, s, "missing", 0, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, ErrNotFound)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		s := newTestErasureStore(t, testData+testParity)
+		if err := s.Put(ctx, "deleted", bytes.NewReader([]byte("data")), 4); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Delete(ctx, "deleted"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readBlob(t, s, "deleted", 0, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, ErrNotFound)
+		}
+	})
+}
+
+func TestNewErasureStoreLimits(t *testing.T) {
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+
+	tests := []struct {
+		name         string
+		data, parity int
+		wantErr      bool
+	}{
+		{name: "fits", data: 2, parity: 1},
+		{name: "no parity", data: 3, parity: 0, wantErr: true},
+		{name: "no data", data: 0, parity: 2, wantErr: true},
+		{name: "more shards than dirs", data: 3, parity: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewErasureStore(dirs, tt.data, tt.parity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
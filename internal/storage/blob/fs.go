@@ -0,0 +1,217 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore хранит blob-ы файлами в каталоге root
+type FileStore struct {
+	root string
+}
+
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root}
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// Put пишет во временный файл рядом с целевым, сбрасывает его на диск и переименовывает
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	const op = "storage.blob.FileStore.Put"
+
+	path := s.path(key)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PutFile переносит файл в хранилище переименованием, если он на той же файловой системе,
+// иначе копирует его через Put
+func (s *FileStore) PutFile(ctx context.Context, key string, path string) error {
+	const op = "storage.blob.FileStore.PutFile"
+
+	target := s.path(key)
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(path, target); err == nil {
+		if err := syncDir(dir); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.Put(ctx, key, file, info.Size()); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, 0)
+}
+
+func (s *FileStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	const op = "storage.blob.FileStore.GetRange"
+
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if length > 0 {
+		return limitReadCloser(file, length), nil
+	}
+	return file, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	const op = "storage.blob.FileStore.Delete"
+
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.FileStore.Stat"
+
+	info, err := os.Stat(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Info{}, fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if info.IsDir() {
+		return Info{}, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	return Info{Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List обходит каталог root. Файлы и каталоги, имя которых начинается с точки, пропускаются:
+// это недописанные Put и staging-каталог загрузок
+func (s *FileStore) List(ctx context.Context, fn func(key string, info Info) error) error {
+	const op = "storage.blob.FileStore.List"
+
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if path != s.root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		key, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(key), Info{Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+func limitReadCloser(rc io.ReadCloser, length int64) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, length), rc}
+}
@@ -0,0 +1,110 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStore держит blob-ы в памяти процесса. Подходит для тестов и локальной разработки
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string]memoryBlob
+}
+
+type memoryBlob struct {
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string]memoryBlob)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	const op = "storage.blob.MemoryStore.Put"
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("%s: read %d bytes, expected %d", op, len(data), size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = memoryBlob{data: data, modTime: time.Now()}
+
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, 0)
+}
+
+func (s *MemoryStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	const op = "storage.blob.MemoryStore.GetRange"
+
+	s.mu.RLock()
+	blob, ok := s.blobs[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	// Содержимое не меняется после Put, поэтому отдаем срез без копирования
+	data := blob.data
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length > 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+
+	return nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.MemoryStore.Stat"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blob, ok := s.blobs[key]
+	if !ok {
+		return Info{}, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	return Info{Size: int64(len(blob.data)), ModTime: blob.modTime}, nil
+}
+
+// List перечисляет снимок blob-ов на момент вызова
+func (s *MemoryStore) List(ctx context.Context, fn func(key string, info Info) error) error {
+	s.mu.RLock()
+	infos := make(map[string]Info, len(s.blobs))
+	for key, blob := range s.blobs {
+		infos[key] = Info{Size: int64(len(blob.data)), ModTime: blob.modTime}
+	}
+	s.mu.RUnlock()
+
+	for key, info := range infos {
+		if err := fn(key, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
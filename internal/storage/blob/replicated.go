@@ -0,0 +1,234 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Replicated реализуют хранилища, которые держат несколько копий blob-а в разных местах
+type Replicated interface {
+	// Placement - места, в которые Put кладет копии key
+	Placement(key string) []string
+	// Locations - все места хранилища в том порядке, в котором в них стоит искать key
+	Locations(key string) []string
+	// Replica - хранилище одного места
+	Replica(location string) (Store, bool)
+}
+
+// ReplicatedStore хранит каждый blob в factor каталогах из dirs. Каталоги для ключа выбираются
+// rendezvous-хешированием: набор не меняется от перезапуска и почти не меняется при добавлении каталога
+type ReplicatedStore struct {
+	dirs   []string
+	stores map[string]*FileStore
+	factor int
+}
+
+var (
+	_ Store      = (*ReplicatedStore)(nil)
+	_ FilePutter = (*ReplicatedStore)(nil)
+	_ Replicated = (*ReplicatedStore)(nil)
+	_ Lister     = (*ReplicatedStore)(nil)
+)
+
+func NewReplicatedStore(dirs []string, factor int) (*ReplicatedStore, error) {
+	const op = "storage.blob.NewReplicatedStore"
+
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("%s: no data dirs", op)
+	}
+	if factor < 1 || factor > len(dirs) {
+		return nil, fmt.Errorf("%s: replication factor %d out of range 1..%d", op, factor, len(dirs))
+	}
+
+	cleaned := make([]string, 0, len(dirs))
+	stores := make(map[string]*FileStore, len(dirs))
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if _, ok := stores[dir]; ok {
+			return nil, fmt.Errorf("%s: data dir %s listed twice", op, dir)
+		}
+		cleaned = append(cleaned, dir)
+		stores[dir] = NewFileStore(dir)
+	}
+
+	return &ReplicatedStore{dirs: cleaned, stores: stores, factor: factor}, nil
+}
+
+func (s *ReplicatedStore) Locations(key string) []string {
+	type scored struct {
+		dir   string
+		score uint64
+	}
+
+	ranked := make([]scored, 0, len(s.dirs))
+	for _, dir := range s.dirs {
+		h := fnv.New64a()
+		h.Write([]byte(dir))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		ranked = append(ranked, scored{dir: dir, score: h.Sum64()})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].dir < ranked[j].dir
+	})
+
+	locations := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		locations = append(locations, r.dir)
+	}
+	return locations
+}
+
+func (s *ReplicatedStore) Placement(key string) []string {
+	return s.Locations(key)[:s.factor]
+}
+
+func (s *ReplicatedStore) Replica(location string) (Store, bool) {
+	store, ok := s.stores[location]
+	return store, ok
+}
+
+// Put пишет первую копию из r, остальные копирует с нее
+func (s *ReplicatedStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	const op = "storage.blob.ReplicatedStore.Put"
+
+	placement := s.Placement(key)
+	first := s.stores[placement[0]]
+	if err := first.Put(ctx, key, r, size); err != nil {
+		return fmt.Errorf("%s: %s: %w", op, placement[0], err)
+	}
+
+	if err := s.copyTo(ctx, key, first.path(key), placement[1:]); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PutFile копирует файл во все места, кроме первого, а в первое переносит его переименованием
+func (s *ReplicatedStore) PutFile(ctx context.Context, key string, path string) error {
+	const op = "storage.blob.ReplicatedStore.PutFile"
+
+	placement := s.Placement(key)
+	if err := s.copyTo(ctx, key, path, placement[1:]); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.stores[placement[0]].PutFile(ctx, key, path); err != nil {
+		return fmt.Errorf("%s: %s: %w", op, placement[0], err)
+	}
+
+	return nil
+}
+
+func (s *ReplicatedStore) copyTo(ctx context.Context, key string, path string, locations []string) error {
+	for _, location := range locations {
+		if err := copyFile(ctx, s.stores[location], key, path); err != nil {
+			return fmt.Errorf("%s: %w", location, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(ctx context.Context, store Store, key string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return store.Put(ctx, key, file, info.Size())
+}
+
+func (s *ReplicatedStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, 0)
+}
+
+// GetRange читает из первой найденной копии. Содержимое не проверяется, это делает вызывающий
+// через Replica, когда знает checksum
+func (s *ReplicatedStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	const op = "storage.blob.ReplicatedStore.GetRange"
+
+	var lastErr error = ErrNotFound
+	for _, location := range s.Locations(key) {
+		rc, err := s.stores[location].GetRange(ctx, key, offset, length)
+		if err == nil {
+			return rc, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", op, lastErr)
+}
+
+// Delete удаляет копии во всех каталогах, а не только в Placement: копия могла остаться
+// в каталоге, где blob лежал до изменения списка каталогов
+func (s *ReplicatedStore) Delete(ctx context.Context, key string) error {
+	const op = "storage.blob.ReplicatedStore.Delete"
+
+	var errs []error
+	for _, dir := range s.dirs {
+		if err := s.stores[dir].Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *ReplicatedStore) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.ReplicatedStore.Stat"
+
+	var lastErr error = ErrNotFound
+	for _, location := range s.Locations(key) {
+		info, err := s.stores[location].Stat(ctx, key)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
+	}
+
+	return Info{}, fmt.Errorf("%s: %w", op, lastErr)
+}
+
+// List перечисляет blob-ы всех каталогов, каждый один раз. Info берется из первой найденной копии
+func (s *ReplicatedStore) List(ctx context.Context, fn func(key string, info Info) error) error {
+	const op = "storage.blob.ReplicatedStore.List"
+
+	seen := make(map[string]bool)
+	for _, dir := range s.dirs {
+		err := s.stores[dir].List(ctx, func(key string, info Info) error {
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+			return fn(key, info)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestReplicatedStore(t *testing.T, dirs int, factor int) *ReplicatedStore {
+	t.Helper()
+
+	paths := make([]string, dirs)
+	for i := range paths {
+		paths[i] = t.TempDir()
+	}
+	s, err := NewReplicatedStore(paths, factor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func hasCopy(t *testing.T, location string, key string) bool {
+	t.Helper()
+
+	_, err := os.Stat(filepath.Join(location, filepath.FromSlash(key)))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestReplicatedPlacement(t *testing.T) {
+	ctx := context.Background()
+	s := newTestReplicatedStore(t, 4, 2)
+	data := []byte("replicated")
+
+	reversed := slices.Clone(s.dirs)
+	slices.Reverse(reversed)
+	other, err := NewReplicatedStore(reversed, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a/b/one", "a/b/two", "three"} {
+		if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+
+		placement := s.Placement(key)
+		if len(placement) != 2 || !slices.Equal(placement, s.Locations(key)[:2]) {
+			t.Fatalf("%s: placement %v of locations %v", key, placement, s.Locations(key))
+		}
+		for _, location := range s.Locations(key) {
+			if got, want := hasCopy(t, location, key), slices.Contains(placement, location); got != want {
+				t.Fatalf("%s: copy in %s is %v, want %v", key, location, got, want)
+			}
+		}
+
+		// Порядок каталогов для ключа не зависит от порядка в списке
+		if !slices.Equal(other.Locations(key), s.Locations(key)) {
+			t.Fatalf("%s: locations %v depend on the dir order, got %v", key, other.Locations(key), s.Locations(key))
+		}
+	}
+}
+
+func TestReplicatedPutFile(t *testing.T) {
+	ctx := context.Background()
+	s := newTestReplicatedStore(t, 3, 3)
+
+	file := filepath.Join(t.TempDir(), "staged")
+	if err := os.WriteFile(file, []byte("staged"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutFile(ctx, "key", file); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, location := range s.Placement("key") {
+		if !hasCopy(t, location, "key") {
+			t.Fatalf("no copy in %s", location)
+		}
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("staged file kept: %v", err)
+	}
+}
+
+// TestReplicatedFallback читает и находит blob, пока жива хоть одна копия, в том числе вне Placement
+func TestReplicatedFallback(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("0123456789")
+
+	tests := []struct {
+		name string
+		// remove - номера каталогов из Locations, из которых удаляется копия
+		remove []int
+		// extra - номер каталога вне Placement, в котором лежит еще одна копия, -1 - нет
+		extra   int
+		wantErr error
+	}{
+		{name: "all copies", extra: -1},
+		{name: "first lost", remove: []int{0}, extra: -1},
+		{name: "second lost", remove: []int{1}, extra: -1},
+		{name: "only a copy outside placement", remove: []int{0, 1}, extra: 2},
+		{name: "all lost", remove: []int{0, 1}, extra: -1, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestReplicatedStore(t, 3, 2)
+			if err := s.Put(ctx, "key", bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatal(err)
+			}
+			locations := s.Locations("key")
+			if tt.extra >= 0 {
+				if err := s.stores[locations[tt.extra]].Put(ctx, "key", bytes.NewReader(data), int64(len(data))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, i := range tt.remove {
+				if err := s.stores[locations[i]].Delete(ctx, "key"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rc, err := s.GetRange(ctx, "key", 3, 4)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("get: got %v, want %v", err, tt.wantErr)
+			}
+			info, statErr := s.Stat(ctx, "key")
+			if !errors.Is(statErr, tt.wantErr) {
+				t.Fatalf("stat: got %v, want %v", statErr, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			defer rc.Close()
+
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "3456" {
+				t.Fatalf("got %q, want %q", got, "3456")
+			}
+			if info.Size != int64(len(data)) {
+				t.Fatalf("stat size %d, want %d", info.Size, len(data))
+			}
+		})
+	}
+}
+
+func TestReplicatedDeleteAndList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestReplicatedStore(t, 3, 2)
+
+	for _, key := range []string{"a", "b"} {
+		if err := s.Put(ctx, key, bytes.NewReader([]byte(key)), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Копия, оставшаяся в каталоге вне Placement, например после изменения списка каталогов
+	stray := s.Locations("a")[2]
+	if err := s.stores[stray].Put(ctx, "a", bytes.NewReader([]byte("a")), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	err := s.List(ctx, func(key string, info Info) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Fatalf("listed %v", keys)
+	}
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, location := range s.dirs {
+		if hasCopy(t, location, "a") {
+			t.Fatalf("copy of a left in %s", location)
+		}
+	}
+	if _, err := s.Stat(ctx, "b"); err != nil {
+		t.Fatalf("b: %v", err)
+	}
+}
+
+func TestNewReplicatedStoreLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		dirs    []string
+		factor  int
+		wantErr bool
+	}{
+		{name: "fits", dirs: []string{dir, t.TempDir()}, factor: 2},
+		{name: "no dirs", factor: 1, wantErr: true},
+		{name: "factor above dirs", dirs: []string{dir}, factor: 2, wantErr: true},
+		{name: "zero factor", dirs: []string{dir}, factor: 0, wantErr: true},
+		{name: "dir listed twice", dirs: []string{dir, dir + "/"}, factor: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReplicatedStore(tt.dirs, tt.factor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"imagestorage/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinS3PartSize - наименьшая часть multipart-загрузки, которую принимает S3
+const MinS3PartSize = 5 << 20
+
+// S3Store хранит blob-ы в бакете S3-совместимого хранилища
+type S3Store struct {
+	client   *minio.Client
+	bucket   string
+	prefix   string
+	partSize uint64
+}
+
+func NewS3Store(cfg config.S3Config) (*S3Store, error) {
+	const op = "storage.blob.NewS3Store"
+
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("%s: S3_ENDPOINT and S3_BUCKET are required", op)
+	}
+	if cfg.PartSize < MinS3PartSize {
+		return nil, fmt.Errorf("%s: S3_PART_SIZE %d is less than %d", op, cfg.PartSize, MinS3PartSize)
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, string(cfg.SecretAccessKey), ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3Store{
+		client:   client,
+		bucket:   cfg.Bucket,
+		prefix:   prefix,
+		partSize: cfg.PartSize,
+	}, nil
+}
+
+func (s *S3Store) object(key string) string {
+	return s.prefix + key
+}
+
+// Put отправляет содержимое multipart-загрузкой частями по partSize, файлы меньше части - одним запросом.
+// Объект появляется в бакете только после завершения всей загрузки
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	const op = "storage.blob.S3Store.Put"
+
+	_, err := s.client.PutObject(ctx, s.bucket, s.object(key), r, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+		PartSize:    s.partSize,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, 0)
+}
+
+// GetRange читает диапазон объекта запросом GET с заголовком Range
+func (s *S3Store) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	const op = "storage.blob.S3Store.GetRange"
+
+	opts := minio.GetObjectOptions{}
+	if offset > 0 || length > 0 {
+		var end int64
+		if length > 0 {
+			end = offset + length - 1
+		}
+		if err := opts.SetRange(offset, end); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// Core.GetObject сразу выполняет один GET, поэтому ошибки видны здесь, а не при первом чтении
+	core := minio.Core{Client: s.client}
+	body, _, _, err := core.GetObject(ctx, s.bucket, s.object(key), opts)
+	if err != nil {
+		return nil, s3Error(op, err)
+	}
+
+	return body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	const op = "storage.blob.S3Store.Delete"
+
+	if err := s.client.RemoveObject(ctx, s.bucket, s.object(key), minio.RemoveObjectOptions{}); err != nil {
+		return s3Error(op, err)
+	}
+	return nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.S3Store.Stat"
+
+	info, err := s.client.StatObject(ctx, s.bucket, s.object(key), minio.StatObjectOptions{})
+	if err != nil {
+		return Info{}, s3Error(op, err)
+	}
+
+	return Info{Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+// List перечисляет объекты под prefix
+func (s *S3Store) List(ctx context.Context, fn func(key string, info Info) error) error {
+	const op = "storage.blob.S3Store.List"
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix, Recursive: true}) {
+		if object.Err != nil {
+			return s3Error(op, object.Err)
+		}
+		key := strings.TrimPrefix(object.Key, s.prefix)
+		if err := fn(key, Info{Size: object.Size, ModTime: object.LastModified}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func s3Error(op string, err error) error {
+	var response minio.ErrorResponse
+	if errors.As(err, &response) && response.Code == "NoSuchKey" {
+		return fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
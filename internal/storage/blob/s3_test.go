@@ -0,0 +1,317 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"imagestorage/internal/config"
+)
+
+// fakeS3 - минимальный S3 в памяти: PUT/GET с Range/HEAD/DELETE объектов и multipart-загрузки
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]map[int][]byte
+	nextID    int
+	multipart int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path
+	query := r.URL.Query()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[number] = data
+		w.Header().Set("ETag", etag(data))
+
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		id := query.Get("uploadId")
+		parts, ok := f.uploads[id]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, number := range numbers {
+			data = append(data, parts[number]...)
+		}
+		f.objects[key] = data
+		delete(f.uploads, id)
+		f.multipart++
+		bucket, object, _ := strings.Cut(strings.TrimPrefix(key, "/"), "/")
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, bucket, object, etag(data))
+
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = data
+		w.Header().Set("ETag", etag(data))
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", etag(data))
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		status := http.StatusOK
+		if header := r.Header.Get("Range"); header != "" {
+			start, end := parseRange(header, int64(len(data)))
+			if start >= int64(len(data)) {
+				writeS3Error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// readS3Body разбирает тело, в том числе aws-chunked, которым minio подписывает PUT по http
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func parseRange(header string, size int64) (int64, int64) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	from, to, _ := strings.Cut(spec, "-")
+	start, _ := strconv.ParseInt(from, 10, 64)
+	end := size - 1
+	if to != "" {
+		end, _ = strconv.ParseInt(to, 10, 64)
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return start, end
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func newTestS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(config.S3Config{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Region:          "us-east-1",
+		Bucket:          "images",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Prefix:          "test",
+		PartSize:        MinS3PartSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, fake
+}
+
+func TestS3StorePutGetRangeDelete(t *testing.T) {
+	store, fake := newTestS3Store(t)
+	ctx := context.Background()
+
+	data := make([]byte, 11<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	if err := store.Put(ctx, "blobs/big", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if fake.multipart != 1 {
+		t.Fatalf("expected multipart upload, got %d", fake.multipart)
+	}
+	if _, ok := fake.objects["/images/test/blobs/big"]; !ok {
+		t.Fatalf("object stored under unexpected key")
+	}
+
+	info, err := store.Stat(ctx, "blobs/big")
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Fatalf("stat size %d, want %d", info.Size, len(data))
+	}
+
+	ranges := []struct{ offset, length int64 }{
+		{0, 0},
+		{0, 1},
+		{1000, 4096},
+		{int64(len(data)) - 10, 0},
+	}
+	for _, rng := range ranges {
+		reader, err := store.GetRange(ctx, "blobs/big", rng.offset, rng.length)
+		if err != nil {
+			t.Fatalf("get range %v: %v", rng, err)
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("read range %v: %v", rng, err)
+		}
+		want := data[rng.offset:]
+		if rng.length > 0 {
+			want = want[:rng.length]
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("range %v: got %d bytes, want %d", rng, len(got), len(want))
+		}
+	}
+
+	if err := store.Delete(ctx, "blobs/big"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "blobs/big"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("stat after delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "blobs/big"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get after delete: %v", err)
+	}
+}
+
+func TestS3StoreSmallPut(t *testing.T) {
+	store, fake := newTestS3Store(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "small", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if fake.multipart != 0 {
+		t.Fatalf("small object should not use multipart upload")
+	}
+
+	reader, err := store.Get(ctx, "small")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer reader.Close()
+
+	got, _ := io.ReadAll(reader)
+	if string(got) != "hello" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestS3StorePartSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		partSize uint64
+		wantErr  bool
+	}{
+		{name: "unset", partSize: 0, wantErr: true},
+		{name: "below minimum", partSize: MinS3PartSize - 1, wantErr: true},
+		{name: "minimum", partSize: MinS3PartSize},
+		{name: "default", partSize: 16 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewS3Store(config.S3Config{Endpoint: "localhost:9000", Bucket: "images", PartSize: tt.partSize})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
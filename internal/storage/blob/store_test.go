@@ -0,0 +1,203 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// stores - локальные хранилища, которые должны вести себя одинаково
+var stores = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{name: "fs", open: func(t *testing.T) Store { return NewFileStore(t.TempDir()) }},
+	{name: "memory", open: func(t *testing.T) Store { return NewMemoryStore() }},
+}
+
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			test(t, s.open(t))
+		})
+	}
+}
+
+func readAll(t *testing.T, rc io.ReadCloser) []byte {
+	t.Helper()
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestStoreRanges(t *testing.T) {
+	data := []byte("0123456789abcdef")
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{name: "whole blob", want: string(data)},
+		{name: "range inside", offset: 3, length: 4, want: "3456"},
+		{name: "up to the end", offset: 10, want: "abcdef"},
+		{name: "length past the end", offset: 12, length: 100, want: "cdef"},
+		{name: "offset at the end", offset: int64(len(data)), want: ""},
+	}
+
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if err := store.Put(ctx, "blobs/digits", bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range tests {
+			reader, err := store.GetRange(ctx, "blobs/digits", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got := string(readAll(t, reader)); got != tt.want {
+				t.Fatalf("%s: got %q, want %q", tt.name, got, tt.want)
+			}
+		}
+
+		if _, err := store.GetRange(ctx, "blobs/missing", 0, 0); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("range of a missing blob: %v", err)
+		}
+	})
+}
+
+func TestStorePutDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		first, second := []byte("first content"), []byte("second")
+
+		if err := store.Put(ctx, "blobs/a", bytes.NewReader(first), int64(len(first))); err != nil {
+			t.Fatal(err)
+		}
+		// Put с неверным размером не заменяет уже записанный blob
+		if err := store.Put(ctx, "blobs/a", bytes.NewReader(second), int64(len(second))+1); err == nil {
+			t.Fatalf("put with a wrong size succeeded")
+		}
+		reader, err := store.Get(ctx, "blobs/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, reader); !bytes.Equal(got, first) {
+			t.Fatalf("after a failed put: %q", got)
+		}
+		if err := store.Put(ctx, "blobs/b", bytes.NewReader(second), 1); err == nil {
+			t.Fatalf("put with a wrong size succeeded")
+		}
+		if _, err := store.Stat(ctx, "blobs/b"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("blob of a failed put: %v", err)
+		}
+
+		if err := store.Put(ctx, "blobs/a", bytes.NewReader(second), int64(len(second))); err != nil {
+			t.Fatal(err)
+		}
+		info, err := store.Stat(ctx, "blobs/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size != int64(len(second)) || info.ModTime.IsZero() {
+			t.Fatalf("stat after overwrite: %+v", info)
+		}
+
+		if err := store.Delete(ctx, "blobs/a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(ctx, "blobs/a"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: %v", err)
+		}
+		if err := store.Delete(ctx, "blobs/a"); err != nil {
+			t.Fatalf("delete of a missing blob: %v", err)
+		}
+	})
+}
+
+func TestStoreList(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		want := []string{"blobs/aa/a", "blobs/bb/b", "variants/c"}
+		for _, key := range want {
+			if err := store.Put(ctx, key, bytes.NewReader([]byte(key)), int64(len(key))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Недописанные Put и staging-каталог FileStore в список не попадают
+		if fs, ok := store.(*FileStore); ok {
+			for _, name := range []string{".staging/session", "blobs/aa/.tmp-123"} {
+				path := fs.path(name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		var keys []string
+		err := store.(Lister).List(ctx, func(key string, info Info) error {
+			if info.Size != int64(len(key)) {
+				t.Fatalf("%s: size %d", key, info.Size)
+			}
+			keys = append(keys, key)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(keys)
+		if len(keys) != len(want) {
+			t.Fatalf("listed %v, want %v", keys, want)
+		}
+		for i := range want {
+			if keys[i] != want[i] {
+				t.Fatalf("listed %v, want %v", keys, want)
+			}
+		}
+
+		errStop := errors.New("stop")
+		if err := store.(Lister).List(ctx, func(string, Info) error { return errStop }); !errors.Is(err, errStop) {
+			t.Fatalf("list stopped with %v", err)
+		}
+	})
+}
+
+func TestFileStorePutFile(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	ctx := context.Background()
+
+	staged := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(staged, []byte("staged upload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutFile(ctx, "blobs/aa/staged", staged); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(staged); !os.IsNotExist(err) {
+		t.Fatalf("staged file is left after PutFile: %v", err)
+	}
+
+	reader, err := store.Get(ctx, "blobs/aa/staged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(readAll(t, reader)); got != "staged upload" {
+		t.Fatalf("got %q", got)
+	}
+	if err := store.PutFile(ctx, "blobs/aa/missing", staged); err == nil {
+		t.Fatalf("put of a missing file succeeded")
+	}
+}
@@ -0,0 +1,387 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"imagestorage/internal/mimetype"
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// Бакеты с записями. Ключ files и blob id - id записи в big-endian, чтобы курсор шел по порядку id.
+// В versionsBucket по имени файла лежит последний выданный номер версии в big-endian
+var (
+	filesBucket    = []byte("files")
+	blobsBucket    = []byte("blobs")
+	sessionsBucket = []byte("upload_sessions")
+	versionsBucket = []byte("file_versions")
+)
+
+// Вторичные индексы files. Значение пустое, id записи - последние 8 байт ключа:
+// filename\x00version\x00id, created_at\x00id, checksum\x00id
+var (
+	filenameIndex  = []byte("idx_files_filename")
+	createdAtIndex = []byte("idx_files_created_at")
+	checksumIndex  = []byte("idx_files_checksum")
+)
+
+var allBuckets = [][]byte{filesBucket, blobsBucket, sessionsBucket, versionsBucket, filenameIndex, createdAtIndex, checksumIndex}
+
+// Storage - каталог файлов во встроенной базе bbolt, без cgo. Типы и ошибки - из пакета catalog
+type Storage struct {
+	db *bbolt.DB
+}
+
+var _ catalog.IStorage = (*Storage)(nil)
+
+// fileRecord - запись files. BlobChecksum пустой у файлов, загруженных до появления blob-ов
+type fileRecord struct {
+	ID       int64  `json:"id"`
+	FileName string `json:"filename"`
+	Path     string `json:"path_to_file"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	// DeclaredMimeType - тип по расширению, если он противоречит содержимому
+	DeclaredMimeType string      `json:"declared_mime_type,omitempty"`
+	Checksum         string      `json:"checksum"`
+	BlobID           int64       `json:"blob_id,omitempty"`
+	BlobChecksum     string      `json:"blob_checksum,omitempty"`
+	Encoding         string      `json:"encoding"`
+	StoredSize       int64       `json:"stored_size"`
+	Version          int64       `json:"version"`
+	PreviousID       int64       `json:"previous_id,omitempty"`
+	Namespace        string      `json:"namespace"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+	DeletedAt        *time.Time  `json:"deleted_at,omitempty"`
+	Exif             *exifRecord `json:"exif,omitempty"`
+}
+
+// blobRecord - запись blobs, ключ - checksum. Replicas - каталоги данных с копиями blob-а,
+// Shards - его фрагменты, Moves - история переносов между hot и cold
+type blobRecord struct {
+	ID             int64     `json:"id"`
+	Checksum       string    `json:"checksum"`
+	Size           int64     `json:"size"`
+	RefCount       int64     `json:"ref_count"`
+	Encoding       string    `json:"encoding"`
+	StoredSize     int64     `json:"stored_size"`
+	KeyID          string    `json:"key_id,omitempty"`
+	WrappedKey     []byte    `json:"wrapped_key,omitempty"`
+	StoredChecksum string    `json:"stored_checksum,omitempty"`
+	Replicas       []string  `json:"replicas,omitempty"`
+	Shards         []shard   `json:"shards,omitempty"`
+	Tier           string    `json:"tier,omitempty"`
+	LastAccessedAt time.Time `json:"last_accessed_at,omitempty"`
+	Moves          []move    `json:"moves,omitempty"`
+	Variants       []variant `json:"variants,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type shard struct {
+	Index     int       `json:"index"`
+	Location  string    `json:"location"`
+	Health    string    `json:"health"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// storedChecksum - sha256 blob-а в хранилище. У записей, созданных до появления поля, он известен
+// только для несжатого и незашифрованного содержимого
+func (b blobRecord) storedChecksum() string {
+	if b.StoredChecksum == "" && b.Encoding == "" && b.KeyID == "" {
+		return b.Checksum
+	}
+	return b.StoredChecksum
+}
+
+// tier - хранилище blob-а, у записей без поля - hot
+func (b blobRecord) tier() string {
+	if b.Tier == "" {
+		return catalog.TierHot
+	}
+	return b.Tier
+}
+
+// lastAccessedAt - последнее скачивание blob-а, у записей без поля - его создание
+func (b blobRecord) lastAccessedAt() time.Time {
+	if b.LastAccessedAt.IsZero() {
+		return b.CreatedAt
+	}
+	return b.LastAccessedAt
+}
+
+// New открывает или создает файл базы. Миграции не нужны: бакеты создаются при открытии
+func New(storagePath string) (*Storage, error) {
+	const op = "storage.bolt.New"
+
+	db, err := bbolt.Open(storagePath, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// SaveImage добавляет новую версию файла и берет ссылку на blob с тем же checksum.
+// declaredMimeType задается, только если расширение противоречит содержимому.
+// Возвращает номер созданной версии и то, заведен ли blob этой загрузкой. Если нет, в хранилище
+// уже лежит содержимое существующего blob-а, а загруженная копия не нужна
+func (s *Storage) SaveImage(imageName string, namespace string, pathToFile string, size int, mimeType string, declaredMimeType string, checksum string, stored catalog.StoredBlob, createdAt time.Time) (int64, bool, error) {
+	const op = "storage.bolt.SaveImage"
+
+	var version int64
+	var created bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		// Для уже сохраненного содержимого берется сжатие и ключ blob-а, а не загруженной копии
+		blob, isNew, err := acquireBlob(tx, checksum, int64(size), stored)
+		if err != nil {
+			return err
+		}
+
+		previous, found, err := latestVersion(tx, imageName, true)
+		if err != nil {
+			return err
+		}
+
+		next, err := nextVersion(tx, imageName, previous.Version)
+		if err != nil {
+			return err
+		}
+
+		id, err := tx.Bucket(filesBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		createdAt = truncateTime(createdAt)
+		file := fileRecord{
+			ID:               int64(id),
+			FileName:         imageName,
+			Path:             pathToFile,
+			Size:             int64(size),
+			MimeType:         mimeType,
+			DeclaredMimeType: declaredMimeType,
+			Checksum:         checksum,
+			BlobID:           blob.ID,
+			BlobChecksum:     blob.Checksum,
+			Encoding:         blob.Encoding,
+			StoredSize:       blob.StoredSize,
+			Version:          next,
+			Namespace:        namespace,
+			CreatedAt:        createdAt,
+			UpdatedAt:        createdAt,
+		}
+		if found {
+			file.PreviousID = previous.ID
+		}
+		version, created = file.Version, isNew
+
+		return putFile(tx, nil, file)
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, created, nil
+}
+
+// nextVersion выдает следующий номер версии файла. Счетчик purge не уменьшает, поэтому номер окончательно
+// удаленной версии не повторяется. В базах, созданных до счетчика, его заменяет latest - последняя версия
+func nextVersion(tx *bbolt.Tx, fileName string, latest int64) (int64, error) {
+	bucket := tx.Bucket(versionsBucket)
+	last := latest
+	if data := bucket.Get([]byte(fileName)); data != nil {
+		last = max(last, int64(binary.BigEndian.Uint64(data)))
+	}
+
+	return last + 1, putLastVersion(tx, fileName, last+1)
+}
+
+func putLastVersion(tx *bbolt.Tx, fileName string, version int64) error {
+	return tx.Bucket(versionsBucket).Put([]byte(fileName), binary.BigEndian.AppendUint64(nil, uint64(version)))
+}
+
+func (s *Storage) FindFileByName(fileName string) (string, error) {
+	const op = "storage.bolt.FindFileByName"
+
+	var found bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		_, found, err = latestVersion(tx, fileName, false)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to find file: %w", op, err)
+	}
+
+	if !found {
+		return "", nil
+	}
+	return fileName, nil
+}
+
+// GetFileInfo возвращает метаданные версии файла (version 0 - последняя)
+func (s *Storage) GetFileInfo(fileName string, version int64) (catalog.FileInfo, error) {
+	const op = "storage.bolt.GetFileInfo"
+
+	var info catalog.FileInfo
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		file, err := findVersion(tx, fileName, version)
+		if err != nil {
+			return err
+		}
+		info, err = file.info(tx)
+		return err
+	})
+	if err != nil {
+		return catalog.FileInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return info, nil
+}
+
+// findVersion возвращает неудаленную версию файла (version 0 - последнюю) или catalog.ErrFileNotFound
+func findVersion(tx *bbolt.Tx, fileName string, version int64) (fileRecord, error) {
+	if version == 0 {
+		file, found, err := latestVersion(tx, fileName, false)
+		if err != nil {
+			return fileRecord{}, err
+		}
+		if !found {
+			return fileRecord{}, catalog.ErrFileNotFound
+		}
+		return file, nil
+	}
+
+	c := tx.Bucket(filenameIndex).Cursor()
+	prefix := filenameVersionPrefix(fileName, version)
+	for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+		if !isFileNameKey(k, filenamePrefix(fileName)) {
+			continue
+		}
+		file, err := getFile(tx, indexedID(k))
+		if err != nil {
+			return fileRecord{}, err
+		}
+		if file.DeletedAt == nil {
+			return file, nil
+		}
+	}
+
+	return fileRecord{}, catalog.ErrFileNotFound
+}
+
+// info собирает метаданные файла, хранилище и последнее скачивание берутся из его blob-а
+// mimeType - тип содержимого. Раньше в mime_type сохранялось расширение, для таких записей тип
+// выводится из него
+func (f fileRecord) mimeType() string {
+	if !strings.HasPrefix(f.MimeType, ".") && f.MimeType != "" {
+		return f.MimeType
+	}
+	if mimeType := mimetype.ByExtension(f.MimeType); mimeType != "" {
+		return mimeType
+	}
+	return mimetype.Unknown
+}
+
+func (f fileRecord) info(tx *bbolt.Tx) (catalog.FileInfo, error) {
+	info := catalog.FileInfo{
+		ID:               f.ID,
+		FileName:         f.FileName,
+		Version:          f.Version,
+		Size:             f.Size,
+		MimeType:         f.mimeType(),
+		DeclaredMimeType: f.DeclaredMimeType,
+		Checksum:         f.Checksum,
+		Encoding:         f.Encoding,
+		StoredSize:       f.StoredSize,
+		CreatedAt:        f.CreatedAt,
+		UpdatedAt:        f.UpdatedAt,
+	}
+	if f.DeletedAt != nil {
+		info.DeletedAt = *f.DeletedAt
+	}
+
+	info.Tier = catalog.TierHot
+	if f.BlobChecksum == "" {
+		return info, nil
+	}
+	blob, found, err := getBlob(tx, f.BlobChecksum)
+	if err != nil || !found {
+		return info, err
+	}
+	info.Tier = blob.tier()
+	info.LastAccessedAt = blob.lastAccessedAt()
+
+	return info, nil
+}
+
+func getFile(tx *bbolt.Tx, id int64) (fileRecord, error) {
+	var file fileRecord
+	data := tx.Bucket(filesBucket).Get(idKey(id))
+	if data == nil {
+		return fileRecord{}, catalog.ErrFileNotFound
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fileRecord{}, err
+	}
+	return file, nil
+}
+
+func getBlob(tx *bbolt.Tx, checksum string) (blobRecord, bool, error) {
+	var blob blobRecord
+	data := tx.Bucket(blobsBucket).Get([]byte(checksum))
+	if data == nil {
+		return blobRecord{}, false, nil
+	}
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return blobRecord{}, false, err
+	}
+	return blob, true, nil
+}
+
+func putBlob(tx *bbolt.Tx, blob blobRecord) error {
+	data, err := json.Marshal(blob)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(blobsBucket).Put([]byte(blob.Checksum), data)
+}
+
+// truncateTime отбрасывает доли секунды, как CURRENT_TIMESTAMP в SQLite
+func truncateTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+func now() time.Time {
+	return truncateTime(time.Now())
+}
+
+func idKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+var errStopIteration = errors.New("stop iteration")
@@ -0,0 +1,331 @@
+package bolt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	st, err := New(filepath.Join(t.TempDir(), "catalog.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func checksum(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+func save(t *testing.T, st *Storage, fileName string, sum string, createdAt time.Time) int64 {
+	t.Helper()
+
+	version, _, err := st.SaveImage(fileName, "", "blobs/"+sum, 10, "image/png", "", sum, catalog.StoredBlob{StoredSize: 10}, createdAt)
+	if err != nil {
+		t.Fatalf("save %s: %v", fileName, err)
+	}
+	return version
+}
+
+// checkIndexes сверяет вторичные индексы с записями files: у каждой записи ровно ее ключи, лишних нет
+func checkIndexes(t *testing.T, st *Storage) {
+	t.Helper()
+
+	err := st.db.View(func(tx *bbolt.Tx) error {
+		want := make(map[string][]string)
+		err := forEachFile(tx, func(file fileRecord) error {
+			for _, index := range fileIndexes(file) {
+				want[string(index.bucket)] = append(want[string(index.bucket)], string(index.key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, bucket := range [][]byte{filenameIndex, createdAtIndex, checksumIndex} {
+			var got []string
+			err := tx.Bucket(bucket).ForEach(func(k, _ []byte) error {
+				got = append(got, string(k))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			expected := want[string(bucket)]
+			slices.Sort(expected)
+			if !slices.Equal(got, expected) {
+				t.Fatalf("%s has %d keys, records need %d: %q vs %q", bucket, len(got), len(expected), got, expected)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexesFollowRecords(t *testing.T) {
+	st := newTestStorage(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name  string
+		apply func() error
+	}{
+		{name: "new files", apply: func() error {
+			save(t, st, "a.png", checksum(1), start)
+			save(t, st, "b.png", checksum(2), start.Add(time.Hour))
+			return nil
+		}},
+		{name: "new versions", apply: func() error {
+			save(t, st, "a.png", checksum(3), start.Add(2*time.Hour))
+			save(t, st, "a.png", checksum(1), start.Add(3*time.Hour))
+			return nil
+		}},
+		{name: "soft delete", apply: func() error {
+			_, err := st.SoftDeleteFile("a.png", 2)
+			return err
+		}},
+		{name: "restore", apply: func() error {
+			_, err := st.RestoreFile("a.png", 0)
+			return err
+		}},
+		{name: "move file", apply: func() error {
+			file, err := st.GetFileInfo("b.png", 0)
+			if err != nil {
+				return err
+			}
+			return st.UpdateFilePath(file.ID, "blobs/00/00/"+checksum(2))
+		}},
+		{name: "exif", apply: func() error {
+			file, err := st.GetFileInfo("a.png", 1)
+			if err != nil {
+				return err
+			}
+			return st.SaveFileExif(file.ID, catalog.Exif{CameraMake: "Canon"})
+		}},
+		{name: "purge version", apply: func() error {
+			if _, err := st.SoftDeleteFile("a.png", 2); err != nil {
+				return err
+			}
+			file, err := st.FindFileLocation("a.png", 1)
+			if err != nil {
+				return err
+			}
+			deleted, err := st.ListDeletedFiles(time.Now().Add(time.Minute), "a.png")
+			if err != nil || len(deleted) != 1 {
+				return fmt.Errorf("deleted versions %v: %v", deleted, err)
+			}
+			purged, _, err := st.PurgeFile(deleted[0].ID, deleted[0].Checksum, time.Now().Add(time.Minute))
+			if err != nil || !purged {
+				return fmt.Errorf("purge: %v %v", purged, err)
+			}
+			// Версия 1 осталась на месте
+			if _, err := st.FindFileLocation("a.png", 1); err != nil {
+				return fmt.Errorf("version 1 of %s lost: %v", file.FileName, err)
+			}
+			return nil
+		}},
+	}
+
+	for _, step := range steps {
+		if err := step.apply(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkIndexes(t, st)
+	}
+}
+
+func TestLatestVersionNamePrefixes(t *testing.T) {
+	st := newTestStorage(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Имена с общим началом, в том числе с нулевым байтом и восемью байтами после него,
+	// как у ключа версии в filenameIndex
+	names := []string{"a", "a\x00b", "a\x00\x00\x00\x00\x00\x00\x00\x00\x09", "a.png", "ab"}
+	for i, name := range names {
+		for v := 0; v <= i; v++ {
+			save(t, st, name, checksum(10*i+v), start.Add(time.Duration(v)*time.Minute))
+		}
+	}
+	if _, err := st.SoftDeleteFile("ab", int64(len(names))); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range names {
+		want := int64(i + 1)
+		if name == "ab" {
+			want--
+		}
+		file, err := st.GetFileInfo(name, 0)
+		if err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		if file.FileName != name || file.Version != want {
+			t.Fatalf("%q: latest is %q v%d, want v%d", name, file.FileName, file.Version, want)
+		}
+
+		history, err := st.ListFiles(catalog.ListFilesFilter{FileName: name, IncludeDeleted: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != i+1 {
+			t.Fatalf("%q: %d versions in history, want %d", name, len(history), i+1)
+		}
+		for _, version := range history {
+			if version.FileName != name {
+				t.Fatalf("%q: history has a version of %q", name, version.FileName)
+			}
+		}
+	}
+}
+
+func TestCreatedAtKeyOrder(t *testing.T) {
+	times := []time.Time{
+		time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Unix(0, 0),
+		time.Unix(1, 0),
+		time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+		time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for i := 1; i < len(times); i++ {
+		if bytes.Compare(createdAtKey(times[i-1]), createdAtKey(times[i])) >= 0 {
+			t.Fatalf("key of %s does not sort before key of %s", times[i-1], times[i])
+		}
+	}
+}
+
+func TestListFilesByIndex(t *testing.T) {
+	st := newTestStorage(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		save(t, st, fmt.Sprintf("img-%02d.png", i), checksum(i%5), start.Add(time.Duration(i)*time.Hour))
+	}
+
+	tests := []struct {
+		name   string
+		filter catalog.ListFilesFilter
+		want   []string
+	}{
+		{name: "prefix", filter: catalog.ListFilesFilter{NamePrefix: "img-1", Limit: 3}, want: []string{"img-10.png", "img-11.png", "img-12.png"}},
+		{name: "created range", filter: catalog.ListFilesFilter{CreatedAfter: start.Add(17 * time.Hour), CreatedBefore: start.Add(19 * time.Hour)}, want: []string{"img-17.png", "img-18.png"}},
+		{name: "created descending", filter: catalog.ListFilesFilter{SortBy: catalog.SortByCreated, Descending: true, Limit: 2}, want: []string{"img-19.png", "img-18.png"}},
+		{name: "before the first", filter: catalog.ListFilesFilter{CreatedBefore: start}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := st.ListFiles(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				got = append(got, file.FileName)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotRebuildsIndexes(t *testing.T) {
+	st := newTestStorage(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		save(t, st, "a.png", checksum(i%2), start.Add(time.Duration(i)*time.Minute))
+		save(t, st, fmt.Sprintf("%d.png", i), checksum(i), start)
+	}
+	if _, err := st.SoftDeleteFile("a.png", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := st.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied := newTestStorage(t)
+	if err := copied.Import(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexes(t, copied)
+	if err := copied.Import(snapshot); !errors.Is(err, catalog.ErrCatalogNotEmpty) {
+		t.Fatalf("second import: %v", err)
+	}
+
+	// После импорта номера версий и id продолжаются
+	if version := save(t, copied, "a.png", checksum(9), start); version != 6 {
+		t.Fatalf("next version after import is %d, want 6", version)
+	}
+	file, err := copied.GetFileInfo("a.png", 3)
+	if !errors.Is(err, catalog.ErrFileNotFound) {
+		t.Fatalf("deleted version is visible after import: %+v %v", file, err)
+	}
+	checkIndexes(t, copied)
+}
+
+// TestSnapshotKeepsVersionCounters переносит счетчики версий: номер удаленной до выгрузки версии не повторяется
+func TestSnapshotKeepsVersionCounters(t *testing.T) {
+	st := newTestStorage(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		save(t, st, "a.png", checksum(i), start)
+	}
+	if _, err := st.SoftDeleteFile("a.png", 3); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().Add(time.Hour)
+	deleted, err := st.ListDeletedFiles(before, "a.png")
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("deleted %v, %v", deleted, err)
+	}
+	if removed, _, err := st.PurgeFile(deleted[0].ID, deleted[0].Checksum, before); err != nil || !removed {
+		t.Fatalf("purge: removed %v, %v", removed, err)
+	}
+
+	snapshot, err := st.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(snapshot.FileVersions, []catalog.FileVersion{{FileName: "a.png", LastVersion: 3}}) {
+		t.Fatalf("exported counters %v", snapshot.FileVersions)
+	}
+
+	tests := []struct {
+		name     string
+		versions []catalog.FileVersion
+		want     int64
+	}{
+		{name: "with counters", versions: snapshot.FileVersions, want: 4},
+		// Выгрузка без счетчиков продолжает с наибольшей оставшейся версии
+		{name: "without counters", want: 3},
+	}
+	for _, tt := range tests {
+		copied := newTestStorage(t)
+		imported := snapshot
+		imported.FileVersions = tt.versions
+		if err := copied.Import(imported); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if version := save(t, copied, "a.png", checksum(9), start); version != tt.want {
+			t.Fatalf("%s: next version %d, want %d", tt.name, version, tt.want)
+		}
+	}
+}
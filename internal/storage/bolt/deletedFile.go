@@ -0,0 +1,203 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// SoftDeleteFile помечает версию файла удаленной (version 0 - все версии).
+// Возвращает количество помеченных версий
+func (s *Storage) SoftDeleteFile(fileName string, version int64) (int64, error) {
+	const op = "storage.bolt.SoftDeleteFile"
+
+	affected, err := s.updateVersions(fileName, version, func(file *fileRecord) bool {
+		if file.DeletedAt != nil {
+			return false
+		}
+		deletedAt := now()
+		file.DeletedAt = &deletedAt
+		file.UpdatedAt = deletedAt
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// RestoreFile снимает пометку об удалении (version 0 - все версии)
+func (s *Storage) RestoreFile(fileName string, version int64) (int64, error) {
+	const op = "storage.bolt.RestoreFile"
+
+	affected, err := s.updateVersions(fileName, version, func(file *fileRecord) bool {
+		if file.DeletedAt == nil {
+			return false
+		}
+		file.DeletedAt = nil
+		file.UpdatedAt = now()
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// updateVersions применяет update к версии файла (version 0 - ко всем) и сохраняет те,
+// для которых update вернул true. Возвращает количество сохраненных
+func (s *Storage) updateVersions(fileName string, version int64, update func(file *fileRecord) bool) (int64, error) {
+	var affected int64
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		// Индекс нельзя менять, пока по нему идет курсор: сначала собираем версии
+		var files []fileRecord
+		err := forEachVersion(tx, fileName, func(file fileRecord) error {
+			if version == 0 || file.Version == version {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, old := range files {
+			file := old
+			if !update(&file) {
+				continue
+			}
+			if err := putFile(tx, &old, file); err != nil {
+				return err
+			}
+			affected++
+		}
+		return nil
+	})
+
+	return affected, err
+}
+
+// ListDeletedFiles возвращает файлы, удаленные не позже before. Пустой fileName - все файлы
+func (s *Storage) ListDeletedFiles(before time.Time, fileName string) ([]catalog.DeletedFile, error) {
+	const op = "storage.bolt.ListDeletedFiles"
+
+	before = truncateTime(before)
+
+	var files []catalog.DeletedFile
+	collect := func(file fileRecord) error {
+		if file.DeletedAt != nil && !file.DeletedAt.After(before) {
+			files = append(files, catalog.DeletedFile{
+				ID:        file.ID,
+				FileName:  file.FileName,
+				Version:   file.Version,
+				Checksum:  file.BlobChecksum,
+				Path:      file.Path,
+				DeletedAt: *file.DeletedAt,
+			})
+		}
+		return nil
+	}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if fileName != "" {
+			return forEachVersion(tx, fileName, collect)
+		}
+		return forEachFile(tx, collect)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return files, nil
+}
+
+// PurgeFile окончательно удаляет запись о файле и отпускает ссылку на его blob.
+// Запись удаляется, только если она все еще помечена удаленной не позже before.
+// Возвращает, была ли запись удалена и была ли это последняя ссылка на blob
+func (s *Storage) PurgeFile(id int64, checksum string, before time.Time) (bool, bool, error) {
+	const op = "storage.bolt.PurgeFile"
+
+	before = truncateTime(before)
+
+	var purged, lastRef bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		file, err := getFile(tx, id)
+		if err == catalog.ErrFileNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if file.DeletedAt == nil || file.DeletedAt.After(before) {
+			// файл успели восстановить
+			return nil
+		}
+
+		purged = true
+		lastRef, err = removeFile(tx, file, checksum)
+		return err
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, lastRef, nil
+}
+
+// DiscardFile удаляет только что записанную версию файла, не помечая ее удаленной, и отпускает ссылку
+// на ее blob. Так откатывается загрузка, чье содержимое не дошло до хранилища.
+// Возвращает, была ли это последняя ссылка на blob
+func (s *Storage) DiscardFile(fileName string, version int64) (bool, error) {
+	const op = "storage.bolt.DiscardFile"
+
+	var lastRef bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		file, err := findVersion(tx, fileName, version)
+		if err != nil {
+			return err
+		}
+		lastRef, err = removeFile(tx, file, file.BlobChecksum)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lastRef, nil
+}
+
+// removeFile удаляет запись файла, снимает ссылки на нее у следующих версий и отпускает blob checksum
+func removeFile(tx *bbolt.Tx, file fileRecord, checksum string) (bool, error) {
+	if err := deleteFile(tx, file); err != nil {
+		return false, err
+	}
+
+	// previous_id ссылается только на версии того же файла
+	var next []fileRecord
+	err := forEachVersion(tx, file.FileName, func(version fileRecord) error {
+		if version.PreviousID == file.ID {
+			next = append(next, version)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, old := range next {
+		version := old
+		version.PreviousID = 0
+		if err := putFile(tx, &old, version); err != nil {
+			return false, err
+		}
+	}
+
+	if checksum == "" {
+		return false, nil
+	}
+	return releaseBlob(tx, checksum)
+}
@@ -0,0 +1,58 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// ListWrappedKeys возвращает ключи данных всех зашифрованных blob-ов
+func (s *Storage) ListWrappedKeys() ([]catalog.WrappedKey, error) {
+	const op = "storage.bolt.ListWrappedKeys"
+
+	var keys []catalog.WrappedKey
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(blobsBucket).ForEach(func(_, data []byte) error {
+			var blob blobRecord
+			if err := json.Unmarshal(data, &blob); err != nil {
+				return err
+			}
+			if blob.KeyID != "" {
+				keys = append(keys, catalog.WrappedKey{Checksum: blob.Checksum, KeyID: blob.KeyID, WrappedKey: blob.WrappedKey})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// UpdateWrappedKey заменяет ключ данных blob-а, закрытый мастер-ключом oldKeyID, на wrapped под keyID.
+// Возвращает false, если ключ blob-а уже поменялся или blob удален
+func (s *Storage) UpdateWrappedKey(checksum string, oldKeyID string, keyID string, wrapped []byte) (bool, error) {
+	const op = "storage.bolt.UpdateWrappedKey"
+
+	var updated bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, checksum)
+		if err != nil || !found || blob.KeyID != oldKeyID {
+			return err
+		}
+
+		blob.KeyID = keyID
+		blob.WrappedKey = wrapped
+		updated = true
+		return putBlob(tx, blob)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updated, nil
+}
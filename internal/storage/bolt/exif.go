@@ -0,0 +1,102 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// exifRecord хранится в записи файла, поэтому уходит вместе с ней при окончательном удалении
+type exifRecord struct {
+	CameraMake  string    `json:"camera_make,omitempty"`
+	CameraModel string    `json:"camera_model,omitempty"`
+	LensMake    string    `json:"lens_make,omitempty"`
+	LensModel   string    `json:"lens_model,omitempty"`
+	CapturedAt  time.Time `json:"captured_at"`
+	Orientation int       `json:"orientation,omitempty"`
+	// GPS == nil - координат нет
+	GPS *[3]float64 `json:"gps,omitempty"`
+}
+
+func toExifRecord(exif *catalog.Exif) *exifRecord {
+	if exif == nil {
+		return nil
+	}
+	record := &exifRecord{
+		CameraMake:  exif.CameraMake,
+		CameraModel: exif.CameraModel,
+		LensMake:    exif.LensMake,
+		LensModel:   exif.LensModel,
+		CapturedAt:  truncateTime(exif.CapturedAt),
+		Orientation: exif.Orientation,
+	}
+	if exif.HasGPS {
+		record.GPS = &[3]float64{exif.Latitude, exif.Longitude, exif.Altitude}
+	}
+	return record
+}
+
+func (r *exifRecord) exif() *catalog.Exif {
+	if r == nil {
+		return nil
+	}
+	exif := &catalog.Exif{
+		CameraMake:  r.CameraMake,
+		CameraModel: r.CameraModel,
+		LensMake:    r.LensMake,
+		LensModel:   r.LensModel,
+		CapturedAt:  r.CapturedAt,
+		Orientation: r.Orientation,
+	}
+	if r.GPS != nil {
+		exif.HasGPS = true
+		exif.Latitude, exif.Longitude, exif.Altitude = r.GPS[0], r.GPS[1], r.GPS[2]
+	}
+	return exif
+}
+
+// SaveFileExif записывает метаданные EXIF версии файла fileID, заменяя прежние
+func (s *Storage) SaveFileExif(fileID int64, exif catalog.Exif) error {
+	const op = "storage.bolt.SaveFileExif"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		file, err := getFile(tx, fileID)
+		if err != nil {
+			return err
+		}
+		old := file
+		file.Exif = toExifRecord(&exif)
+		return putFile(tx, &old, file)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetFileExif возвращает метаданные EXIF версии файла fileID
+func (s *Storage) GetFileExif(fileID int64) (catalog.Exif, error) {
+	const op = "storage.bolt.GetFileExif"
+
+	var exif *catalog.Exif
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		file, err := getFile(tx, fileID)
+		if err != nil {
+			return err
+		}
+		exif = file.Exif.exif()
+		return nil
+	})
+	if err != nil {
+		return catalog.Exif{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if exif == nil {
+		return catalog.Exif{}, catalog.ErrExifNotFound
+	}
+
+	return *exif, nil
+}
@@ -0,0 +1,154 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// putFile сохраняет запись и переставляет ее ключи во вторичных индексах. old == nil - новая запись
+func putFile(tx *bbolt.Tx, old *fileRecord, file fileRecord) error {
+	if old != nil {
+		if err := deleteIndexes(tx, *old); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(filesBucket).Put(idKey(file.ID), data); err != nil {
+		return err
+	}
+
+	for _, index := range fileIndexes(file) {
+		if err := tx.Bucket(index.bucket).Put(index.key, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteFile удаляет запись вместе с ее ключами в индексах
+func deleteFile(tx *bbolt.Tx, file fileRecord) error {
+	if err := deleteIndexes(tx, file); err != nil {
+		return err
+	}
+	return tx.Bucket(filesBucket).Delete(idKey(file.ID))
+}
+
+func deleteIndexes(tx *bbolt.Tx, file fileRecord) error {
+	for _, index := range fileIndexes(file) {
+		if err := tx.Bucket(index.bucket).Delete(index.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type indexKey struct {
+	bucket []byte
+	key    []byte
+}
+
+func fileIndexes(file fileRecord) []indexKey {
+	id := idKey(file.ID)
+
+	indexes := []indexKey{
+		{filenameIndex, append(filenameVersionPrefix(file.FileName, file.Version), id...)},
+		{createdAtIndex, append(createdAtKey(file.CreatedAt), id...)},
+	}
+	if file.Checksum != "" {
+		indexes = append(indexes, indexKey{checksumIndex, append(checksumPrefix(file.Checksum), id...)})
+	}
+
+	return indexes
+}
+
+// filenamePrefix - начало ключей всех версий файла в filenameIndex
+func filenamePrefix(fileName string) []byte {
+	return append([]byte(fileName), 0)
+}
+
+func filenameVersionPrefix(fileName string, version int64) []byte {
+	return append(binary.BigEndian.AppendUint64(filenamePrefix(fileName), uint64(version)), 0)
+}
+
+// createdAtKey - секунды со сдвигом, чтобы время до 1970 года тоже шло по порядку
+func createdAtKey(t time.Time) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, uint64(t.Unix())^(1<<63)), 0)
+}
+
+func checksumPrefix(checksum string) []byte {
+	return append([]byte(checksum), 0)
+}
+
+// indexedID достает id записи из ключа индекса
+func indexedID(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[len(key)-8:]))
+}
+
+func hasPrefix(key []byte, prefix []byte) bool {
+	return bytes.HasPrefix(key, prefix)
+}
+
+// latestVersion находит последнюю версию файла по filenameIndex. С includeDeleted == false
+// удаленные версии пропускаются
+func latestVersion(tx *bbolt.Tx, fileName string, includeDeleted bool) (fileRecord, bool, error) {
+	c := tx.Bucket(filenameIndex).Cursor()
+	prefix := filenamePrefix(fileName)
+
+	// Встаем за последний возможный ключ файла и идем назад
+	k, _ := c.Seek(filenameVersionPrefix(fileName, math.MaxInt64))
+	if k == nil {
+		k, _ = c.Last()
+	} else {
+		k, _ = c.Prev()
+	}
+
+	for ; k != nil && hasPrefix(k, prefix); k, _ = c.Prev() {
+		if !isFileNameKey(k, prefix) {
+			continue
+		}
+		file, err := getFile(tx, indexedID(k))
+		if err != nil {
+			return fileRecord{}, false, err
+		}
+		if includeDeleted || file.DeletedAt == nil {
+			return file, true, nil
+		}
+	}
+
+	return fileRecord{}, false, nil
+}
+
+// isFileNameKey проверяет, что ключ filenameIndex относится именно к файлу с prefix,
+// а не к файлу, имя которого начинается с того же имени и нулевого байта
+func isFileNameKey(key []byte, prefix []byte) bool {
+	return len(key) == len(prefix)+17 && hasPrefix(key, prefix)
+}
+
+// forEachVersion вызывает fn для всех версий файла по возрастанию
+func forEachVersion(tx *bbolt.Tx, fileName string, fn func(file fileRecord) error) error {
+	c := tx.Bucket(filenameIndex).Cursor()
+	prefix := filenamePrefix(fileName)
+	for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+		if !isFileNameKey(k, prefix) {
+			continue
+		}
+		file, err := getFile(tx, indexedID(k))
+		if err != nil {
+			return err
+		}
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
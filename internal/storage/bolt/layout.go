@@ -0,0 +1,113 @@
+package bolt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// ListFileLocations возвращает расположение содержимого всех записей, включая удаленные.
+// Реплики и фрагменты не заполняются
+func (s *Storage) ListFileLocations() ([]catalog.FileLocation, error) {
+	const op = "storage.bolt.ListFileLocations"
+
+	var locations []catalog.FileLocation
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return forEachFile(tx, func(file fileRecord) error {
+			location := catalog.FileLocation{
+				ID:       file.ID,
+				FileName: file.FileName,
+				Version:  file.Version,
+				Checksum: file.BlobChecksum,
+				Path:     file.Path,
+				Size:     file.Size,
+				StoredBlob: catalog.StoredBlob{
+					Encoding:   file.Encoding,
+					StoredSize: file.StoredSize,
+					Tier:       catalog.TierHot,
+				},
+			}
+			if file.BlobChecksum != "" {
+				blob, found, err := getBlob(tx, file.BlobChecksum)
+				if err != nil {
+					return err
+				}
+				if found {
+					location.KeyID = blob.KeyID
+					location.StoredChecksum = blob.storedChecksum()
+					location.Tier = blob.tier()
+				}
+			}
+			locations = append(locations, location)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return locations, nil
+}
+
+// HasFilePath проверяет, ссылается ли на path хоть одна запись files, включая удаленные, или вариант blob-а.
+// Индекса по path_to_file нет, записи просматриваются целиком
+func (s *Storage) HasFilePath(path string) (bool, error) {
+	const op = "storage.bolt.HasFilePath"
+
+	var found bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		err := forEachFile(tx, func(file fileRecord) error {
+			if file.Path == path {
+				found = true
+				return errStopIteration
+			}
+			return nil
+		})
+		if found || err != nil {
+			return err
+		}
+
+		found, err = hasVariantPath(tx, path)
+		return err
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return found, nil
+}
+
+func (s *Storage) UpdateFilePath(id int64, path string) error {
+	const op = "storage.bolt.UpdateFilePath"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		file, err := getFile(tx, id)
+		if err != nil {
+			return err
+		}
+
+		// path_to_file не входит в индексы
+		file.Path = path
+		return putFile(tx, &file, file)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// forEachFile обходит все записи files по возрастанию id
+func forEachFile(tx *bbolt.Tx, fn func(file fileRecord) error) error {
+	return tx.Bucket(filesBucket).ForEach(func(_, data []byte) error {
+		var file fileRecord
+		if err := json.Unmarshal(data, &file); err != nil {
+			return err
+		}
+		return fn(file)
+	})
+}
@@ -0,0 +1,217 @@
+package bolt
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// ListFiles возвращает страницу файлов, отфильтрованных и отсортированных по filter.
+// Кандидаты выбираются по самому узкому из индексов, остальные условия проверяются на записях
+func (s *Storage) ListFiles(filter catalog.ListFilesFilter) ([]catalog.FileInfo, error) {
+	const op = "storage.bolt.ListFiles"
+
+	sortBy, descending := filter.Sort()
+	compare := compareBy(sortBy)
+
+	var after *fileRecord
+	if filter.After != nil {
+		cursor, err := cursorRecord(sortBy, *filter.After)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		after = &cursor
+	}
+
+	var infos []catalog.FileInfo
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var files []fileRecord
+		err := forEachCandidate(tx, filter, func(file fileRecord) error {
+			if !matches(file, filter) {
+				return nil
+			}
+
+			if after != nil {
+				c := compare(file, *after)
+				if (!descending && c <= 0) || (descending && c >= 0) {
+					return nil
+				}
+			}
+
+			// Без FileName в список попадает только последняя версия каждого файла
+			if filter.FileName == "" {
+				latest, found, err := latestVersion(tx, file.FileName, filter.IncludeDeleted)
+				if err != nil {
+					return err
+				}
+				if !found || latest.ID != file.ID {
+					return nil
+				}
+			}
+
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		slices.SortFunc(files, func(a, b fileRecord) int {
+			if descending {
+				return compare(b, a)
+			}
+			return compare(a, b)
+		})
+
+		if filter.Limit > 0 && len(files) > filter.Limit {
+			files = files[:filter.Limit]
+		}
+
+		infos = make([]catalog.FileInfo, 0, len(files))
+		for _, file := range files {
+			info, err := file.info(tx)
+			if err != nil {
+				return err
+			}
+			infos = append(infos, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return infos, nil
+}
+
+// forEachCandidate обходит записи, которые могут подойти под filter, используя индексы
+func forEachCandidate(tx *bbolt.Tx, filter catalog.ListFilesFilter, fn func(file fileRecord) error) error {
+	switch {
+	case filter.FileName != "":
+		return forEachVersion(tx, filter.FileName, fn)
+
+	case filter.NamePrefix != "":
+		prefix := []byte(filter.NamePrefix)
+		return forEachIndexed(tx, filenameIndex, prefix, func(key []byte) bool {
+			return bytes.HasPrefix(key, prefix)
+		}, fn)
+
+	case !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero():
+		var from []byte
+		if !filter.CreatedAfter.IsZero() {
+			from = createdAtKey(truncateTime(filter.CreatedAfter))
+		}
+		var to []byte
+		if !filter.CreatedBefore.IsZero() {
+			to = createdAtKey(truncateTime(filter.CreatedBefore))
+		}
+		return forEachIndexed(tx, createdAtIndex, from, func(key []byte) bool {
+			return to == nil || bytes.Compare(key, to) < 0
+		}, fn)
+
+	default:
+		return forEachFile(tx, fn)
+	}
+}
+
+// forEachIndexed обходит записи индекса начиная с from (nil - с начала), пока inRange возвращает true
+func forEachIndexed(tx *bbolt.Tx, index []byte, from []byte, inRange func(key []byte) bool, fn func(file fileRecord) error) error {
+	c := tx.Bucket(index).Cursor()
+
+	k, _ := c.First()
+	if from != nil {
+		k, _ = c.Seek(from)
+	}
+
+	for ; k != nil && inRange(k); k, _ = c.Next() {
+		file, err := getFile(tx, indexedID(k))
+		if err != nil {
+			return err
+		}
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matches(file fileRecord, filter catalog.ListFilesFilter) bool {
+	if !filter.IncludeDeleted && file.DeletedAt != nil {
+		return false
+	}
+	if filter.NamePrefix != "" && !strings.HasPrefix(file.FileName, filter.NamePrefix) {
+		return false
+	}
+	if filter.MimeMismatch && file.DeclaredMimeType == "" {
+		return false
+	}
+	if filter.MimeType != "" && file.mimeType() != filter.MimeType {
+		return false
+	}
+	if filter.MinSize > 0 && file.Size < filter.MinSize {
+		return false
+	}
+	if filter.MaxSize > 0 && file.Size > filter.MaxSize {
+		return false
+	}
+	if !filter.CreatedAfter.IsZero() && file.CreatedAt.Before(truncateTime(filter.CreatedAfter)) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !file.CreatedAt.Before(truncateTime(filter.CreatedBefore)) {
+		return false
+	}
+	return true
+}
+
+// compareBy сравнивает записи по полю сортировки, при равенстве - по id
+func compareBy(sortBy catalog.SortField) func(a, b fileRecord) int {
+	return func(a, b fileRecord) int {
+		var c int
+		switch sortBy {
+		case catalog.SortByCreated:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case catalog.SortBySize:
+			c = cmp.Compare(a.Size, b.Size)
+		case catalog.SortByVersion:
+			c = cmp.Compare(a.Version, b.Version)
+		default:
+			c = strings.Compare(a.FileName, b.FileName)
+		}
+		if c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	}
+}
+
+// cursorRecord превращает курсор в запись с заполненными полем сортировки и id
+func cursorRecord(sortBy catalog.SortField, cursor catalog.Cursor) (fileRecord, error) {
+	record := fileRecord{ID: cursor.ID}
+
+	switch sortBy {
+	case catalog.SortBySize, catalog.SortByVersion:
+		var n int64
+		if _, err := fmt.Sscan(cursor.Value, &n); err != nil {
+			return fileRecord{}, fmt.Errorf("invalid cursor value %q: %w", cursor.Value, err)
+		}
+		record.Size, record.Version = n, n
+	case catalog.SortByCreated:
+		createdAt, err := time.ParseInLocation(catalog.CursorTimeLayout, cursor.Value, time.UTC)
+		if err != nil {
+			return fileRecord{}, fmt.Errorf("invalid cursor value %q: %w", cursor.Value, err)
+		}
+		record.CreatedAt = createdAt
+	default:
+		record.FileName = cursor.Value
+	}
+
+	return record, nil
+}
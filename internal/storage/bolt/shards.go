@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+func toShards(shards []catalog.BlobShard, updatedAt time.Time) []shard {
+	var records []shard
+	for _, s := range shards {
+		records = append(records, shard{Index: s.Index, Location: s.Location, Health: s.Health, UpdatedAt: updatedAt})
+	}
+	return records
+}
+
+func fromShards(records []shard) []catalog.BlobShard {
+	var shards []catalog.BlobShard
+	for _, r := range records {
+		shards = append(shards, catalog.BlobShard{Index: r.Index, Location: r.Location, Health: r.Health})
+	}
+	return shards
+}
+
+// UpdateShardHealth записывает, где и в каком состоянии нашелся фрагмент index blob-а checksum
+func (s *Storage) UpdateShardHealth(checksum string, index int, location string, health string) error {
+	const op = "storage.bolt.UpdateShardHealth"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, checksum)
+		if err != nil || !found {
+			return err
+		}
+
+		for i := range blob.Shards {
+			if blob.Shards[i].Index == index {
+				blob.Shards[i].Location = location
+				blob.Shards[i].Health = health
+				blob.Shards[i].UpdatedAt = now()
+				return putBlob(tx, blob)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package bolt
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// Export читает весь каталог
+func (s *Storage) Export() (catalog.Snapshot, error) {
+	const op = "storage.bolt.Export"
+
+	var snapshot catalog.Snapshot
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(blobsBucket).ForEach(func(_, data []byte) error {
+			var blob blobRecord
+			if err := json.Unmarshal(data, &blob); err != nil {
+				return err
+			}
+			snapshot.Blobs = append(snapshot.Blobs, catalog.BlobRow{
+				ID:             blob.ID,
+				Checksum:       blob.Checksum,
+				Size:           blob.Size,
+				RefCount:       blob.RefCount,
+				Encoding:       blob.Encoding,
+				StoredSize:     blob.StoredSize,
+				KeyID:          blob.KeyID,
+				WrappedKey:     blob.WrappedKey,
+				StoredChecksum: blob.StoredChecksum,
+				Replicas:       blob.Replicas,
+				Shards:         fromShards(blob.Shards),
+				Tier:           blob.tier(),
+				LastAccessedAt: blob.lastAccessedAt(),
+				Moves:          fromMoves(blob.Moves),
+				Variants:       fromVariants(blob.Checksum, blob.Variants),
+				CreatedAt:      blob.CreatedAt,
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// blobs лежат по checksum, а выгрузка идет по id, как у sqlite
+		slices.SortFunc(snapshot.Blobs, func(a, b catalog.BlobRow) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+
+		err = forEachFile(tx, func(file fileRecord) error {
+			row := catalog.FileRow{
+				ID:               file.ID,
+				FileName:         file.FileName,
+				Path:             file.Path,
+				Size:             file.Size,
+				MimeType:         file.mimeType(),
+				DeclaredMimeType: file.DeclaredMimeType,
+				Checksum:         file.Checksum,
+				BlobID:           file.BlobID,
+				Encoding:         file.Encoding,
+				StoredSize:       file.StoredSize,
+				Version:          file.Version,
+				PreviousID:       file.PreviousID,
+				Namespace:        file.Namespace,
+				CreatedAt:        file.CreatedAt,
+				UpdatedAt:        file.UpdatedAt,
+				Exif:             file.Exif.exif(),
+			}
+			if file.DeletedAt != nil {
+				row.DeletedAt = *file.DeletedAt
+			}
+			snapshot.Files = append(snapshot.Files, row)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = forEachSession(tx, func(session catalog.UploadSession) error {
+			snapshot.UploadSessions = append(snapshot.UploadSessions, session)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(versionsBucket).ForEach(func(k, v []byte) error {
+			snapshot.FileVersions = append(snapshot.FileVersions, catalog.FileVersion{
+				FileName:    string(k),
+				LastVersion: int64(binary.BigEndian.Uint64(v)),
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return snapshot, nil
+}
+
+// Import загружает snapshot в пустой каталог, сохраняя id записей
+func (s *Storage) Import(snapshot catalog.Snapshot) error {
+	const op = "storage.bolt.Import"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range allBuckets {
+			if k, _ := tx.Bucket(name).Cursor().First(); k != nil {
+				return catalog.ErrCatalogNotEmpty
+			}
+		}
+
+		blobChecksums := make(map[int64]string, len(snapshot.Blobs))
+		var maxBlobID int64
+		for _, row := range snapshot.Blobs {
+			err := putBlob(tx, blobRecord{
+				ID:             row.ID,
+				Checksum:       row.Checksum,
+				Size:           row.Size,
+				RefCount:       row.RefCount,
+				Encoding:       row.Encoding,
+				StoredSize:     row.StoredSize,
+				KeyID:          row.KeyID,
+				WrappedKey:     row.WrappedKey,
+				StoredChecksum: row.StoredChecksum,
+				Replicas:       row.Replicas,
+				Shards:         toShards(row.Shards, truncateTime(row.CreatedAt)),
+				Tier:           row.Tier,
+				LastAccessedAt: truncateTime(row.LastAccessedAt),
+				Moves:          toMoves(row.Moves),
+				Variants:       toVariants(row.Variants),
+				CreatedAt:      truncateTime(row.CreatedAt),
+			})
+			if err != nil {
+				return err
+			}
+			blobChecksums[row.ID] = row.Checksum
+			maxBlobID = max(maxBlobID, row.ID)
+		}
+
+		var maxFileID int64
+		for _, row := range snapshot.Files {
+			file := fileRecord{
+				ID:               row.ID,
+				FileName:         row.FileName,
+				Path:             row.Path,
+				Size:             row.Size,
+				MimeType:         row.MimeType,
+				DeclaredMimeType: row.DeclaredMimeType,
+				Checksum:         row.Checksum,
+				BlobID:           row.BlobID,
+				BlobChecksum:     blobChecksums[row.BlobID],
+				Encoding:         row.Encoding,
+				StoredSize:       row.StoredSize,
+				Version:          row.Version,
+				PreviousID:       row.PreviousID,
+				Namespace:        row.Namespace,
+				CreatedAt:        truncateTime(row.CreatedAt),
+				UpdatedAt:        truncateTime(row.UpdatedAt),
+				Exif:             toExifRecord(row.Exif),
+			}
+			if !row.DeletedAt.IsZero() {
+				deletedAt := truncateTime(row.DeletedAt)
+				file.DeletedAt = &deletedAt
+			}
+			if err := putFile(tx, nil, file); err != nil {
+				return err
+			}
+			maxFileID = max(maxFileID, row.ID)
+		}
+
+		for _, session := range snapshot.UploadSessions {
+			if err := putSession(tx, session); err != nil {
+				return err
+			}
+		}
+
+		for _, version := range snapshot.LastVersions() {
+			if err := putLastVersion(tx, version.FileName, version.LastVersion); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Bucket(blobsBucket).SetSequence(uint64(maxBlobID)); err != nil {
+			return err
+		}
+		return tx.Bucket(filesBucket).SetSequence(uint64(maxFileID))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package bolt
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+type move struct {
+	FromTier string    `json:"from_tier"`
+	ToTier   string    `json:"to_tier"`
+	MovedAt  time.Time `json:"moved_at"`
+}
+
+func toMoves(moves []catalog.TierMove) []move {
+	var records []move
+	for _, m := range moves {
+		records = append(records, move{FromTier: m.FromTier, ToTier: m.ToTier, MovedAt: truncateTime(m.MovedAt)})
+	}
+	return records
+}
+
+func fromMoves(records []move) []catalog.TierMove {
+	var moves []catalog.TierMove
+	for _, r := range records {
+		moves = append(moves, catalog.TierMove{FromTier: r.FromTier, ToTier: r.ToTier, MovedAt: r.MovedAt})
+	}
+	return moves
+}
+
+// TouchBlob записывает время последнего скачивания blob-а
+func (s *Storage) TouchBlob(checksum string, at time.Time) error {
+	const op = "storage.bolt.TouchBlob"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, checksum)
+		if err != nil || !found {
+			return err
+		}
+		blob.LastAccessedAt = truncateTime(at)
+		return putBlob(tx, blob)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListIdleBlobs возвращает до limit blob-ов из tier, которые не скачивали с before, начиная с самых давних.
+// Индекса по времени скачивания нет, поэтому бакет blobs просматривается целиком
+func (s *Storage) ListIdleBlobs(tier string, before time.Time, limit int) ([]catalog.IdleBlob, error) {
+	const op = "storage.bolt.ListIdleBlobs"
+
+	type idle struct {
+		id   int64
+		blob catalog.IdleBlob
+	}
+
+	var found []idle
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(blobsBucket).ForEach(func(_, data []byte) error {
+			var blob blobRecord
+			if err := json.Unmarshal(data, &blob); err != nil {
+				return err
+			}
+			if blob.tier() != tier || !blob.lastAccessedAt().Before(before) {
+				return nil
+			}
+			found = append(found, idle{id: blob.ID, blob: catalog.IdleBlob{
+				Checksum:       blob.Checksum,
+				StoredChecksum: blob.storedChecksum(),
+				LastAccessedAt: blob.lastAccessedAt(),
+			}})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slices.SortFunc(found, func(a, b idle) int {
+		if c := a.blob.LastAccessedAt.Compare(b.blob.LastAccessedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.id, b.id)
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	var blobs []catalog.IdleBlob
+	for _, f := range found {
+		blobs = append(blobs, f.blob)
+	}
+
+	return blobs, nil
+}
+
+// MoveBlobTier переводит blob из хранилища from в to и записывает перенос в историю.
+// Возвращает false, если blob уже не в from или удален
+func (s *Storage) MoveBlobTier(checksum string, from string, to string, at time.Time) (bool, error) {
+	const op = "storage.bolt.MoveBlobTier"
+
+	var moved bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, checksum)
+		if err != nil || !found || blob.tier() != from {
+			return err
+		}
+
+		blob.Tier = to
+		blob.Moves = append(blob.Moves, move{FromTier: from, ToTier: to, MovedAt: truncateTime(at)})
+		moved = true
+		return putBlob(tx, blob)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moved, nil
+}
+
+// ListBlobMoves возвращает историю переносов blob-а по порядку
+func (s *Storage) ListBlobMoves(checksum string) ([]catalog.TierMove, error) {
+	const op = "storage.bolt.ListBlobMoves"
+
+	var moves []catalog.TierMove
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		blob, _, err := getBlob(tx, checksum)
+		moves = fromMoves(blob.Moves)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moves, nil
+}
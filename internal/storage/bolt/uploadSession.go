@@ -0,0 +1,151 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+func (s *Storage) CreateUploadSession(sessionID string, fileName string, namespace string) error {
+	const op = "storage.bolt.CreateUploadSession"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(sessionsBucket).Get([]byte(sessionID)) != nil {
+			return fmt.Errorf("upload session %s already exists", sessionID)
+		}
+
+		createdAt := now()
+		return putSession(tx, catalog.UploadSession{
+			ID:        sessionID,
+			FileName:  fileName,
+			Namespace: namespace,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUploadSession(sessionID string) (catalog.UploadSession, error) {
+	const op = "storage.bolt.GetUploadSession"
+
+	var session catalog.UploadSession
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		session, err = getSession(tx, sessionID)
+		return err
+	})
+	if err != nil {
+		return catalog.UploadSession{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// UpdateUploadOffset сохраняет смещение вместе с состоянием хеша, чтобы после докачки не перечитывать файл
+func (s *Storage) UpdateUploadOffset(sessionID string, offset int64, hashState []byte) error {
+	const op = "storage.bolt.UpdateUploadOffset"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		session, err := getSession(tx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		session.Offset = offset
+		session.HashState = hashState
+		session.UpdatedAt = now()
+		return putSession(tx, session)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUploadSession(sessionID string) error {
+	const op = "storage.bolt.DeleteUploadSession"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(sessionID))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredUploadSessions удаляет сессии, которые не обновлялись с before, и возвращает их id
+func (s *Storage) DeleteExpiredUploadSessions(before time.Time) ([]string, error) {
+	const op = "storage.bolt.DeleteExpiredUploadSessions"
+
+	var ids []string
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		err := forEachSession(tx, func(session catalog.UploadSession) error {
+			if session.UpdatedAt.Before(before) {
+				ids = append(ids, session.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := tx.Bucket(sessionsBucket).Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+func getSession(tx *bbolt.Tx, sessionID string) (catalog.UploadSession, error) {
+	var session catalog.UploadSession
+	data := tx.Bucket(sessionsBucket).Get([]byte(sessionID))
+	if data == nil {
+		return catalog.UploadSession{}, catalog.ErrSessionNotFound
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return catalog.UploadSession{}, err
+	}
+	return session, nil
+}
+
+func putSession(tx *bbolt.Tx, session catalog.UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(sessionsBucket).Put([]byte(session.ID), data)
+}
+
+// forEachSession обходит все сессии, errStopIteration из fn прерывает обход без ошибки
+func forEachSession(tx *bbolt.Tx, fn func(session catalog.UploadSession) error) error {
+	err := tx.Bucket(sessionsBucket).ForEach(func(_, data []byte) error {
+		var session catalog.UploadSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		return fn(session)
+	})
+	if err == errStopIteration {
+		return nil
+	}
+	return err
+}
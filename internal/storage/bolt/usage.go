@@ -0,0 +1,42 @@
+package bolt
+
+import (
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// GetNamespaceUsage считает файлы (включая еще не очищенные удаленные) и байты незавершенных
+// загрузок пространства имен. Сессия excludeSessionID не учитывается - это текущая загрузка
+func (s *Storage) GetNamespaceUsage(namespace string, excludeSessionID string) (catalog.Usage, error) {
+	const op = "storage.bolt.GetNamespaceUsage"
+
+	var usage catalog.Usage
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		err := forEachFile(tx, func(file fileRecord) error {
+			if file.Namespace == namespace {
+				usage.Bytes += file.Size
+				usage.Files++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return forEachSession(tx, func(session catalog.UploadSession) error {
+			if session.Namespace == namespace && session.ID != excludeSessionID {
+				usage.Bytes += session.Offset
+				usage.Files++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return catalog.Usage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,182 @@
+package bolt
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+
+	"go.etcd.io/bbolt"
+)
+
+// variant хранится в записи blob-а, поэтому уходит вместе с ней при удалении последней ссылки
+type variant struct {
+	Name           string    `json:"name"`
+	Path           string    `json:"path"`
+	MimeType       string    `json:"mime_type"`
+	Width          int       `json:"width"`
+	Height         int       `json:"height"`
+	Size           int64     `json:"size"`
+	StoredSize     int64     `json:"stored_size"`
+	StoredChecksum string    `json:"stored_checksum"`
+	KeySalt        []byte    `json:"key_salt,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func toVariant(v catalog.Variant) variant {
+	return variant{
+		Name:           v.Name,
+		Path:           v.Path,
+		MimeType:       v.MimeType,
+		Width:          v.Width,
+		Height:         v.Height,
+		Size:           v.Size,
+		StoredSize:     v.StoredSize,
+		StoredChecksum: v.StoredChecksum,
+		KeySalt:        v.KeySalt,
+		CreatedAt:      truncateTime(v.CreatedAt),
+	}
+}
+
+func toVariants(variants []catalog.Variant) []variant {
+	var records []variant
+	for _, v := range variants {
+		records = append(records, toVariant(v))
+	}
+	return records
+}
+
+func fromVariants(checksum string, records []variant) []catalog.Variant {
+	var variants []catalog.Variant
+	for _, r := range records {
+		variants = append(variants, catalog.Variant{
+			Checksum:       checksum,
+			Name:           r.Name,
+			Path:           r.Path,
+			MimeType:       r.MimeType,
+			Width:          r.Width,
+			Height:         r.Height,
+			Size:           r.Size,
+			StoredSize:     r.StoredSize,
+			StoredChecksum: r.StoredChecksum,
+			KeySalt:        r.KeySalt,
+			CreatedAt:      r.CreatedAt,
+		})
+	}
+	return variants
+}
+
+// SaveBlobVariant записывает вариант blob-а variant.Checksum, заменяя прежний с тем же именем.
+// Возвращает false, если blob-а уже нет
+func (s *Storage) SaveBlobVariant(v catalog.Variant) (bool, error) {
+	const op = "storage.bolt.SaveBlobVariant"
+
+	var saved bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, v.Checksum)
+		if err != nil || !found {
+			return err
+		}
+
+		blob.Variants = slices.DeleteFunc(blob.Variants, func(r variant) bool {
+			return r.Name == v.Name
+		})
+		blob.Variants = append(blob.Variants, toVariant(v))
+		slices.SortFunc(blob.Variants, func(a, b variant) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		saved = true
+		return putBlob(tx, blob)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved, nil
+}
+
+// ListBlobVariants возвращает варианты blob-а по имени, пустой checksum - варианты всех blob-ов.
+// Все варианты собираются просмотром бакета blobs целиком
+func (s *Storage) ListBlobVariants(checksum string) ([]catalog.Variant, error) {
+	const op = "storage.bolt.ListBlobVariants"
+
+	var variants []catalog.Variant
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if checksum != "" {
+			blob, _, err := getBlob(tx, checksum)
+			variants = fromVariants(blob.Checksum, blob.Variants)
+			return err
+		}
+
+		var blobs []blobRecord
+		err := tx.Bucket(blobsBucket).ForEach(func(_, data []byte) error {
+			var blob blobRecord
+			if err := json.Unmarshal(data, &blob); err != nil {
+				return err
+			}
+			if len(blob.Variants) > 0 {
+				blobs = append(blobs, blob)
+			}
+			return nil
+		})
+		// Порядок по id blob-а, как у sqlite
+		slices.SortFunc(blobs, func(a, b blobRecord) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		for _, blob := range blobs {
+			variants = append(variants, fromVariants(blob.Checksum, blob.Variants)...)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return variants, nil
+}
+
+// DeleteBlobVariant удаляет запись о варианте. Данные в хранилище остаются
+func (s *Storage) DeleteBlobVariant(checksum string, name string) error {
+	const op = "storage.bolt.DeleteBlobVariant"
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		blob, found, err := getBlob(tx, checksum)
+		if err != nil || !found {
+			return err
+		}
+
+		blob.Variants = slices.DeleteFunc(blob.Variants, func(r variant) bool {
+			return r.Name == name
+		})
+		return putBlob(tx, blob)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// hasVariantPath проверяет, лежит ли по path вариант какого-нибудь blob-а
+func hasVariantPath(tx *bbolt.Tx, path string) (bool, error) {
+	var found bool
+	err := tx.Bucket(blobsBucket).ForEach(func(_, data []byte) error {
+		var blob blobRecord
+		if err := json.Unmarshal(data, &blob); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(blob.Variants, func(r variant) bool { return r.Path == path }) {
+			found = true
+			return errStopIteration
+		}
+		return nil
+	})
+	if errors.Is(err, errStopIteration) {
+		err = nil
+	}
+	return found, err
+}
@@ -0,0 +1,92 @@
+package catalog
+
+import "time"
+
+// FileLocation - где в хранилище лежит содержимое записи files
+type FileLocation struct {
+	ID       int64
+	FileName string
+	Version  int64
+	// Checksum пустой у файлов, загруженных до появления blob-ов
+	Checksum string
+	Path     string
+	// Size - размер содержимого
+	Size int64
+	// LastAccessedAt - последнее скачивание blob-а, нулевое у файлов без blob-а
+	LastAccessedAt time.Time
+	StoredBlob
+}
+
+// StoredBlob - в каком виде содержимое лежит в хранилище
+type StoredBlob struct {
+	// Encoding - алгоритм сжатия, StoredSize - сколько байт занято в хранилище
+	Encoding   string
+	StoredSize int64
+	// KeyID - мастер-ключ, которым закрыт WrappedKey. Пустой - содержимое не зашифровано
+	KeyID      string
+	WrappedKey []byte
+	// StoredChecksum - sha256 содержимого в том виде, в каком оно лежит в хранилище. Пустой у blob-ов,
+	// сохраненных до появления реплик сжатыми или зашифрованными
+	StoredChecksum string
+	// Replicas - каталоги данных с копиями blob-а, пусто без репликации
+	Replicas []string
+	// Shards - фрагменты blob-а, пусто без erasure coding
+	Shards []BlobShard
+	// Tier - TierHot или TierCold
+	Tier string
+}
+
+// Состояние фрагмента при последнем чтении
+const (
+	ShardOK      = "ok"
+	ShardMissing = "missing"
+	ShardCorrupt = "corrupt"
+)
+
+// BlobShard - фрагмент blob-а и каталог данных, в котором он лежит
+type BlobShard struct {
+	Index    int
+	Location string
+	Health   string
+}
+
+// Хранилища blob-ов: hot - основное, cold - медленное для давно не читавшихся blob-ов
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
+
+// IdleBlob - blob, который давно не скачивали
+type IdleBlob struct {
+	Checksum string
+	// StoredChecksum пустой, если не известен
+	StoredChecksum string
+	LastAccessedAt time.Time
+}
+
+// TierMove - перенос blob-а между хранилищами
+type TierMove struct {
+	FromTier string
+	ToTier   string
+	MovedAt  time.Time
+}
+
+// Variant - производное изображение blob-а (миниатюра), лежит в хранилище отдельно по Path.
+// Зашифрован, если зашифрован blob: ключом, выведенным из ключа данных blob-а
+type Variant struct {
+	// Checksum - blob, из которого построен вариант
+	Checksum string
+	Name     string
+	Path     string
+	MimeType string
+	Width    int
+	Height   int
+	// Size - размер изображения, StoredSize и StoredChecksum - размер и sha256 в хранилище
+	Size           int64
+	StoredSize     int64
+	StoredChecksum string
+	// KeySalt - случайная соль, из которой вместе с именем выводится ключ варианта зашифрованного blob-а.
+	// Пусто у незашифрованных и у вариантов, созданных до появления соли
+	KeySalt   []byte
+	CreatedAt time.Time
+}
@@ -0,0 +1,131 @@
+// Package catalog - типы, ошибки и интерфейс каталога файлов, общие для всех его реализаций
+// (sqlite, postgres, bolt). Пакет не зависит от драйверов баз данных
+package catalog
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrFileNotFound = errors.New("file not found")
+
+// IStorage - каталог файлов, версий, blob-ов и сессий загрузки
+type IStorage interface {
+	SaveImage(imageName string, namespace string, pathToFile string, size int, mimeType string, declaredMimeType string, checksum string, stored StoredBlob, createdAt time.Time) (int64, bool, error)
+	ListFiles(filter ListFilesFilter) ([]FileInfo, error)
+	FindFileByName(fileName string) (string, error)
+	GetFileInfo(fileName string, version int64) (FileInfo, error)
+
+	CreateUploadSession(sessionID string, fileName string, namespace string) error
+	GetUploadSession(sessionID string) (UploadSession, error)
+	UpdateUploadOffset(sessionID string, offset int64, hashState []byte) error
+	DeleteUploadSession(sessionID string) error
+	DeleteExpiredUploadSessions(before time.Time) ([]string, error)
+	GetNamespaceUsage(namespace string, excludeSessionID string) (Usage, error)
+
+	FindFileLocation(fileName string, version int64) (FileLocation, error)
+	ReleaseBlob(checksum string) (bool, error)
+	LockBlob(ctx context.Context, key string) (func() error, error)
+
+	SoftDeleteFile(fileName string, version int64) (int64, error)
+	RestoreFile(fileName string, version int64) (int64, error)
+	ListDeletedFiles(before time.Time, fileName string) ([]DeletedFile, error)
+	PurgeFile(id int64, checksum string, before time.Time) (bool, bool, error)
+	DiscardFile(fileName string, version int64) (bool, error)
+
+	ListFileLocations() ([]FileLocation, error)
+	UpdateFilePath(id int64, path string) error
+	HasFilePath(path string) (bool, error)
+	ListWrappedKeys() ([]WrappedKey, error)
+	UpdateWrappedKey(checksum string, oldKeyID string, keyID string, wrapped []byte) (bool, error)
+	UpdateShardHealth(checksum string, index int, location string, health string) error
+
+	TouchBlob(checksum string, at time.Time) error
+	ListIdleBlobs(tier string, before time.Time, limit int) ([]IdleBlob, error)
+	MoveBlobTier(checksum string, from string, to string, at time.Time) (bool, error)
+	ListBlobMoves(checksum string) ([]TierMove, error)
+
+	SaveBlobVariant(variant Variant) (bool, error)
+	ListBlobVariants(checksum string) ([]Variant, error)
+	DeleteBlobVariant(checksum string, name string) error
+
+	SaveFileExif(fileID int64, exif Exif) error
+	GetFileExif(fileID int64) (Exif, error)
+}
+
+type FileInfo struct {
+	ID       int64
+	FileName string
+	Version  int64
+	Size     int64
+	// MimeType - тип по содержимому, DeclaredMimeType - тип по расширению, если он противоречит содержимому
+	MimeType         string
+	DeclaredMimeType string
+	Checksum         string
+	// Encoding - алгоритм сжатия в хранилище, StoredSize - сколько байт занимает там содержимое
+	Encoding   string
+	StoredSize int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// DeletedAt - нулевое время, если файл не удален
+	DeletedAt time.Time
+	// Tier - хранилище blob-а, LastAccessedAt - его последнее скачивание (нулевое у файлов без blob-а)
+	Tier           string
+	LastAccessedAt time.Time
+}
+
+// DeletedFile - версия, помеченная удаленной и ожидающая окончательного удаления
+type DeletedFile struct {
+	ID       int64
+	FileName string
+	Version  int64
+	Checksum string
+	// Path - ключ содержимого в хранилище blob-ов
+	Path      string
+	DeletedAt time.Time
+}
+
+var ErrSessionNotFound = errors.New("upload session not found")
+
+type UploadSession struct {
+	ID        string
+	FileName  string
+	Namespace string
+	Offset    int64
+	// HashState - сериализованное состояние sha256 для принятых Offset байт
+	HashState []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Usage - сколько занимает пространство имен вместе с незавершенными загрузками
+type Usage struct {
+	Bytes int64
+	Files int64
+}
+
+var ErrExifNotFound = errors.New("exif not found")
+
+// Exif - метаданные EXIF версии файла
+type Exif struct {
+	CameraMake  string
+	CameraModel string
+	LensMake    string
+	LensModel   string
+	// CapturedAt - нулевое время, если время съемки не известно
+	CapturedAt  time.Time
+	Orientation int
+	// HasGPS - координаты известны, высота в метрах
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// WrappedKey - ключ данных blob-а, закрытый мастер-ключом KeyID
+type WrappedKey struct {
+	Checksum   string
+	KeyID      string
+	WrappedKey []byte
+}
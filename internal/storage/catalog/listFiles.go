@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"fmt"
+	"time"
+)
+
+// CursorTimeLayout - формат created_at в курсорах CursorFor, он же формат CURRENT_TIMESTAMP в SQLite
+const CursorTimeLayout = "2006-01-02 15:04:05"
+
+type SortField string
+
+const (
+	// SortDefault - по имени для списка файлов, от новых версий к старым для истории
+	SortDefault   SortField = ""
+	SortByName    SortField = "filename"
+	SortByCreated SortField = "created_at"
+	SortBySize    SortField = "size_kb"
+	SortByVersion SortField = "version"
+)
+
+// Cursor - позиция последней записи предыдущей страницы
+type Cursor struct {
+	Value string
+	ID    int64
+}
+
+type ListFilesFilter struct {
+	// Если задано, возвращается история версий файла, иначе последние версии всех файлов
+	FileName       string
+	IncludeDeleted bool
+
+	NamePrefix    string
+	MimeType      string
+	MinSize       int64
+	MaxSize       int64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// MimeMismatch - только файлы, у которых расширение противоречит содержимому
+	MimeMismatch bool
+
+	SortBy     SortField
+	Descending bool
+	Limit      int
+	After      *Cursor
+}
+
+// Sort возвращает поле и направление сортировки с учетом SortDefault
+func (f ListFilesFilter) Sort() (SortField, bool) {
+	if f.SortBy != SortDefault {
+		return f.SortBy, f.Descending
+	}
+	if f.FileName != "" {
+		return SortByVersion, true
+	}
+	return SortByName, f.Descending
+}
+
+// CursorFor возвращает курсор, указывающий на file при сортировке по sortBy
+func CursorFor(file FileInfo, sortBy SortField) Cursor {
+	var value string
+	switch sortBy {
+	case SortByCreated:
+		value = file.CreatedAt.UTC().Format(CursorTimeLayout)
+	case SortBySize:
+		value = fmt.Sprint(file.Size)
+	case SortByVersion:
+		value = fmt.Sprint(file.Version)
+	default:
+		value = file.FileName
+	}
+
+	return Cursor{Value: value, ID: file.ID}
+}
@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrCatalogNotEmpty = errors.New("catalog is not empty")
+
+// Snapshot - весь каталог со служебными полями, для переноса между хранилищами метаданных
+type Snapshot struct {
+	Blobs          []BlobRow
+	Files          []FileRow
+	UploadSessions []UploadSession
+	FileVersions   []FileVersion
+}
+
+// FileVersion - последний выданный номер версии имени файла
+type FileVersion struct {
+	FileName    string
+	LastVersion int64
+}
+
+// LastVersions возвращает счетчики версий по именам. У выгрузок без счетчиков
+// и у имен, которых в FileVersions нет, счетчик - наибольшая версия в Files
+func (s Snapshot) LastVersions() []FileVersion {
+	last := make(map[string]int64)
+	for _, file := range s.Files {
+		last[file.FileName] = max(last[file.FileName], file.Version)
+	}
+	for _, version := range s.FileVersions {
+		last[version.FileName] = max(last[version.FileName], version.LastVersion)
+	}
+
+	versions := make([]FileVersion, 0, len(last))
+	for fileName, version := range last {
+		versions = append(versions, FileVersion{FileName: fileName, LastVersion: version})
+	}
+	slices.SortFunc(versions, func(a, b FileVersion) int {
+		return strings.Compare(a.FileName, b.FileName)
+	})
+	return versions
+}
+
+type BlobRow struct {
+	ID         int64
+	Checksum   string
+	Size       int64
+	RefCount   int64
+	Encoding   string
+	StoredSize int64
+	KeyID      string
+	WrappedKey []byte
+	// StoredChecksum пустой, если не известен
+	StoredChecksum string
+	Replicas       []string
+	Shards         []BlobShard
+	Tier           string
+	LastAccessedAt time.Time
+	Moves          []TierMove
+	Variants       []Variant
+	CreatedAt      time.Time
+}
+
+type FileRow struct {
+	ID       int64
+	FileName string
+	Path     string
+	Size     int64
+	MimeType string
+	// DeclaredMimeType - тип по расширению, если он противоречит содержимому
+	DeclaredMimeType string
+	Checksum         string
+	// BlobID и PreviousID равны 0, если ссылки нет
+	BlobID     int64
+	Encoding   string
+	StoredSize int64
+	Version    int64
+	PreviousID int64
+	Namespace  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// DeletedAt - нулевое время, если файл не удален
+	DeletedAt time.Time
+	// Exif == nil - метаданных нет
+	Exif *Exif
+}
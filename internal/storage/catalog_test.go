@@ -0,0 +1,568 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"imagestorage/internal/storage/bolt"
+	"imagestorage/internal/storage/catalog"
+	"imagestorage/internal/storage/postgres"
+	"imagestorage/internal/storage/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// catalogs - реализации каталога, которые должны вести себя одинаково.
+// PostgreSQL нужен сервер, поэтому он проверяется, только если задан TEST_POSTGRES_DSN
+var catalogs = []struct {
+	name string
+	open func(t *testing.T) catalog.IStorage
+}{
+	{name: "sqlite", open: openSQLite},
+	{name: "bolt", open: openBolt},
+	{name: "postgres", open: openPostgres},
+}
+
+func openSQLite(t *testing.T) catalog.IStorage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "catalog.db")
+	m, err := migrate.New("file://../../migrations", "sqlite3://"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	st, err := sqlite.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func openBolt(t *testing.T) catalog.IStorage {
+	t.Helper()
+
+	st, err := bolt.New(filepath.Join(t.TempDir(), "catalog.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// openPostgres создает для теста отдельную схему в базе TEST_POSTGRES_DSN и удаляет ее после теста
+func openPostgres(t *testing.T) catalog.IStorage {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	schema := fmt.Sprintf("catalog_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	// search_path уходит серверу параметром соединения: и миграции, и каталог работают в схеме теста
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "search_path=" + schema
+
+	m, err := migrate.New("file://../../migrations/postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	st, err := postgres.New(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// forEachCatalog запускает test на пустом каталоге каждой реализации
+func forEachCatalog(t *testing.T, test func(t *testing.T, st catalog.IStorage)) {
+	for _, catalog := range catalogs {
+		t.Run(catalog.name, func(t *testing.T) {
+			test(t, catalog.open(t))
+		})
+	}
+}
+
+// checksum - правдоподобный sha256 по номеру
+func checksum(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+func saveImage(t *testing.T, st catalog.IStorage, fileName string, size int, sum string, createdAt time.Time) int64 {
+	t.Helper()
+
+	version, _, err := st.SaveImage(fileName, "", "blobs/"+sum, size, "image/png", "", sum, catalog.StoredBlob{StoredSize: int64(size)}, createdAt)
+	if err != nil {
+		t.Fatalf("save %s: %v", fileName, err)
+	}
+	return version
+}
+
+func TestSaveImageVersions(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for i, sum := range []string{checksum(1), checksum(2), checksum(1)} {
+			if version := saveImage(t, st, "a.png", 100*(i+1), sum, now.Add(time.Duration(i)*time.Second)); version != int64(i+1) {
+				t.Fatalf("version of upload %d is %d", i+1, version)
+			}
+		}
+		if version := saveImage(t, st, "b.png", 10, checksum(3), now); version != 1 {
+			t.Fatalf("first version of b.png is %d", version)
+		}
+
+		tests := []struct {
+			fileName string
+			version  int64
+			want     int64
+			checksum string
+			size     int64
+			err      error
+		}{
+			{fileName: "a.png", version: 0, want: 3, checksum: checksum(1), size: 300},
+			{fileName: "a.png", version: 1, want: 1, checksum: checksum(1), size: 100},
+			{fileName: "a.png", version: 2, want: 2, checksum: checksum(2), size: 200},
+			{fileName: "a.png", version: 4, err: catalog.ErrFileNotFound},
+			{fileName: "b.png", version: 0, want: 1, checksum: checksum(3), size: 10},
+			{fileName: "c.png", version: 0, err: catalog.ErrFileNotFound},
+		}
+		for _, tt := range tests {
+			file, err := st.GetFileInfo(tt.fileName, tt.version)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("get %s v%d: %v, want %v", tt.fileName, tt.version, err, tt.err)
+			}
+			location, locationErr := st.FindFileLocation(tt.fileName, tt.version)
+			if !errors.Is(locationErr, tt.err) {
+				t.Fatalf("locate %s v%d: %v, want %v", tt.fileName, tt.version, locationErr, tt.err)
+			}
+			if tt.err != nil {
+				continue
+			}
+			if file.Version != tt.want || file.Checksum != tt.checksum || file.Size != tt.size {
+				t.Fatalf("get %s v%d: got v%d %s %d bytes", tt.fileName, tt.version, file.Version, file.Checksum, file.Size)
+			}
+			if location.Version != tt.want || location.Checksum != tt.checksum || location.Path != "blobs/"+tt.checksum {
+				t.Fatalf("locate %s v%d: got v%d %s at %s", tt.fileName, tt.version, location.Version, location.Checksum, location.Path)
+			}
+		}
+	})
+}
+
+func TestDeletedVersions(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		now := time.Now().UTC().Truncate(time.Second)
+		for i := 1; i <= 3; i++ {
+			saveImage(t, st, "a.png", i, checksum(i), now)
+		}
+
+		steps := []struct {
+			name    string
+			apply   func() (int64, error)
+			changed int64
+			// latest - последняя видимая версия, 0 - файла не видно
+			latest int64
+		}{
+			{name: "delete latest", apply: func() (int64, error) { return st.SoftDeleteFile("a.png", 3) }, changed: 1, latest: 2},
+			{name: "delete it again", apply: func() (int64, error) { return st.SoftDeleteFile("a.png", 3) }, changed: 0, latest: 2},
+			{name: "delete all", apply: func() (int64, error) { return st.SoftDeleteFile("a.png", 0) }, changed: 2, latest: 0},
+			{name: "restore first", apply: func() (int64, error) { return st.RestoreFile("a.png", 1) }, changed: 1, latest: 1},
+			{name: "restore all", apply: func() (int64, error) { return st.RestoreFile("a.png", 0) }, changed: 2, latest: 3},
+			{name: "delete missing version", apply: func() (int64, error) { return st.SoftDeleteFile("a.png", 7) }, changed: 0, latest: 3},
+		}
+		for _, step := range steps {
+			changed, err := step.apply()
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if changed != step.changed {
+				t.Fatalf("%s: changed %d versions, want %d", step.name, changed, step.changed)
+			}
+
+			file, err := st.GetFileInfo("a.png", 0)
+			if step.latest == 0 {
+				if !errors.Is(err, catalog.ErrFileNotFound) {
+					t.Fatalf("%s: deleted file is visible: %v", step.name, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if file.Version != step.latest {
+				t.Fatalf("%s: latest version %d, want %d", step.name, file.Version, step.latest)
+			}
+		}
+
+		// Номера удаленных версий не переиспользуются
+		if _, err := st.SoftDeleteFile("a.png", 3); err != nil {
+			t.Fatal(err)
+		}
+		if version := saveImage(t, st, "a.png", 4, checksum(4), now); version != 4 {
+			t.Fatalf("version after deleting the latest one is %d, want 4", version)
+		}
+	})
+}
+
+// listFixture заполняет каталог для тестов ListFiles и возвращает время первой загрузки
+func listFixture(t *testing.T, st catalog.IStorage) time.Time {
+	t.Helper()
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	files := []struct {
+		name     string
+		size     int
+		mimeType string
+		declared string
+		minutes  int
+	}{
+		{name: "a.png", size: 100, mimeType: "image/png", minutes: 0},
+		{name: "b.jpg", size: 200, mimeType: "image/jpeg", minutes: 1},
+		{name: "c.png", size: 200, mimeType: "image/png", minutes: 2},
+		{name: "d.txt", size: 50, mimeType: "text/plain", minutes: 3},
+		{name: "a.png", size: 300, mimeType: "image/png", minutes: 4},
+		{name: "e.png", size: 70, mimeType: "image/gif", declared: "image/png", minutes: 5},
+	}
+	for i, f := range files {
+		_, _, err := st.SaveImage(f.name, "", "blobs/"+checksum(i), f.size, f.mimeType, f.declared, checksum(i),
+			catalog.StoredBlob{StoredSize: int64(f.size)}, start.Add(time.Duration(f.minutes)*time.Minute))
+		if err != nil {
+			t.Fatalf("save %s: %v", f.name, err)
+		}
+	}
+	if _, err := st.SoftDeleteFile("d.txt", 0); err != nil {
+		t.Fatal(err)
+	}
+	return start
+}
+
+func fileNames(files []catalog.FileInfo) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, fmt.Sprintf("%s@%d", file.FileName, file.Version))
+	}
+	return names
+}
+
+func TestListFiles(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		start := listFixture(t, st)
+
+		tests := []struct {
+			name   string
+			filter catalog.ListFilesFilter
+			want   []string
+		}{
+			{name: "latest versions", want: []string{"a.png@2", "b.jpg@1", "c.png@1", "e.png@1"}},
+			{name: "with deleted", filter: catalog.ListFilesFilter{IncludeDeleted: true}, want: []string{"a.png@2", "b.jpg@1", "c.png@1", "d.txt@1", "e.png@1"}},
+			{name: "history", filter: catalog.ListFilesFilter{FileName: "a.png"}, want: []string{"a.png@2", "a.png@1"}},
+			{name: "history of deleted file", filter: catalog.ListFilesFilter{FileName: "d.txt"}},
+			{name: "name prefix", filter: catalog.ListFilesFilter{NamePrefix: "b"}, want: []string{"b.jpg@1"}},
+			{name: "mime type", filter: catalog.ListFilesFilter{MimeType: "image/png"}, want: []string{"a.png@2", "c.png@1"}},
+			{name: "mime mismatch", filter: catalog.ListFilesFilter{MimeMismatch: true}, want: []string{"e.png@1"}},
+			{name: "min size", filter: catalog.ListFilesFilter{MinSize: 200}, want: []string{"a.png@2", "b.jpg@1", "c.png@1"}},
+			{name: "max size skips older versions", filter: catalog.ListFilesFilter{MaxSize: 100}, want: []string{"e.png@1"}},
+			{name: "created after", filter: catalog.ListFilesFilter{CreatedAfter: start.Add(2 * time.Minute)}, want: []string{"a.png@2", "c.png@1", "e.png@1"}},
+			{name: "created before", filter: catalog.ListFilesFilter{CreatedBefore: start.Add(2 * time.Minute)}, want: []string{"b.jpg@1"}},
+			{name: "by name descending", filter: catalog.ListFilesFilter{SortBy: catalog.SortByName, Descending: true}, want: []string{"e.png@1", "c.png@1", "b.jpg@1", "a.png@2"}},
+			{name: "by size, ties by id", filter: catalog.ListFilesFilter{SortBy: catalog.SortBySize, Descending: true}, want: []string{"a.png@2", "c.png@1", "b.jpg@1", "e.png@1"}},
+			{name: "by created", filter: catalog.ListFilesFilter{SortBy: catalog.SortByCreated}, want: []string{"b.jpg@1", "c.png@1", "a.png@2", "e.png@1"}},
+			{name: "limit", filter: catalog.ListFilesFilter{Limit: 2}, want: []string{"a.png@2", "b.jpg@1"}},
+		}
+		for _, tt := range tests {
+			files, err := st.ListFiles(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got := fileNames(files); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestListFilesPages(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		listFixture(t, st)
+
+		filters := []catalog.ListFilesFilter{
+			{},
+			{SortBy: catalog.SortByName, Descending: true},
+			{SortBy: catalog.SortByCreated},
+			{SortBy: catalog.SortByCreated, Descending: true},
+			{SortBy: catalog.SortBySize},
+			{SortBy: catalog.SortBySize, Descending: true},
+			{IncludeDeleted: true, SortBy: catalog.SortBySize},
+			{FileName: "a.png"},
+			{FileName: "a.png", SortBy: catalog.SortByVersion},
+		}
+		for _, filter := range filters {
+			all, err := st.ListFiles(filter)
+			if err != nil {
+				t.Fatalf("%+v: %v", filter, err)
+			}
+
+			sortBy, _ := filter.Sort()
+			var paged []catalog.FileInfo
+			page := filter
+			page.Limit = 1
+			for len(paged) <= len(all) {
+				files, err := st.ListFiles(page)
+				if err != nil {
+					t.Fatalf("%+v: %v", page, err)
+				}
+				if len(files) == 0 {
+					break
+				}
+				paged = append(paged, files...)
+				cursor := catalog.CursorFor(files[len(files)-1], sortBy)
+				page.After = &cursor
+				page.Limit = 2
+			}
+
+			if fmt.Sprint(fileNames(paged)) != fmt.Sprint(fileNames(all)) {
+				t.Fatalf("%+v: pages give %v, want %v", filter, fileNames(paged), fileNames(all))
+			}
+		}
+	})
+}
+
+// TestSaveImageNewBlob заводит blob при первой ссылке на содержимое и снова после того, как ссылок не осталось
+func TestSaveImageNewBlob(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		sum := checksum(1)
+		steps := []struct {
+			name string
+			// save - имя загружаемого файла, пусто - отпустить ссылку на blob
+			save    string
+			keyID   string
+			created bool
+			last    bool
+			// wantKey - ключ, которым зашифровано содержимое, на которое ссылается файл
+			wantKey string
+		}{
+			{name: "first upload", save: "a.png", keyID: "k1", created: true, wantKey: "k1"},
+			{name: "same content", save: "b.png", keyID: "k2", wantKey: "k1"},
+			{name: "release one", last: false},
+			{name: "release last", last: true},
+			{name: "upload after release", save: "c.png", keyID: "k3", created: true, wantKey: "k3"},
+		}
+		for _, step := range steps {
+			if step.save == "" {
+				last, err := st.ReleaseBlob(sum)
+				if err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+				if last != step.last {
+					t.Fatalf("%s: last %v, want %v", step.name, last, step.last)
+				}
+				continue
+			}
+
+			stored := catalog.StoredBlob{StoredSize: 10, KeyID: step.keyID, WrappedKey: []byte(step.keyID), StoredChecksum: step.keyID}
+			_, created, err := st.SaveImage(step.save, "", "blobs/"+sum, 10, "image/png", "", sum, stored, time.Now())
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if created != step.created {
+				t.Fatalf("%s: created %v, want %v", step.name, created, step.created)
+			}
+			location, err := st.FindFileLocation(step.save, 0)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if location.KeyID != step.wantKey || string(location.WrappedKey) != step.wantKey || location.StoredChecksum != step.wantKey {
+				t.Fatalf("%s: file refers to %+v, want key %s", step.name, location.StoredBlob, step.wantKey)
+			}
+		}
+	})
+}
+
+// TestDiscardFile убирает версию без пометки об удалении, и то же содержимое снова заводит blob
+func TestDiscardFile(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		sum := checksum(1)
+		version := saveImage(t, st, "a.png", 10, sum, time.Now())
+
+		last, err := st.DiscardFile("a.png", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !last {
+			t.Fatalf("discarding the only reference is not the last one")
+		}
+		if _, err := st.FindFileLocation("a.png", version); !errors.Is(err, catalog.ErrFileNotFound) {
+			t.Fatalf("discarded version: %v", err)
+		}
+		if deleted, err := st.ListDeletedFiles(time.Now().Add(time.Hour), ""); err != nil || len(deleted) != 0 {
+			t.Fatalf("deleted files %v, %v", deleted, err)
+		}
+		if _, err := st.DiscardFile("a.png", version); !errors.Is(err, catalog.ErrFileNotFound) {
+			t.Fatalf("discard twice: %v", err)
+		}
+
+		_, created, err := st.SaveImage("b.png", "", "blobs/"+sum, 10, "image/png", "", sum, catalog.StoredBlob{StoredSize: 10}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !created {
+			t.Fatalf("blob of a discarded version is still in the catalog")
+		}
+	})
+}
+
+// TestPurgeFile отпускает ссылку на blob при окончательном удалении версии и не трогает восстановленные
+func TestPurgeFile(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		sum := checksum(1)
+		for _, name := range []string{"a.png", "b.png", "c.png"} {
+			saveImage(t, st, name, 10, sum, time.Now())
+			if _, err := st.SoftDeleteFile(name, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := st.RestoreFile("c.png", 0); err != nil {
+			t.Fatal(err)
+		}
+
+		deleted, err := st.ListDeletedFiles(time.Now().Add(time.Hour), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted) != 2 {
+			t.Fatalf("deleted files %v, want a.png and b.png", deleted)
+		}
+		before := time.Now().Add(time.Hour)
+
+		// Версию удалили позже before: запись остается, ссылка на blob тоже
+		removed, last, err := st.PurgeFile(deleted[0].ID, sum, time.Now().Add(-time.Hour))
+		if err != nil || removed || last {
+			t.Fatalf("purge before deletion time: removed %v, last %v, %v", removed, last, err)
+		}
+		// Восстановленный c.png все еще ссылается на blob
+		for _, file := range deleted {
+			removed, last, err := st.PurgeFile(file.ID, file.Checksum, before)
+			if err != nil {
+				t.Fatalf("purge %s: %v", file.FileName, err)
+			}
+			if !removed || last {
+				t.Fatalf("purge %s: removed %v, last %v", file.FileName, removed, last)
+			}
+			if _, err := st.FindFileLocation(file.FileName, file.Version); !errors.Is(err, catalog.ErrFileNotFound) {
+				t.Fatalf("purged %s: %v", file.FileName, err)
+			}
+		}
+
+		if _, err := st.SoftDeleteFile("c.png", 0); err != nil {
+			t.Fatal(err)
+		}
+		deleted, err = st.ListDeletedFiles(before, "c.png")
+		if err != nil || len(deleted) != 1 {
+			t.Fatalf("deleted c.png %v, %v", deleted, err)
+		}
+		removed, last, err = st.PurgeFile(deleted[0].ID, sum, before)
+		if err != nil || !removed || !last {
+			t.Fatalf("purge of the last reference: removed %v, last %v, %v", removed, last, err)
+		}
+	})
+}
+
+// TestVersionsAfterPurge не выдает номер окончательно удаленной версии следующей загрузке
+func TestVersionsAfterPurge(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		now := time.Now()
+		for i := 1; i <= 2; i++ {
+			saveImage(t, st, "a.png", 10, checksum(i), now)
+		}
+		saveImage(t, st, "b.png", 10, checksum(3), now)
+
+		// a.png теряет последнюю версию, b.png - все
+		if _, err := st.SoftDeleteFile("a.png", 2); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.SoftDeleteFile("b.png", 0); err != nil {
+			t.Fatal(err)
+		}
+		before := time.Now().Add(time.Hour)
+		deleted, err := st.ListDeletedFiles(before, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range deleted {
+			if removed, _, err := st.PurgeFile(file.ID, file.Checksum, before); err != nil || !removed {
+				t.Fatalf("purge %s v%d: removed %v, %v", file.FileName, file.Version, removed, err)
+			}
+		}
+
+		if version := saveImage(t, st, "a.png", 10, checksum(4), now); version != 3 {
+			t.Fatalf("version after purging a.png v2 is %d, want 3", version)
+		}
+		if version := saveImage(t, st, "b.png", 10, checksum(5), now); version != 2 {
+			t.Fatalf("version after purging all of b.png is %d, want 2", version)
+		}
+		file, err := st.GetFileInfo("a.png", 0)
+		if err != nil || file.Version != 3 {
+			t.Fatalf("latest a.png: v%d, %v", file.Version, err)
+		}
+	})
+}
+
+// TestUploadSessionsOfOneName: у каждой загрузки своя сессия, даже если имя файла то же
+func TestUploadSessionsOfOneName(t *testing.T) {
+	forEachCatalog(t, func(t *testing.T, st catalog.IStorage) {
+		for _, sessionID := range []string{"session-1", "session-2"} {
+			if err := st.CreateUploadSession(sessionID, "a.png", "ns"); err != nil {
+				t.Fatalf("create %s: %v", sessionID, err)
+			}
+		}
+		if err := st.UpdateUploadOffset("session-1", 10, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		for sessionID, offset := range map[string]int64{"session-1": 10, "session-2": 0} {
+			session, err := st.GetUploadSession(sessionID)
+			if err != nil {
+				t.Fatalf("get %s: %v", sessionID, err)
+			}
+			if session.FileName != "a.png" || session.Offset != offset {
+				t.Fatalf("%s: %+v, want offset %d", sessionID, session, offset)
+			}
+		}
+	})
+}
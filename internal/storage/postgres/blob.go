@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// acquireBlob заводит blob для checksum или увеличивает счетчик ссылок на существующий.
+// Возвращает id blob-а, то, в каком виде он лежит в хранилище, и создан ли он сейчас. Реплики и фрагменты
+// записываются только для нового blob-а: у существующего они уже есть, а загруженная копия не сохраняется
+func acquireBlob(tx *sql.Tx, checksum string, size int, stored catalog.StoredBlob) (int64, catalog.StoredBlob, bool, error) {
+	var keyID, storedChecksum sql.NullString
+	if stored.KeyID != "" {
+		keyID = sql.NullString{String: stored.KeyID, Valid: true}
+	}
+	if stored.StoredChecksum != "" {
+		storedChecksum = sql.NullString{String: stored.StoredChecksum, Valid: true}
+	}
+
+	var blobID, refCount int64
+	var existing catalog.StoredBlob
+	err := tx.QueryRow(`
+	INSERT INTO blobs (checksum, size_bytes, ref_count, encoding, stored_size, key_id, wrapped_key, stored_checksum,
+		last_accessed_at)
+	VALUES ($1, $2, 1, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+	ON CONFLICT (checksum) DO UPDATE SET ref_count = blobs.ref_count + 1
+	RETURNING id, ref_count, encoding, stored_size, COALESCE(key_id, ''), wrapped_key, COALESCE(stored_checksum, '')
+	`, checksum, size, stored.Encoding, stored.StoredSize, keyID, stored.WrappedKey, storedChecksum).
+		Scan(&blobID, &refCount, &existing.Encoding, &existing.StoredSize, &existing.KeyID, &existing.WrappedKey,
+			&existing.StoredChecksum)
+	if err != nil {
+		return 0, catalog.StoredBlob{}, false, err
+	}
+
+	if refCount == 1 {
+		for _, location := range stored.Replicas {
+			_, err := tx.Exec(`
+			INSERT INTO blob_replicas (blob_id, location) VALUES ($1, $2) ON CONFLICT DO NOTHING
+			`, blobID, location)
+			if err != nil {
+				return 0, catalog.StoredBlob{}, false, err
+			}
+		}
+		if err := insertShards(tx, blobID, stored.Shards); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+		existing.Replicas = stored.Replicas
+		existing.Shards = stored.Shards
+	} else {
+		if existing.Replicas, err = blobReplicas(tx, blobID); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+		if existing.Shards, err = blobShards(tx, blobID); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+	}
+
+	return blobID, existing, refCount == 1, nil
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// blobReplicas возвращает каталоги с копиями blob-а в порядке записи
+func blobReplicas(q queryer, blobID int64) ([]string, error) {
+	rows, err := q.Query(`
+	SELECT location FROM blob_replicas WHERE blob_id = $1 ORDER BY created_at, location
+	`, blobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var replicas []string
+	for rows.Next() {
+		var location string
+		if err := rows.Scan(&location); err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, location)
+	}
+
+	return replicas, rows.Err()
+}
+
+// FindFileLocation возвращает, где и в каком виде в хранилище лежит содержимое версии файла
+// (version 0 - последняя)
+func (s *Storage) FindFileLocation(fileName string, version int64) (catalog.FileLocation, error) {
+	const op = "storage.postgres.FindFileLocation"
+
+	var location catalog.FileLocation
+	var blobID int64
+	var lastAccessedAt sql.NullTime
+	err := s.db.QueryRow(`
+	SELECT f.id, f.filename, f.version, COALESCE(b.checksum, ''), f.path_to_file, f.size_kb, f.encoding, COALESCE(f.stored_size, f.size_kb),
+		COALESCE(b.key_id, ''), b.wrapped_key, COALESCE(b.stored_checksum, ''), COALESCE(b.id, 0),
+		COALESCE(b.tier, 'hot'), b.last_accessed_at
+	FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE f.filename = $1 AND ($2 = 0 OR f.version = $2) AND f.deleted_at IS NULL
+	ORDER BY f.version DESC LIMIT 1
+	`, fileName, version).Scan(&location.ID, &location.FileName, &location.Version, &location.Checksum, &location.Path,
+		&location.Size, &location.Encoding, &location.StoredSize, &location.KeyID, &location.WrappedKey,
+		&location.StoredChecksum, &blobID, &location.Tier, &lastAccessedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, catalog.ErrFileNotFound)
+		}
+		return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	location.LastAccessedAt = lastAccessedAt.Time.UTC()
+
+	if blobID != 0 {
+		if location.Replicas, err = blobReplicas(s.db, blobID); err != nil {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if location.Shards, err = blobShards(s.db, blobID); err != nil {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return location, nil
+}
+
+// ReleaseBlob уменьшает счетчик ссылок и удаляет запись о blob-е, когда ссылок не осталось.
+// Возвращает true, если это была последняя ссылка и данные можно удалять
+func (s *Storage) ReleaseBlob(checksum string) (bool, error) {
+	const op = "storage.postgres.ReleaseBlob"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	last, err := releaseBlob(tx, checksum)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return last, nil
+}
+
+func releaseBlob(tx *sql.Tx, checksum string) (bool, error) {
+	var refCount int64
+	err := tx.QueryRow(`
+	UPDATE blobs SET ref_count = ref_count - 1
+	WHERE checksum = $1 AND ref_count > 0
+	RETURNING ref_count
+	`, checksum).Scan(&refCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if refCount > 0 {
+		return false, nil
+	}
+
+	for _, table := range []string{"blob_replicas", "blob_shards", "blob_moves", "blob_variants"} {
+		_, err = tx.Exec(`
+		DELETE FROM `+table+` WHERE blob_id = (SELECT id FROM blobs WHERE checksum = $1)
+		`, checksum)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM blobs WHERE checksum = $1", checksum); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// blobLockClass отделяет локи blob-ов от остальных advisory-локов базы ("blob")
+const blobLockClass = 0x626c6f62
+
+// LockBlob берет лок blob-а key, общий для всех экземпляров сервера с этой базой: advisory-лок
+// транзакции, которая остается открытой до unlock. Соединение с базой на это время занято
+func (s *Storage) LockBlob(ctx context.Context, key string) (func() error, error) {
+	const op = "storage.postgres.LockBlob"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", blobLockClass, key); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tx.Commit, nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// ListWrappedKeys возвращает ключи данных всех зашифрованных blob-ов
+func (s *Storage) ListWrappedKeys() ([]catalog.WrappedKey, error) {
+	const op = "storage.postgres.ListWrappedKeys"
+
+	rows, err := s.db.Query(`
+	SELECT checksum, key_id, wrapped_key FROM blobs
+	WHERE key_id IS NOT NULL
+	ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []catalog.WrappedKey
+	for rows.Next() {
+		var key catalog.WrappedKey
+		if err := rows.Scan(&key.Checksum, &key.KeyID, &key.WrappedKey); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// UpdateWrappedKey заменяет ключ данных blob-а, закрытый мастер-ключом oldKeyID, на wrapped под keyID.
+// Возвращает false, если ключ blob-а уже поменялся или blob удален
+func (s *Storage) UpdateWrappedKey(checksum string, oldKeyID string, keyID string, wrapped []byte) (bool, error) {
+	const op = "storage.postgres.UpdateWrappedKey"
+
+	res, err := s.db.Exec(`
+	UPDATE blobs SET key_id = $1, wrapped_key = $2
+	WHERE checksum = $3 AND key_id = $4
+	`, keyID, wrapped, checksum, oldKeyID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// SaveFileExif записывает метаданные EXIF версии файла fileID, заменяя прежние
+func (s *Storage) SaveFileExif(fileID int64, exif catalog.Exif) error {
+	const op = "storage.postgres.SaveFileExif"
+
+	var capturedAt sql.NullTime
+	if !exif.CapturedAt.IsZero() {
+		capturedAt = sql.NullTime{Time: exif.CapturedAt.UTC(), Valid: true}
+	}
+	var latitude, longitude, altitude sql.NullFloat64
+	if exif.HasGPS {
+		latitude = sql.NullFloat64{Float64: exif.Latitude, Valid: true}
+		longitude = sql.NullFloat64{Float64: exif.Longitude, Valid: true}
+		altitude = sql.NullFloat64{Float64: exif.Altitude, Valid: true}
+	}
+
+	_, err := s.db.Exec(`
+	INSERT INTO file_exif (file_id, camera_make, camera_model, lens_make, lens_model, captured_at, orientation,
+		gps_latitude, gps_longitude, gps_altitude)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (file_id) DO UPDATE SET camera_make = EXCLUDED.camera_make, camera_model = EXCLUDED.camera_model,
+		lens_make = EXCLUDED.lens_make, lens_model = EXCLUDED.lens_model, captured_at = EXCLUDED.captured_at,
+		orientation = EXCLUDED.orientation, gps_latitude = EXCLUDED.gps_latitude,
+		gps_longitude = EXCLUDED.gps_longitude, gps_altitude = EXCLUDED.gps_altitude
+	`, fileID, exif.CameraMake, exif.CameraModel, exif.LensMake, exif.LensModel, capturedAt, exif.Orientation,
+		latitude, longitude, altitude)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetFileExif возвращает метаданные EXIF версии файла fileID
+func (s *Storage) GetFileExif(fileID int64) (catalog.Exif, error) {
+	const op = "storage.postgres.GetFileExif"
+
+	var exif catalog.Exif
+	var capturedAt sql.NullTime
+	var latitude, longitude, altitude sql.NullFloat64
+	err := s.db.QueryRow(`
+	SELECT camera_make, camera_model, lens_make, lens_model, captured_at, orientation, gps_latitude,
+		gps_longitude, gps_altitude
+	FROM file_exif WHERE file_id = $1
+	`, fileID).Scan(&exif.CameraMake, &exif.CameraModel, &exif.LensMake, &exif.LensModel, &capturedAt,
+		&exif.Orientation, &latitude, &longitude, &altitude)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.Exif{}, catalog.ErrExifNotFound
+		}
+		return catalog.Exif{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	exif.CapturedAt = capturedAt.Time
+	exif.HasGPS = latitude.Valid && longitude.Valid
+	exif.Latitude, exif.Longitude, exif.Altitude = latitude.Float64, longitude.Float64, altitude.Float64
+
+	return exif, nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// ListFileLocations возвращает расположение содержимого всех записей, включая удаленные.
+// Реплики и фрагменты не заполняются
+func (s *Storage) ListFileLocations() ([]catalog.FileLocation, error) {
+	const op = "storage.postgres.ListFileLocations"
+
+	rows, err := s.db.Query(`
+	SELECT f.id, f.filename, f.version, COALESCE(b.checksum, ''), f.path_to_file, f.size_kb, f.encoding,
+		COALESCE(f.stored_size, f.size_kb), COALESCE(b.key_id, ''), COALESCE(b.stored_checksum, ''), COALESCE(b.tier, 'hot')
+	FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	ORDER BY f.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var locations []catalog.FileLocation
+	for rows.Next() {
+		var location catalog.FileLocation
+		err := rows.Scan(&location.ID, &location.FileName, &location.Version, &location.Checksum, &location.Path,
+			&location.Size, &location.Encoding, &location.StoredSize, &location.KeyID, &location.StoredChecksum, &location.Tier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return locations, nil
+}
+
+// HasFilePath проверяет, ссылается ли на path хоть одна запись files, включая удаленные, или вариант blob-а
+func (s *Storage) HasFilePath(path string) (bool, error) {
+	const op = "storage.postgres.HasFilePath"
+
+	var found bool
+	err := s.db.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM files WHERE path_to_file = $1) OR EXISTS (SELECT 1 FROM blob_variants WHERE path = $1)
+	`, path).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return found, nil
+}
+
+func (s *Storage) UpdateFilePath(id int64, path string) error {
+	const op = "storage.postgres.UpdateFilePath"
+
+	_, err := s.db.Exec("UPDATE files SET path_to_file = $1 WHERE id = $2", path, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
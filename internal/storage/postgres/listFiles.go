@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// ListFiles возвращает страницу файлов, отфильтрованных и отсортированных по filter
+func (s *Storage) ListFiles(filter catalog.ListFilesFilter) ([]catalog.FileInfo, error) {
+	const op = "storage.postgres.ListFiles"
+
+	sortBy, descending := filter.Sort()
+
+	var where []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.FileName != "" {
+		where = append(where, "f.filename = "+arg(filter.FileName))
+	} else {
+		where = append(where, "f.version = (SELECT MAX(version) FROM files WHERE filename = f.filename AND ("+arg(filter.IncludeDeleted)+" OR deleted_at IS NULL))")
+	}
+
+	if !filter.IncludeDeleted {
+		where = append(where, "f.deleted_at IS NULL")
+	}
+
+	if filter.NamePrefix != "" {
+		// диапазон вместо LIKE, чтобы работал idx_files_filename
+		where = append(where, "f.filename >= "+arg(filter.NamePrefix)+" AND f.filename < "+arg(filter.NamePrefix+"\U0010FFFF"))
+	}
+
+	if filter.MimeType != "" {
+		where = append(where, "f.mime_type = "+arg(filter.MimeType))
+	}
+
+	if filter.MimeMismatch {
+		where = append(where, "f.declared_mime_type IS NOT NULL")
+	}
+
+	if filter.MinSize > 0 {
+		where = append(where, "f.size_kb >= "+arg(filter.MinSize))
+	}
+
+	if filter.MaxSize > 0 {
+		where = append(where, "f.size_kb <= "+arg(filter.MaxSize))
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "f.created_at >= "+arg(filter.CreatedAfter.UTC()))
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "f.created_at < "+arg(filter.CreatedBefore.UTC()))
+	}
+
+	order := "ASC"
+	cmp := ">"
+	if descending {
+		order = "DESC"
+		cmp = "<"
+	}
+
+	if filter.After != nil {
+		value, err := cursorValue(sortBy, filter.After.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		where = append(where, fmt.Sprintf("(f.%s, f.id) %s (%s, %s)", sortBy, cmp, arg(value), arg(filter.After.ID)))
+	}
+
+	query := fmt.Sprintf(`
+	SELECT `+fileInfoColumns+`
+	FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE %s
+	ORDER BY f.%s %s, f.id %s
+	`, strings.Join(where, " AND "), sortBy, order, order)
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	files, err := scanFileInfos(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return files, nil
+}
+
+func cursorValue(sortBy catalog.SortField, value string) (any, error) {
+	switch sortBy {
+	case catalog.SortBySize, catalog.SortByVersion:
+		var n int64
+		if _, err := fmt.Sscan(value, &n); err != nil {
+			return nil, fmt.Errorf("invalid cursor value %q: %w", value, err)
+		}
+		return n, nil
+	case catalog.SortByCreated:
+		createdAt, err := time.ParseInLocation(catalog.CursorTimeLayout, value, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor value %q: %w", value, err)
+		}
+		return createdAt, nil
+	}
+	return value, nil
+}
+
+// fileInfoColumns - колонки files f и blobs b, которые читает scanFileInfo
+const fileInfoColumns = `f.id, f.filename, f.version, f.size_kb, COALESCE(f.mime_type, ''), COALESCE(f.declared_mime_type, ''), COALESCE(f.checksum, ''),
+	f.encoding, COALESCE(f.stored_size, f.size_kb), f.created_at, f.updated_at, f.deleted_at,
+	COALESCE(b.tier, 'hot'), b.last_accessed_at`
+
+func scanFileInfo(row interface{ Scan(...any) error }, file *catalog.FileInfo) error {
+	var deletedAt, lastAccessedAt sql.NullTime
+	err := row.Scan(&file.ID, &file.FileName, &file.Version, &file.Size, &file.MimeType, &file.DeclaredMimeType, &file.Checksum,
+		&file.Encoding, &file.StoredSize, &file.CreatedAt, &file.UpdatedAt, &deletedAt,
+		&file.Tier, &lastAccessedAt)
+	if err != nil {
+		return err
+	}
+	file.CreatedAt = file.CreatedAt.UTC()
+	file.UpdatedAt = file.UpdatedAt.UTC()
+	file.DeletedAt = deletedAt.Time
+	file.LastAccessedAt = lastAccessedAt.Time.UTC()
+	return nil
+}
+
+func scanFileInfos(rows *sql.Rows) ([]catalog.FileInfo, error) {
+	var files []catalog.FileInfo
+
+	for rows.Next() {
+		var file catalog.FileInfo
+		if err := scanFileInfo(rows, &file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
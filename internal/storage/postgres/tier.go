@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// TouchBlob записывает время последнего скачивания blob-а
+func (s *Storage) TouchBlob(checksum string, at time.Time) error {
+	const op = "storage.postgres.TouchBlob"
+
+	_, err := s.db.Exec(`
+	UPDATE blobs SET last_accessed_at = $1 WHERE checksum = $2
+	`, at.UTC(), checksum)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListIdleBlobs возвращает до limit blob-ов из tier, которые не скачивали с before, начиная с самых давних
+func (s *Storage) ListIdleBlobs(tier string, before time.Time, limit int) ([]catalog.IdleBlob, error) {
+	const op = "storage.postgres.ListIdleBlobs"
+
+	rows, err := s.db.Query(`
+	SELECT checksum, COALESCE(stored_checksum, ''), last_accessed_at FROM blobs
+	WHERE tier = $1 AND last_accessed_at < $2
+	ORDER BY last_accessed_at, id LIMIT $3
+	`, tier, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var blobs []catalog.IdleBlob
+	for rows.Next() {
+		var blob catalog.IdleBlob
+		var accessed sql.NullTime
+		if err := rows.Scan(&blob.Checksum, &blob.StoredChecksum, &accessed); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		blob.LastAccessedAt = accessed.Time.UTC()
+		blobs = append(blobs, blob)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return blobs, nil
+}
+
+// MoveBlobTier переводит blob из хранилища from в to и записывает перенос в историю.
+// Возвращает false, если blob уже не в from или удален
+func (s *Storage) MoveBlobTier(checksum string, from string, to string, at time.Time) (bool, error) {
+	const op = "storage.postgres.MoveBlobTier"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var blobID int64
+	err = tx.QueryRow(`
+	UPDATE blobs SET tier = $1 WHERE checksum = $2 AND tier = $3 RETURNING id
+	`, to, checksum, from).Scan(&blobID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO blob_moves (blob_id, from_tier, to_tier, moved_at) VALUES ($1, $2, $3, $4)
+	`, blobID, from, to, at.UTC())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// ListBlobMoves возвращает историю переносов blob-а по порядку
+func (s *Storage) ListBlobMoves(checksum string) ([]catalog.TierMove, error) {
+	const op = "storage.postgres.ListBlobMoves"
+
+	var blobID int64
+	err := s.db.QueryRow("SELECT id FROM blobs WHERE checksum = $1", checksum).Scan(&blobID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	moves, err := blobMoves(s.db, blobID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moves, nil
+}
+
+func blobMoves(q queryer, blobID int64) ([]catalog.TierMove, error) {
+	rows, err := q.Query(`
+	SELECT from_tier, to_tier, moved_at FROM blob_moves WHERE blob_id = $1 ORDER BY id
+	`, blobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []catalog.TierMove
+	for rows.Next() {
+		var move catalog.TierMove
+		var movedAt sql.NullTime
+		if err := rows.Scan(&move.FromTier, &move.ToTier, &movedAt); err != nil {
+			return nil, err
+		}
+		move.MovedAt = movedAt.Time.UTC()
+		moves = append(moves, move)
+	}
+
+	return moves, rows.Err()
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// GetNamespaceUsage считает файлы (включая еще не очищенные удаленные) и байты незавершенных
+// загрузок пространства имен. Сессия excludeSessionID не учитывается - это текущая загрузка
+func (s *Storage) GetNamespaceUsage(namespace string, excludeSessionID string) (catalog.Usage, error) {
+	const op = "storage.postgres.GetNamespaceUsage"
+
+	var usage catalog.Usage
+	err := s.db.QueryRow(`
+	SELECT
+		(SELECT COALESCE(SUM(size_kb), 0) FROM files WHERE namespace = $1) +
+		(SELECT COALESCE(SUM(received_offset), 0) FROM upload_sessions WHERE namespace = $1 AND id != $2),
+		(SELECT COUNT(*) FROM files WHERE namespace = $1) +
+		(SELECT COUNT(*) FROM upload_sessions WHERE namespace = $1 AND id != $2)
+	`, namespace, excludeSessionID).Scan(&usage.Bytes, &usage.Files)
+	if err != nil {
+		return catalog.Usage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return usage, nil
+}
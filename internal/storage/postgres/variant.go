@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// SaveBlobVariant записывает вариант blob-а variant.Checksum, заменяя прежний с тем же именем.
+// Возвращает false, если blob-а уже нет
+func (s *Storage) SaveBlobVariant(variant catalog.Variant) (bool, error) {
+	const op = "storage.postgres.SaveBlobVariant"
+
+	result, err := s.db.Exec(`
+	INSERT INTO blob_variants (blob_id, name, path, mime_type, width, height, size_bytes, stored_size, stored_checksum,
+		key_salt, created_at)
+	SELECT id, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10 FROM blobs WHERE checksum = $11
+	ON CONFLICT (blob_id, name) DO UPDATE SET path = EXCLUDED.path, mime_type = EXCLUDED.mime_type,
+		width = EXCLUDED.width, height = EXCLUDED.height, size_bytes = EXCLUDED.size_bytes,
+		stored_size = EXCLUDED.stored_size, stored_checksum = EXCLUDED.stored_checksum, key_salt = EXCLUDED.key_salt,
+		created_at = EXCLUDED.created_at
+	`, variant.Name, variant.Path, variant.MimeType, variant.Width, variant.Height, variant.Size, variant.StoredSize,
+		variant.StoredChecksum, variant.KeySalt, variant.CreatedAt.UTC(), variant.Checksum)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved > 0, nil
+}
+
+// ListBlobVariants возвращает варианты blob-а по имени, пустой checksum - варианты всех blob-ов
+func (s *Storage) ListBlobVariants(checksum string) ([]catalog.Variant, error) {
+	const op = "storage.postgres.ListBlobVariants"
+
+	rows, err := s.db.Query(`
+	SELECT b.checksum, v.name, v.path, v.mime_type, v.width, v.height, v.size_bytes, v.stored_size,
+		v.stored_checksum, v.key_salt, v.created_at
+	FROM blob_variants v
+	JOIN blobs b ON b.id = v.blob_id
+	WHERE $1 = '' OR b.checksum = $1
+	ORDER BY v.blob_id, v.name
+	`, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var variants []catalog.Variant
+	for rows.Next() {
+		var variant catalog.Variant
+		var createdAt sql.NullTime
+		err := rows.Scan(&variant.Checksum, &variant.Name, &variant.Path, &variant.MimeType, &variant.Width,
+			&variant.Height, &variant.Size, &variant.StoredSize, &variant.StoredChecksum, &variant.KeySalt, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		variant.CreatedAt = createdAt.Time
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return variants, nil
+}
+
+// DeleteBlobVariant удаляет запись о варианте. Данные в хранилище остаются
+func (s *Storage) DeleteBlobVariant(checksum string, name string) error {
+	const op = "storage.postgres.DeleteBlobVariant"
+
+	_, err := s.db.Exec(`
+	DELETE FROM blob_variants WHERE blob_id = (SELECT id FROM blobs WHERE checksum = $1) AND name = $2
+	`, checksum, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
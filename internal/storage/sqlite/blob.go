@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// acquireBlob заводит blob для checksum или увеличивает счетчик ссылок на существующий.
+// Возвращает id blob-а, то, в каком виде он лежит в хранилище, и создан ли он сейчас. Реплики и фрагменты
+// записываются только для нового blob-а: у существующего они уже есть, а загруженная копия не сохраняется
+func acquireBlob(tx *sql.Tx, checksum string, size int, stored catalog.StoredBlob) (int64, catalog.StoredBlob, bool, error) {
+	var keyID sql.NullString
+	if stored.KeyID != "" {
+		keyID = sql.NullString{String: stored.KeyID, Valid: true}
+	}
+
+	_, err := tx.Exec(`
+	INSERT INTO blobs (checksum, size_bytes, ref_count, encoding, stored_size, key_id, wrapped_key, stored_checksum,
+		last_accessed_at)
+	VALUES (?, ?, 1, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(checksum) DO UPDATE SET ref_count = ref_count + 1
+	`, checksum, size, stored.Encoding, stored.StoredSize, keyID, stored.WrappedKey, nullString(stored.StoredChecksum))
+	if err != nil {
+		return 0, catalog.StoredBlob{}, false, err
+	}
+
+	var blobID, refCount int64
+	var existing catalog.StoredBlob
+	err = tx.QueryRow(`
+	SELECT id, ref_count, encoding, stored_size, COALESCE(key_id, ''), wrapped_key, COALESCE(stored_checksum, '')
+	FROM blobs WHERE checksum = ?
+	`, checksum).Scan(&blobID, &refCount, &existing.Encoding, &existing.StoredSize, &existing.KeyID,
+		&existing.WrappedKey, &existing.StoredChecksum)
+	if err != nil {
+		return 0, catalog.StoredBlob{}, false, err
+	}
+
+	if refCount == 1 {
+		if err := insertReplicas(tx, blobID, stored.Replicas); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+		if err := insertShards(tx, blobID, stored.Shards); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+		existing.Replicas = stored.Replicas
+		existing.Shards = stored.Shards
+	} else {
+		if existing.Replicas, err = blobReplicas(tx, blobID); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+		if existing.Shards, err = blobShards(tx, blobID); err != nil {
+			return 0, catalog.StoredBlob{}, false, err
+		}
+	}
+
+	return blobID, existing, refCount == 1, nil
+}
+
+func insertReplicas(tx *sql.Tx, blobID int64, replicas []string) error {
+	for _, location := range replicas {
+		_, err := tx.Exec(`
+		INSERT INTO blob_replicas (blob_id, location) VALUES (?, ?) ON CONFLICT DO NOTHING
+		`, blobID, location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// blobReplicas возвращает каталоги с копиями blob-а в порядке записи
+func blobReplicas(q queryer, blobID int64) ([]string, error) {
+	rows, err := q.Query(`
+	SELECT location FROM blob_replicas WHERE blob_id = ? ORDER BY created_at, rowid
+	`, blobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var replicas []string
+	for rows.Next() {
+		var location string
+		if err := rows.Scan(&location); err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, location)
+	}
+
+	return replicas, rows.Err()
+}
+
+// FindFileLocation возвращает, где и в каком виде в хранилище лежит содержимое версии файла
+// (version 0 - последняя)
+func (s *Storage) FindFileLocation(fileName string, version int64) (catalog.FileLocation, error) {
+	const op = "storage.sqlite.FindFileLocation"
+
+	var location catalog.FileLocation
+	var blobID int64
+	var lastAccessedAt sql.NullTime
+	err := s.db.QueryRow(`
+	SELECT f.id, f.filename, f.version, COALESCE(b.checksum, ''), f.path_to_file, f.size_kb, f.encoding, COALESCE(f.stored_size, f.size_kb),
+		COALESCE(b.key_id, ''), b.wrapped_key, COALESCE(b.stored_checksum, ''), COALESCE(b.id, 0),
+		COALESCE(b.tier, 'hot'), b.last_accessed_at
+	FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE f.filename = ? AND (? = 0 OR f.version = ?) AND f.deleted_at IS NULL
+	ORDER BY f.version DESC LIMIT 1
+	`, fileName, version, version).Scan(&location.ID, &location.FileName, &location.Version, &location.Checksum, &location.Path,
+		&location.Size, &location.Encoding, &location.StoredSize, &location.KeyID, &location.WrappedKey,
+		&location.StoredChecksum, &blobID, &location.Tier, &lastAccessedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, catalog.ErrFileNotFound)
+		}
+		return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	location.LastAccessedAt = lastAccessedAt.Time
+
+	if blobID != 0 {
+		if location.Replicas, err = blobReplicas(s.db, blobID); err != nil {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if location.Shards, err = blobShards(s.db, blobID); err != nil {
+			return catalog.FileLocation{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return location, nil
+}
+
+// ReleaseBlob уменьшает счетчик ссылок и удаляет запись о blob-е, когда ссылок не осталось.
+// Возвращает true, если это была последняя ссылка и данные на диске можно удалять
+func (s *Storage) ReleaseBlob(checksum string) (bool, error) {
+	const op = "storage.sqlite.ReleaseBlob"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	last, err := releaseBlob(tx, checksum)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return last, nil
+}
+
+func releaseBlob(tx *sql.Tx, checksum string) (bool, error) {
+	var refCount int64
+	err := tx.QueryRow(`
+	UPDATE blobs SET ref_count = ref_count - 1
+	WHERE checksum = ? AND ref_count > 0
+	RETURNING ref_count
+	`, checksum).Scan(&refCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if refCount > 0 {
+		return false, nil
+	}
+
+	for _, table := range []string{"blob_replicas", "blob_shards", "blob_moves", "blob_variants"} {
+		_, err = tx.Exec(`
+		DELETE FROM `+table+` WHERE blob_id = (SELECT id FROM blobs WHERE checksum = ?)
+		`, checksum)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM blobs WHERE checksum = ?", checksum); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// LockBlob ничего не делает: файл базы открывает один экземпляр сервера, и ему хватает лока в процессе
+func (s *Storage) LockBlob(ctx context.Context, key string) (func() error, error) {
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// sqliteTimeLayout совпадает с форматом CURRENT_TIMESTAMP
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// SoftDeleteFile помечает версию файла удаленной (version 0 - все версии).
+// Возвращает количество помеченных версий
+func (s *Storage) SoftDeleteFile(fileName string, version int64) (int64, error) {
+	const op = "storage.sqlite.SoftDeleteFile"
+
+	result, err := s.db.Exec(`
+	UPDATE files SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	WHERE filename = ? AND (? = 0 OR version = ?) AND deleted_at IS NULL
+	`, fileName, version, version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// RestoreFile снимает пометку об удалении (version 0 - все версии)
+func (s *Storage) RestoreFile(fileName string, version int64) (int64, error) {
+	const op = "storage.sqlite.RestoreFile"
+
+	result, err := s.db.Exec(`
+	UPDATE files SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE filename = ? AND (? = 0 OR version = ?) AND deleted_at IS NOT NULL
+	`, fileName, version, version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// ListDeletedFiles возвращает файлы, удаленные не позже before. Пустой fileName - все файлы
+func (s *Storage) ListDeletedFiles(before time.Time, fileName string) ([]catalog.DeletedFile, error) {
+	const op = "storage.sqlite.ListDeletedFiles"
+
+	rows, err := s.db.Query(`
+	SELECT f.id, f.filename, f.version, COALESCE(b.checksum, ''), f.path_to_file, f.deleted_at FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE f.deleted_at IS NOT NULL AND f.deleted_at <= ? AND (? = '' OR f.filename = ?)
+	`, before.UTC().Format(sqliteTimeLayout), fileName, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var files []catalog.DeletedFile
+	for rows.Next() {
+		var file catalog.DeletedFile
+		if err := rows.Scan(&file.ID, &file.FileName, &file.Version, &file.Checksum, &file.Path, &file.DeletedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return files, nil
+}
+
+// PurgeFile окончательно удаляет запись о файле и отпускает ссылку на его blob.
+// Запись удаляется, только если она все еще помечена удаленной не позже before.
+// Возвращает, была ли запись удалена и была ли это последняя ссылка на blob
+func (s *Storage) PurgeFile(id int64, checksum string, before time.Time) (bool, bool, error) {
+	const op = "storage.sqlite.PurgeFile"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+	DELETE FROM files WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at <= ?
+	`, id, before.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		// файл успели восстановить
+		return false, false, nil
+	}
+
+	lastRef, err := unlinkFile(tx, id, checksum)
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, lastRef, nil
+}
+
+// DiscardFile удаляет только что записанную версию файла, не помечая ее удаленной, и отпускает ссылку
+// на ее blob. Так откатывается загрузка, чье содержимое не дошло до хранилища.
+// Возвращает, была ли это последняя ссылка на blob
+func (s *Storage) DiscardFile(fileName string, version int64) (bool, error) {
+	const op = "storage.sqlite.DiscardFile"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var checksum string
+	err = tx.QueryRow(`
+	SELECT f.id, COALESCE(b.checksum, '') FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE f.filename = ? AND f.version = ?
+	`, fileName, version).Scan(&id, &checksum)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("%s: %w", op, catalog.ErrFileNotFound)
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM files WHERE id = ?", id); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lastRef, err := unlinkFile(tx, id, checksum)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lastRef, nil
+}
+
+// unlinkFile убирает ссылки на удаленную запись файла id и отпускает его blob
+func unlinkFile(tx *sql.Tx, id int64, checksum string) (bool, error) {
+	if _, err := tx.Exec("UPDATE files SET previous_id = NULL WHERE previous_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM file_exif WHERE file_id = ?", id); err != nil {
+		return false, err
+	}
+
+	if checksum == "" {
+		return false, nil
+	}
+	return releaseBlob(tx, checksum)
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// gps переводит координаты в NULL, если их нет
+func gps(e catalog.Exif) (sql.NullFloat64, sql.NullFloat64, sql.NullFloat64) {
+	if !e.HasGPS {
+		return sql.NullFloat64{}, sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: e.Latitude, Valid: true}, sql.NullFloat64{Float64: e.Longitude, Valid: true},
+		sql.NullFloat64{Float64: e.Altitude, Valid: true}
+}
+
+func scanExif(row interface{ Scan(...any) error }, exif *catalog.Exif) error {
+	var capturedAt sql.NullTime
+	var latitude, longitude, altitude sql.NullFloat64
+	err := row.Scan(&exif.CameraMake, &exif.CameraModel, &exif.LensMake, &exif.LensModel, &capturedAt,
+		&exif.Orientation, &latitude, &longitude, &altitude)
+	exif.CapturedAt = capturedAt.Time
+	exif.HasGPS = latitude.Valid && longitude.Valid
+	exif.Latitude, exif.Longitude, exif.Altitude = latitude.Float64, longitude.Float64, altitude.Float64
+	return err
+}
+
+const exifColumns = `camera_make, camera_model, lens_make, lens_model, captured_at, orientation, gps_latitude,
+	gps_longitude, gps_altitude`
+
+// SaveFileExif записывает метаданные EXIF версии файла fileID, заменяя прежние
+func (s *Storage) SaveFileExif(fileID int64, exif catalog.Exif) error {
+	const op = "storage.sqlite.SaveFileExif"
+
+	if err := insertExif(s.db, fileID, exif); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertExif(q execer, fileID int64, exif catalog.Exif) error {
+	latitude, longitude, altitude := gps(exif)
+	_, err := q.Exec(`
+	INSERT OR REPLACE INTO file_exif (file_id, `+exifColumns+`)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, fileID, exif.CameraMake, exif.CameraModel, exif.LensMake, exif.LensModel, formatTime(exif.CapturedAt),
+		exif.Orientation, latitude, longitude, altitude)
+	return err
+}
+
+// GetFileExif возвращает метаданные EXIF версии файла fileID
+func (s *Storage) GetFileExif(fileID int64) (catalog.Exif, error) {
+	const op = "storage.sqlite.GetFileExif"
+
+	var exif catalog.Exif
+	err := scanExif(s.db.QueryRow(`SELECT `+exifColumns+` FROM file_exif WHERE file_id = ?`, fileID), &exif)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.Exif{}, catalog.ErrExifNotFound
+		}
+		return catalog.Exif{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exif, nil
+}
@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+func insertShards(tx *sql.Tx, blobID int64, shards []catalog.BlobShard) error {
+	for _, shard := range shards {
+		_, err := tx.Exec(`
+		INSERT INTO blob_shards (blob_id, shard_index, location, health) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING
+		`, blobID, shard.Index, shard.Location, shard.Health)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blobShards возвращает фрагменты blob-а по возрастанию номера
+func blobShards(q queryer, blobID int64) ([]catalog.BlobShard, error) {
+	rows, err := q.Query(`
+	SELECT shard_index, location, health FROM blob_shards WHERE blob_id = ? ORDER BY shard_index
+	`, blobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shards []catalog.BlobShard
+	for rows.Next() {
+		var shard catalog.BlobShard
+		if err := rows.Scan(&shard.Index, &shard.Location, &shard.Health); err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+
+	return shards, rows.Err()
+}
+
+// UpdateShardHealth записывает, где и в каком состоянии нашелся фрагмент index blob-а checksum
+func (s *Storage) UpdateShardHealth(checksum string, index int, location string, health string) error {
+	const op = "storage.sqlite.UpdateShardHealth"
+
+	_, err := s.db.Exec(`
+	UPDATE blob_shards SET location = ?, health = ?, updated_at = CURRENT_TIMESTAMP
+	WHERE shard_index = ? AND blob_id = (SELECT id FROM blobs WHERE checksum = ?)
+	`, location, health, index, checksum)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
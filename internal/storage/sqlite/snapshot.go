@@ -0,0 +1,291 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+// Export читает весь каталог
+func (s *Storage) Export() (catalog.Snapshot, error) {
+	const op = "storage.sqlite.Export"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var snapshot catalog.Snapshot
+	if snapshot.Blobs, err = exportBlobs(tx); err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if snapshot.Files, err = exportFiles(tx); err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if snapshot.UploadSessions, err = exportUploadSessions(tx); err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if snapshot.FileVersions, err = exportFileVersions(tx); err != nil {
+		return catalog.Snapshot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return snapshot, nil
+}
+
+func exportBlobs(tx *sql.Tx) ([]catalog.BlobRow, error) {
+	rows, err := tx.Query(`
+	SELECT id, checksum, size_bytes, ref_count, encoding, COALESCE(stored_size, size_bytes),
+		COALESCE(key_id, ''), wrapped_key, COALESCE(stored_checksum, ''), tier, last_accessed_at, created_at
+	FROM blobs ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blobs []catalog.BlobRow
+	for rows.Next() {
+		var blob catalog.BlobRow
+		var lastAccessedAt, createdAt sql.NullTime
+		err := rows.Scan(&blob.ID, &blob.Checksum, &blob.Size, &blob.RefCount, &blob.Encoding, &blob.StoredSize,
+			&blob.KeyID, &blob.WrappedKey, &blob.StoredChecksum, &blob.Tier, &lastAccessedAt, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		blob.LastAccessedAt = lastAccessedAt.Time
+		blob.CreatedAt = createdAt.Time
+		blobs = append(blobs, blob)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range blobs {
+		if blobs[i].Replicas, err = blobReplicas(tx, blobs[i].ID); err != nil {
+			return nil, err
+		}
+		if blobs[i].Shards, err = blobShards(tx, blobs[i].ID); err != nil {
+			return nil, err
+		}
+		if blobs[i].Moves, err = blobMoves(tx, blobs[i].ID); err != nil {
+			return nil, err
+		}
+		if blobs[i].Variants, err = blobVariants(tx, blobs[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return blobs, nil
+}
+
+func exportFiles(tx *sql.Tx) ([]catalog.FileRow, error) {
+	rows, err := tx.Query(`
+	SELECT id, filename, path_to_file, size_kb, COALESCE(mime_type, ''), COALESCE(declared_mime_type, ''), COALESCE(checksum, ''),
+		COALESCE(blob_id, 0), encoding, COALESCE(stored_size, size_kb), version, COALESCE(previous_id, 0),
+		namespace, created_at, updated_at, deleted_at
+	FROM files ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []catalog.FileRow
+	for rows.Next() {
+		var file catalog.FileRow
+		var createdAt, updatedAt, deletedAt sql.NullTime
+		err := rows.Scan(&file.ID, &file.FileName, &file.Path, &file.Size, &file.MimeType, &file.DeclaredMimeType, &file.Checksum,
+			&file.BlobID, &file.Encoding, &file.StoredSize, &file.Version, &file.PreviousID,
+			&file.Namespace, &createdAt, &updatedAt, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		file.CreatedAt = createdAt.Time
+		file.UpdatedAt = updatedAt.Time
+		file.DeletedAt = deletedAt.Time
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		var exif catalog.Exif
+		err := scanExif(tx.QueryRow(`SELECT `+exifColumns+` FROM file_exif WHERE file_id = ?`, files[i].ID), &exif)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[i].Exif = &exif
+	}
+
+	return files, nil
+}
+
+func exportUploadSessions(tx *sql.Tx) ([]catalog.UploadSession, error) {
+	rows, err := tx.Query(`
+	SELECT id, filename, namespace, received_offset, hash_state, created_at, updated_at
+	FROM upload_sessions ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []catalog.UploadSession
+	for rows.Next() {
+		var session catalog.UploadSession
+		err := rows.Scan(&session.ID, &session.FileName, &session.Namespace, &session.Offset, &session.HashState,
+			&session.CreatedAt, &session.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func exportFileVersions(tx *sql.Tx) ([]catalog.FileVersion, error) {
+	rows, err := tx.Query("SELECT filename, last_version FROM file_versions ORDER BY filename")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []catalog.FileVersion
+	for rows.Next() {
+		var version catalog.FileVersion
+		if err := rows.Scan(&version.FileName, &version.LastVersion); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// Import загружает snapshot в пустой каталог, сохраняя id записей
+func (s *Storage) Import(snapshot catalog.Snapshot) error {
+	const op = "storage.sqlite.Import"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var count int64
+	err = tx.QueryRow(`
+	SELECT (SELECT COUNT(*) FROM files) + (SELECT COUNT(*) FROM blobs) + (SELECT COUNT(*) FROM upload_sessions)
+		+ (SELECT COUNT(*) FROM file_versions)
+	`).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%s: %w", op, catalog.ErrCatalogNotEmpty)
+	}
+
+	for _, blob := range snapshot.Blobs {
+		_, err := tx.Exec(`
+		INSERT INTO blobs (id, checksum, size_bytes, ref_count, encoding, stored_size, key_id, wrapped_key,
+			stored_checksum, tier, last_accessed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, blob.ID, blob.Checksum, blob.Size, blob.RefCount, blob.Encoding, blob.StoredSize,
+			nullString(blob.KeyID), blob.WrappedKey, nullString(blob.StoredChecksum), blob.Tier,
+			formatTime(blob.LastAccessedAt), formatTime(blob.CreatedAt))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := insertReplicas(tx, blob.ID, blob.Replicas); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := insertShards(tx, blob.ID, blob.Shards); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, move := range blob.Moves {
+			_, err := tx.Exec(`
+			INSERT INTO blob_moves (blob_id, from_tier, to_tier, moved_at) VALUES (?, ?, ?, ?)
+			`, blob.ID, move.FromTier, move.ToTier, formatTime(move.MovedAt))
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		for _, variant := range blob.Variants {
+			_, err := tx.Exec(`
+			INSERT INTO blob_variants (blob_id, name, path, mime_type, width, height, size_bytes, stored_size,
+				stored_checksum, key_salt, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, blob.ID, variant.Name, variant.Path, variant.MimeType, variant.Width, variant.Height, variant.Size,
+				variant.StoredSize, variant.StoredChecksum, variant.KeySalt, formatTime(variant.CreatedAt))
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	for _, file := range snapshot.Files {
+		_, err := tx.Exec(`
+		INSERT INTO files (id, filename, path_to_file, size_kb, mime_type, declared_mime_type, checksum, blob_id, encoding,
+			stored_size, version, previous_id, namespace, created_at, updated_at, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, file.ID, file.FileName, file.Path, file.Size, file.MimeType, nullString(file.DeclaredMimeType), file.Checksum,
+			nullID(file.BlobID), file.Encoding, file.StoredSize, file.Version, nullID(file.PreviousID), file.Namespace,
+			formatTime(file.CreatedAt), formatTime(file.UpdatedAt), formatTime(file.DeletedAt))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if file.Exif != nil {
+			if err := insertExif(tx, file.ID, *file.Exif); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	for _, session := range snapshot.UploadSessions {
+		_, err := tx.Exec(`
+		INSERT INTO upload_sessions (id, filename, namespace, received_offset, hash_state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, session.ID, session.FileName, session.Namespace, session.Offset, session.HashState,
+			formatTime(session.CreatedAt), formatTime(session.UpdatedAt))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	for _, version := range snapshot.LastVersions() {
+		_, err := tx.Exec("INSERT INTO file_versions (filename, last_version) VALUES (?, ?)", version.FileName, version.LastVersion)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// formatTime переводит время в формат CURRENT_TIMESTAMP, нулевое время - в NULL
+func formatTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(sqliteTimeLayout), Valid: true}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
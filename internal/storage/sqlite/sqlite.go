@@ -8,27 +8,19 @@ import (
 	"os"
 	"time"
 
+	"imagestorage/internal/storage/catalog"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
 //TODO: Надо бы накрутить транзакции
 //При удалении файла нужен лок на время удаления
 
-type IStorage interface {
-	SaveImage(imageName string, size int, mimeType string, checksum string, createdAt time.Time) error
-	ListFiles() ([]FileInfo, error)
-	FindFileByName(fileName string) (string, error)
-}
-
 type Storage struct {
 	db *sql.DB
 }
 
-type FileInfo struct {
-	FileName  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-}
+var _ catalog.IStorage = (*Storage)(nil)
 
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
@@ -43,64 +35,87 @@ func New(storagePath string) (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveImage(imageName string, size int, mimeType string, checksum string, createdAt time.Time) error {
+// SaveImage добавляет новую версию файла и берет ссылку на blob с тем же checksum.
+// declaredMimeType задается, только если расширение противоречит содержимому.
+// Возвращает номер созданной версии и то, заведен ли blob этой загрузкой. Если нет, в хранилище
+// уже лежит содержимое существующего blob-а, а загруженная копия не нужна
+func (s *Storage) SaveImage(imageName string, namespace string, pathToFile string, size int, mimeType string, declaredMimeType string, checksum string, stored catalog.StoredBlob, createdAt time.Time) (int64, bool, error) {
 	const op = "storage.sqlite.SaveImage"
 
-	insertStmt, err := s.db.Prepare(`
-	INSERT INTO files (filename, path_to_file, size_kb, mime_type, checksum)
-	VALUES (?, ?, ?, ?, ?)
-	`)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	result, err := insertStmt.Exec(
-		imageName,
-		os.Getenv("PATH_TO_SAVED_IMAGES"),
-		size,
-		mimeType,
-		checksum,
-	)
+	// Для уже сохраненного содержимого берется сжатие и ключ blob-а, а не загруженной копии
+	blobID, stored, created, err := acquireBlob(tx, checksum, size, stored)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	fmt.Println("res: ", result, op)
-	return nil
-}
-
-func (s *Storage) ListFiles() ([]FileInfo, error) {
-	const op = "storage.sqlite.ListFiles"
+	var previousID sql.NullInt64
+	err = tx.QueryRow(`
+	SELECT id FROM files WHERE filename = ?
+	ORDER BY version DESC LIMIT 1
+	`, imageName).Scan(&previousID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
 
-	rows, err := s.db.Query("SELECT filename, created_at, updated_at FROM files")
+	// Номер берется из счетчика, а не из оставшихся версий: после purge последней версии он не повторяется
+	var version int64
+	err = tx.QueryRow(`
+	INSERT INTO file_versions (filename, last_version) VALUES (?, 1)
+	ON CONFLICT (filename) DO UPDATE SET last_version = file_versions.last_version + 1
+	RETURNING last_version
+	`, imageName).Scan(&version)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
-	defer rows.Close()
 
-	var files []FileInfo
+	insertStmt, err := tx.Prepare(`
+	INSERT INTO files (filename, path_to_file, size_kb, mime_type, declared_mime_type, checksum, blob_id, encoding, stored_size, version, previous_id, namespace, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer insertStmt.Close()
 
-	for rows.Next() {
-		var file FileInfo
-		err := rows.Scan(&file.FileName, &file.CreatedAt, &file.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-		files = append(files, file)
+	result, err := insertStmt.Exec(
+		imageName,
+		pathToFile,
+		size,
+		mimeType,
+		nullString(declaredMimeType),
+		checksum,
+		blobID,
+		stored.Encoding,
+		stored.StoredSize,
+		version,
+		previousID,
+		namespace,
+		createdAt.UTC().Format(sqliteTimeLayout),
+		createdAt.UTC().Format(sqliteTimeLayout),
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return files, nil
+	fmt.Println("res: ", result, op)
+	return version, created, nil
 }
 
 func (s *Storage) FindFileByName(fileName string) (string, error) {
 	const op = "storage.sqlite.FindFileByName"
 
 	var foundFileName string
-	err := s.db.QueryRow("SELECT filename FROM files WHERE filename = ?", fileName).Scan(&foundFileName)
+	err := s.db.QueryRow("SELECT filename FROM files WHERE filename = ? AND deleted_at IS NULL LIMIT 1", fileName).Scan(&foundFileName)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -115,6 +130,27 @@ func (s *Storage) FindFileByName(fileName string) (string, error) {
 	return foundFileName, nil
 }
 
+// GetFileInfo возвращает метаданные версии файла (version 0 - последняя)
+func (s *Storage) GetFileInfo(fileName string, version int64) (catalog.FileInfo, error) {
+	const op = "storage.sqlite.GetFileInfo"
+
+	var file catalog.FileInfo
+	err := scanFileInfo(s.db.QueryRow(`
+	SELECT `+fileInfoColumns+` FROM files f
+	LEFT JOIN blobs b ON b.id = f.blob_id
+	WHERE f.filename = ? AND (? = 0 OR f.version = ?) AND f.deleted_at IS NULL
+	ORDER BY f.version DESC LIMIT 1
+	`, fileName, version, version), &file)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.FileInfo{}, fmt.Errorf("%s: %w", op, catalog.ErrFileNotFound)
+		}
+		return catalog.FileInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return file, nil
+}
+
 func (s *Storage) CheckTable(ctx context.Context) error {
 	const op = "storage.sqlite.CheckTable"
 
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+func (s *Storage) CreateUploadSession(sessionID string, fileName string, namespace string) error {
+	const op = "storage.sqlite.CreateUploadSession"
+
+	_, err := s.db.Exec(`
+	INSERT INTO upload_sessions (id, filename, namespace, received_offset)
+	VALUES (?, ?, ?, 0)
+	`, sessionID, fileName, namespace)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUploadSession(sessionID string) (catalog.UploadSession, error) {
+	const op = "storage.sqlite.GetUploadSession"
+
+	var session catalog.UploadSession
+	err := s.db.QueryRow(`
+	SELECT id, filename, namespace, received_offset, hash_state, created_at, updated_at
+	FROM upload_sessions WHERE id = ?
+	`, sessionID).Scan(&session.ID, &session.FileName, &session.Namespace, &session.Offset, &session.HashState, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return catalog.UploadSession{}, fmt.Errorf("%s: %w", op, catalog.ErrSessionNotFound)
+		}
+		return catalog.UploadSession{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+// UpdateUploadOffset сохраняет смещение вместе с состоянием хеша, чтобы после докачки не перечитывать файл
+func (s *Storage) UpdateUploadOffset(sessionID string, offset int64, hashState []byte) error {
+	const op = "storage.sqlite.UpdateUploadOffset"
+
+	result, err := s.db.Exec(`
+	UPDATE upload_sessions SET received_offset = ?, hash_state = ?, updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?
+	`, offset, hashState, sessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, catalog.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUploadSession(sessionID string) error {
+	const op = "storage.sqlite.DeleteUploadSession"
+
+	_, err := s.db.Exec("DELETE FROM upload_sessions WHERE id = ?", sessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredUploadSessions удаляет сессии, которые не обновлялись с before, и возвращает их id
+func (s *Storage) DeleteExpiredUploadSessions(before time.Time) ([]string, error) {
+	const op = "storage.sqlite.DeleteExpiredUploadSessions"
+
+	rows, err := s.db.Query("DELETE FROM upload_sessions WHERE updated_at < ? RETURNING id", before.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
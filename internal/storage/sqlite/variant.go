@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"imagestorage/internal/storage/catalog"
+)
+
+const variantColumns = `b.checksum, v.name, v.path, v.mime_type, v.width, v.height, v.size_bytes, v.stored_size,
+	v.stored_checksum, v.key_salt, v.created_at`
+
+func scanVariant(row interface{ Scan(...any) error }, variant *catalog.Variant) error {
+	var createdAt sql.NullTime
+	err := row.Scan(&variant.Checksum, &variant.Name, &variant.Path, &variant.MimeType, &variant.Width, &variant.Height,
+		&variant.Size, &variant.StoredSize, &variant.StoredChecksum, &variant.KeySalt, &createdAt)
+	variant.CreatedAt = createdAt.Time
+	return err
+}
+
+// SaveBlobVariant записывает вариант blob-а variant.Checksum, заменяя прежний с тем же именем.
+// Возвращает false, если blob-а уже нет
+func (s *Storage) SaveBlobVariant(variant catalog.Variant) (bool, error) {
+	const op = "storage.sqlite.SaveBlobVariant"
+
+	result, err := s.db.Exec(`
+	INSERT INTO blob_variants (blob_id, name, path, mime_type, width, height, size_bytes, stored_size, stored_checksum,
+		key_salt, created_at)
+	SELECT id, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? FROM blobs WHERE checksum = ?
+	ON CONFLICT(blob_id, name) DO UPDATE SET path = excluded.path, mime_type = excluded.mime_type,
+		width = excluded.width, height = excluded.height, size_bytes = excluded.size_bytes,
+		stored_size = excluded.stored_size, stored_checksum = excluded.stored_checksum, key_salt = excluded.key_salt,
+		created_at = excluded.created_at
+	`, variant.Name, variant.Path, variant.MimeType, variant.Width, variant.Height, variant.Size, variant.StoredSize,
+		variant.StoredChecksum, variant.KeySalt, formatTime(variant.CreatedAt), variant.Checksum)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return saved > 0, nil
+}
+
+// ListBlobVariants возвращает варианты blob-а по имени, пустой checksum - варианты всех blob-ов
+func (s *Storage) ListBlobVariants(checksum string) ([]catalog.Variant, error) {
+	const op = "storage.sqlite.ListBlobVariants"
+
+	rows, err := s.db.Query(`
+	SELECT `+variantColumns+` FROM blob_variants v
+	JOIN blobs b ON b.id = v.blob_id
+	WHERE ? = '' OR b.checksum = ?
+	ORDER BY v.blob_id, v.name
+	`, checksum, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var variants []catalog.Variant
+	for rows.Next() {
+		var variant catalog.Variant
+		if err := scanVariant(rows, &variant); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return variants, nil
+}
+
+// DeleteBlobVariant удаляет запись о варианте. Данные в хранилище остаются
+func (s *Storage) DeleteBlobVariant(checksum string, name string) error {
+	const op = "storage.sqlite.DeleteBlobVariant"
+
+	_, err := s.db.Exec(`
+	DELETE FROM blob_variants WHERE blob_id = (SELECT id FROM blobs WHERE checksum = ?) AND name = ?
+	`, checksum, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func blobVariants(q queryer, blobID int64) ([]catalog.Variant, error) {
+	rows, err := q.Query(`
+	SELECT `+variantColumns+` FROM blob_variants v
+	JOIN blobs b ON b.id = v.blob_id
+	WHERE v.blob_id = ? ORDER BY v.name
+	`, blobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []catalog.Variant
+	for rows.Next() {
+		var variant catalog.Variant
+		if err := scanVariant(rows, &variant); err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
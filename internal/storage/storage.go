@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"fmt"
+
+	"imagestorage/internal/config"
+	"imagestorage/internal/storage/bolt"
+	"imagestorage/internal/storage/catalog"
+	"imagestorage/internal/storage/postgres"
+	"imagestorage/internal/storage/sqlite"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverBolt     = "bolt"
+)
+
+// New открывает каталог файлов выбранного в конфиге драйвера.
+// Для sqlite и bolt StoragePath - путь к файлу базы, для postgres - строка подключения
+func New(cfg config.DBConfig) (catalog.IStorage, error) {
+	const op = "storage.New"
+
+	switch cfg.Driver {
+	case DriverSQLite, "":
+		return sqlite.New(string(cfg.StoragePath))
+	case DriverPostgres:
+		return postgres.New(string(cfg.StoragePath))
+	case DriverBolt:
+		return bolt.New(string(cfg.StoragePath))
+	default:
+		return nil, fmt.Errorf("%s: unknown db driver %q", op, cfg.Driver)
+	}
+}
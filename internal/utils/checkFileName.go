@@ -1,11 +1,11 @@
 package utils
 
 import (
-	"mime"
-	"path/filepath"
 	"strings"
 )
 
+// CheckFileName проверяет, что имя файла не пустое и не выходит за пределы каталога.
+// Тип файла по имени не проверяется: он определяется по содержимому при загрузке
 func CheckFileName(fileName string) bool {
 	if fileName == "" {
 		return false
@@ -22,21 +22,5 @@ func CheckFileName(fileName string) bool {
 		return false
 	}
 
-	contentType := mime.TypeByExtension(filepath.Ext(fileName))
-	if contentType == "" {
-		contentType = "application/octet-stream" // По умолчанию
-	}
-
-	// allowedTypes := []string{"image/jpeg", "image/png", "image/gif"}
-	// if !contains(allowedTypes, contentType) {
-	//     return false
-	// }
-
 	return true
 }
-
-func GetExt(filename string) string {
-	ext := filepath.Ext(filename)
-
-	return ext
-}
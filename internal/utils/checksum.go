@@ -22,3 +22,13 @@ func CalculateChecksum(filePath string) (string, error) {
 	checksum := hex.EncodeToString(hash.Sum(nil))
 	return checksum, nil
 }
+
+// IsChecksum проверяет, что строка - sha256 в hex
+func IsChecksum(checksum string) bool {
+	if len(checksum) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(checksum)
+	return err == nil
+}
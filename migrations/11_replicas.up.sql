@@ -0,0 +1,12 @@
+-- stored_checksum - sha256 содержимого в том виде, в каком оно лежит в хранилище (после сжатия и
+-- шифрования), по нему проверяются копии. У несжатых и незашифрованных blob-ов совпадает с checksum
+ALTER TABLE blobs ADD COLUMN stored_checksum VARCHAR(64);
+UPDATE blobs SET stored_checksum = checksum WHERE encoding = '' AND key_id IS NULL;
+
+-- Каталоги данных, в которые записаны копии blob-а
+CREATE TABLE IF NOT EXISTS blob_replicas (
+    blob_id INTEGER NOT NULL REFERENCES blobs(id),
+    location VARCHAR(500) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blob_id, location)
+);
@@ -0,0 +1,10 @@
+-- Фрагменты blob-а в режиме erasure coding: в каком каталоге данных лежит фрагмент shard_index
+-- и в каком состоянии он был при последнем чтении (ok, missing, corrupt)
+CREATE TABLE IF NOT EXISTS blob_shards (
+    blob_id INTEGER NOT NULL REFERENCES blobs(id),
+    shard_index INTEGER NOT NULL,
+    location VARCHAR(500) NOT NULL,
+    health VARCHAR(16) NOT NULL DEFAULT 'ok',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blob_id, shard_index)
+);
@@ -0,0 +1,18 @@
+-- tier - где лежит blob: hot - основное хранилище, cold - холодное. last_accessed_at - последнее
+-- скачивание, по нему холодными становятся давно не читавшиеся blob-ы
+ALTER TABLE blobs ADD COLUMN tier VARCHAR(8) NOT NULL DEFAULT 'hot';
+ALTER TABLE blobs ADD COLUMN last_accessed_at DATETIME;
+UPDATE blobs SET last_accessed_at = created_at;
+
+CREATE INDEX idx_blobs_tier_last_accessed_at ON blobs(tier, last_accessed_at);
+
+-- История переносов blob-а между хранилищами
+CREATE TABLE IF NOT EXISTS blob_moves (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    blob_id INTEGER NOT NULL REFERENCES blobs(id),
+    from_tier VARCHAR(8) NOT NULL,
+    to_tier VARCHAR(8) NOT NULL,
+    moved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_blob_moves_blob_id ON blob_moves(blob_id);
@@ -0,0 +1,17 @@
+-- Производные изображения blob-а, например миниатюры thumb_256: name - имя варианта в DownloadRequest,
+-- path - ключ в хранилище blob-ов. Вариант зашифрован, если зашифрован сам blob
+CREATE TABLE IF NOT EXISTS blob_variants (
+    blob_id INTEGER NOT NULL REFERENCES blobs(id),
+    name VARCHAR(64) NOT NULL,
+    path VARCHAR(500) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    stored_size INTEGER NOT NULL,
+    stored_checksum VARCHAR(64) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blob_id, name)
+);
+
+CREATE INDEX idx_blob_variants_path ON blob_variants(path);
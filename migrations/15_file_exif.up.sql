@@ -0,0 +1,17 @@
+-- Метаданные EXIF загруженных JPEG, по записи на версию файла. Координаты пустые, если их нет
+-- или они вырезаны из файла
+CREATE TABLE IF NOT EXISTS file_exif (
+    file_id INTEGER PRIMARY KEY REFERENCES files(id),
+    camera_make VARCHAR(255) NOT NULL DEFAULT '',
+    camera_model VARCHAR(255) NOT NULL DEFAULT '',
+    lens_make VARCHAR(255) NOT NULL DEFAULT '',
+    lens_model VARCHAR(255) NOT NULL DEFAULT '',
+    captured_at DATETIME DEFAULT NULL,
+    orientation INTEGER NOT NULL DEFAULT 0,
+    gps_latitude REAL DEFAULT NULL,
+    gps_longitude REAL DEFAULT NULL,
+    gps_altitude REAL DEFAULT NULL
+);
+
+CREATE INDEX idx_file_exif_camera_model ON file_exif(camera_model);
+CREATE INDEX idx_file_exif_captured_at ON file_exif(captured_at);
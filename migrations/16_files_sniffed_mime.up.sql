@@ -0,0 +1,29 @@
+-- mime_type теперь определяется по содержимому. declared_mime_type - тип по расширению,
+-- если он противоречит содержимому, иначе NULL
+ALTER TABLE files ADD COLUMN declared_mime_type VARCHAR(100) DEFAULT NULL;
+
+CREATE INDEX idx_files_declared_mime_type ON files(declared_mime_type) WHERE declared_mime_type IS NOT NULL;
+
+-- Раньше в mime_type сохранялось расширение файла
+UPDATE files SET mime_type = CASE LOWER(mime_type)
+    WHEN '.jpg' THEN 'image/jpeg'
+    WHEN '.jpeg' THEN 'image/jpeg'
+    WHEN '.png' THEN 'image/png'
+    WHEN '.gif' THEN 'image/gif'
+    WHEN '.webp' THEN 'image/webp'
+    WHEN '.bmp' THEN 'image/bmp'
+    WHEN '.svg' THEN 'image/svg+xml'
+    WHEN '.avif' THEN 'image/avif'
+    WHEN '.ico' THEN 'image/vnd.microsoft.icon'
+    WHEN '.tif' THEN 'image/tiff'
+    WHEN '.tiff' THEN 'image/tiff'
+    WHEN '.pdf' THEN 'application/pdf'
+    WHEN '.txt' THEN 'text/plain'
+    WHEN '.json' THEN 'application/json'
+    WHEN '.zip' THEN 'application/zip'
+    WHEN '.mp4' THEN 'video/mp4'
+    WHEN '.webm' THEN 'video/webm'
+    WHEN '.mp3' THEN 'audio/mpeg'
+    ELSE 'application/octet-stream'
+END
+WHERE mime_type LIKE '.%' OR mime_type = '';
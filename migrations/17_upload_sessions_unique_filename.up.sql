@@ -0,0 +1,10 @@
+-- На имя файла не больше одной сессии загрузки: проверка и создание сессии - один INSERT.
+-- Из повторяющихся сессий остается последняя обновленная, staging-файлы остальных удаляются при старте
+DELETE FROM upload_sessions WHERE EXISTS (
+    SELECT 1 FROM upload_sessions s
+    WHERE s.filename = upload_sessions.filename
+        AND (s.updated_at > upload_sessions.updated_at OR (s.updated_at = upload_sessions.updated_at AND s.id > upload_sessions.id))
+);
+
+DROP INDEX idx_upload_sessions_filename;
+CREATE UNIQUE INDEX idx_upload_sessions_filename ON upload_sessions(filename);
@@ -0,0 +1,4 @@
+-- У каждой загрузки своя сессия и свой staging-файл, поэтому у одного имени может быть несколько
+-- незавершенных сессий: брошенная загрузка не мешает загружать новые версии файла
+DROP INDEX idx_upload_sessions_filename;
+CREATE INDEX idx_upload_sessions_filename ON upload_sessions(filename);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    checksum VARCHAR(64) NOT NULL UNIQUE,
+    size_bytes INTEGER NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE files ADD COLUMN blob_id INTEGER REFERENCES blobs(id);
+
+CREATE INDEX idx_files_blob_id ON files(blob_id);
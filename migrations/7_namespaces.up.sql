@@ -0,0 +1,5 @@
+ALTER TABLE files ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE upload_sessions ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_files_namespace ON files(namespace);
+CREATE INDEX idx_upload_sessions_namespace ON upload_sessions(namespace);
@@ -0,0 +1,3 @@
+-- Ключ данных blob-а, закрытый мастер-ключом key_id. NULL - содержимое не зашифровано
+ALTER TABLE blobs ADD COLUMN key_id VARCHAR(32);
+ALTER TABLE blobs ADD COLUMN wrapped_key BYTEA;
@@ -0,0 +1,3 @@
+-- Случайная соль ключа варианта зашифрованного blob-а: при перегенерации варианта ключ новый,
+-- и номера кадров в качестве nonce не повторяются под тем же ключом. У старых вариантов соли нет
+ALTER TABLE blob_variants ADD COLUMN key_salt BYTEA;
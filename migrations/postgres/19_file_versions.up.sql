@@ -0,0 +1,10 @@
+-- Последний выданный номер версии каждого имени. Purge его не уменьшает, поэтому номер окончательно
+-- удаленной версии не достается следующей загрузке. Номера, удаленные до этой миграции, уже не восстановить:
+-- счетчик начинается с наибольшей оставшейся версии
+CREATE TABLE IF NOT EXISTS file_versions (
+    filename VARCHAR(255) COLLATE "C" PRIMARY KEY,
+    last_version BIGINT NOT NULL
+);
+
+INSERT INTO file_versions (filename, last_version)
+SELECT filename, MAX(version) FROM files GROUP BY filename;
@@ -0,0 +1,16 @@
+-- filename сравнивается побайтово, как в SQLite: от этого зависят поиск по префиксу и курсоры ListFiles
+CREATE TABLE IF NOT EXISTS files (
+    id BIGSERIAL PRIMARY KEY,
+    filename VARCHAR(255) COLLATE "C" NOT NULL,
+    path_to_file VARCHAR(500) NOT NULL,
+    size_kb BIGINT NOT NULL,
+    mime_type VARCHAR(100),
+    created_at TIMESTAMPTZ(0) DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ(0) DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ(0) DEFAULT NULL,
+    checksum VARCHAR(64)
+);
+
+CREATE INDEX idx_files_filename ON files(filename);
+CREATE INDEX idx_files_created_at ON files(created_at);
+CREATE INDEX idx_files_deleted_at ON files(deleted_at);
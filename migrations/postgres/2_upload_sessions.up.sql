@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    filename VARCHAR(255) COLLATE "C" NOT NULL,
+    received_offset BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ(0) DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ(0) DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_upload_sessions_filename ON upload_sessions(filename);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS blobs (
+    id BIGSERIAL PRIMARY KEY,
+    checksum VARCHAR(64) NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL,
+    ref_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ(0) DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE files ADD COLUMN blob_id BIGINT REFERENCES blobs(id);
+
+CREATE INDEX idx_files_blob_id ON files(blob_id);
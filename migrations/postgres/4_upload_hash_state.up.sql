@@ -0,0 +1 @@
+ALTER TABLE upload_sessions ADD COLUMN hash_state BYTEA;
@@ -0,0 +1,12 @@
+ALTER TABLE files ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE files ADD COLUMN previous_id BIGINT REFERENCES files(id);
+
+-- Файлы с одинаковым именем, загруженные раньше, становятся версиями друг друга
+UPDATE files SET version = (
+    SELECT COUNT(*) FROM files f2 WHERE f2.filename = files.filename AND f2.id <= files.id
+);
+UPDATE files SET previous_id = (
+    SELECT MAX(f2.id) FROM files f2 WHERE f2.filename = files.filename AND f2.id < files.id
+);
+
+CREATE UNIQUE INDEX idx_files_filename_version ON files(filename, version);
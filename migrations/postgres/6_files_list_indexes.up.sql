@@ -0,0 +1,2 @@
+CREATE INDEX idx_files_mime_type ON files(mime_type);
+CREATE INDEX idx_files_size_kb ON files(size_kb);
@@ -0,0 +1,5 @@
+-- path_to_file хранил только каталог из env, теперь это ключ содержимого в хранилище blob-ов
+UPDATE files SET path_to_file = COALESCE(
+    (SELECT 'blobs/' || b.checksum FROM blobs b WHERE b.id = files.blob_id),
+    filename
+);
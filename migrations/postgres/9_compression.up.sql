@@ -0,0 +1,8 @@
+-- encoding - алгоритм сжатия содержимого ('' - без сжатия), stored_size - размер в хранилище
+ALTER TABLE blobs ADD COLUMN encoding VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE blobs ADD COLUMN stored_size BIGINT;
+UPDATE blobs SET stored_size = size_bytes;
+
+ALTER TABLE files ADD COLUMN encoding VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE files ADD COLUMN stored_size BIGINT;
+UPDATE files SET stored_size = size_kb;